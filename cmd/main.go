@@ -14,13 +14,13 @@ var (
 
 func main() {
 	flag.Parse()
-	routerC, databaseC, securityC, authC, err := config.Load(*configYAML)
+	routerC, databaseC, securityC, authC, authzC, printC, webhookC, mailC, twoFactorC, taxC, kitchenC, err := config.Load(*configYAML)
 	if err != nil {
 		log.Fatalf("error parsing config.yml: %v", err)
 	}
 
-	app := server.NewApp(routerC, databaseC, authC, securityC, *seedDatabase)
+	app := server.NewApp(routerC, databaseC, authC, authzC, securityC, printC, webhookC, mailC, twoFactorC, taxC, kitchenC, *seedDatabase)
 	if err := app.Run(); err != nil {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
@@ -9,8 +9,15 @@ import (
 	"time"
 
 	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/apikey"
+	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/kitchen"
 	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/order"
+	"github.com/coquizen/servercarte/domain/promotion"
+	"github.com/coquizen/servercarte/domain/twofactor"
 	"github.com/coquizen/servercarte/domain/user"
+	"github.com/coquizen/servercarte/domain/webhook"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -21,12 +28,12 @@ import (
 	"github.com/coquizen/servercarte/internal/config"
 )
 
-var configYAML   = flag.String("c", "config.yml", "configure db")
+var configYAML = flag.String("c", "config.yml", "configure db")
 var db *gorm.DB
 
 func main() {
 	flag.Parse()
-	_, databaseC, _, _, err := config.Load(*configYAML)
+	_, databaseC, _, _, _, _, _, _, _, _, _, err := config.Load(*configYAML)
 	if err != nil {
 		log.Fatalf("error parsing config.yml %v", err)
 	}
@@ -81,8 +88,6 @@ func open(dbConf config.Database, gormCfg *gorm.Config) (*gorm.DB, error) {
 		return &gorm.DB{}, fmt.Errorf("%s is unsupported", dbConf.Type)
 	}
 
-
-
 	db, err := gorm.Open(dialect, gormCfg)
 	if err != nil {
 		return db, err
@@ -96,16 +101,29 @@ func StrPtr(s string) *string {
 }
 
 // PopulateDB populates the db with sample data.
-func PopulateDB () error {
+func PopulateDB() error {
 	// Drop all Tables
 	db.Migrator().DropTable(&menu.Section{})
 	db.Migrator().DropTable(&menu.Item{})
+	db.Migrator().DropTable(&menu.ModifierGroup{})
+	db.Migrator().DropTable(&menu.ModifierOption{})
+	db.Migrator().DropTable(&menu.Variant{})
+	db.Migrator().DropTable(&menu.AvailabilityWindow{})
+	db.Migrator().DropTable(&menu.Tag{}, "item_tags", "section_tags")
+	db.Migrator().DropTable(&menu.Snapshot{})
 	db.Migrator().DropTable(&user.User{})
-	db.Migrator().DropTable(&account.Account{})
+	db.Migrator().DropTable(&account.Account{}, &account.OneTimeToken{}, &account.LoginThrottle{}, &account.AuditEntry{}, &account.PasswordHistory{})
+	db.Migrator().DropTable(&authentication.Session{}, &authentication.RefreshToken{})
+	db.Migrator().DropTable(&twofactor.Enrollment{}, &twofactor.RecoveryCode{}, &twofactor.Challenge{})
+	db.Migrator().DropTable(&webhook.Subscription{}, &webhook.Delivery{})
+	db.Migrator().DropTable(&apikey.APIKey{})
+	db.Migrator().DropTable(&order.Order{}, &order.LineItem{}, &order.Selection{})
+	db.Migrator().DropTable(&promotion.Promotion{}, &promotion.Target{})
+	db.Migrator().DropTable(&kitchen.Ticket{}, &kitchen.TicketItem{}, &kitchen.RoutedOrder{})
 	fmt.Print("Old tables have been deleted...")
 
 	// Migrate model over to db
-	err := db.AutoMigrate(&menu.Section{}, &menu.Item{}, &user.User{}, &account.Account{})
+	err := db.AutoMigrate(&menu.Section{}, &menu.Item{}, &menu.ModifierGroup{}, &menu.ModifierOption{}, &menu.Variant{}, &menu.AvailabilityWindow{}, &menu.Tag{}, &menu.Snapshot{}, &user.User{}, &account.Account{}, &account.OneTimeToken{}, &account.LoginThrottle{}, &account.AuditEntry{}, &account.PasswordHistory{}, &authentication.Session{}, &authentication.RefreshToken{}, &twofactor.Enrollment{}, &twofactor.RecoveryCode{}, &twofactor.Challenge{}, &webhook.Subscription{}, &webhook.Delivery{}, &apikey.APIKey{}, &order.Order{}, &order.LineItem{}, &order.Selection{}, &promotion.Promotion{}, &promotion.Target{}, &kitchen.Ticket{}, &kitchen.TicketItem{}, &kitchen.RoutedOrder{})
 	if err != nil {
 		return fmt.Errorf("error migrating scheme to db: %v", err)
 	}
@@ -165,6 +183,3 @@ func PopulateDB () error {
 
 	return nil
 }
-
-
-
@@ -1,9 +1,31 @@
 package account
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrAccountNotFound = errors.New("account not found")
 	ErrNotAuthorized   = errors.New("unauthorized access: username or password incorrect")
+	ErrInvalidToken    = errors.New("token is invalid, expired or already used")
+	ErrNothingToUnlock = errors.New("a username or an IP address is required")
+	ErrPasswordReused  = errors.New("password was used recently; choose another one")
+	ErrPasswordExpired = errors.New("password expired; choose a new one at /password/change")
+	ErrInvalidPIN      = errors.New("unauthorized access: username or pin incorrect")
 )
 
+// ThrottledError is returned instead of checking a password while failed logins hold a username or an IP address
+// back, either for a short delay or, when Locked, for the rest of a lockout.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed logins; locked for another %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many failed logins; try again in %s", e.RetryAfter.Round(time.Second))
+}
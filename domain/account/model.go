@@ -1,6 +1,8 @@
 package account
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +19,28 @@ const (
 	Guest
 )
 
+// AccessLevels lists every access level, highest first.
+var AccessLevels = []AccessLevel{Admin, Employee, Guest}
+
+var accessLevelNames = map[AccessLevel]string{Admin: "admin", Employee: "employee", Guest: "guest"}
+
+func (a AccessLevel) String() string {
+	if name, ok := accessLevelNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("AccessLevel(%d)", int(a))
+}
+
+// AccessLevelFromName parses the name of an access level, e.g. employee.
+func AccessLevelFromName(name string) (AccessLevel, error) {
+	for level, levelName := range accessLevelNames {
+		if levelName == strings.ToLower(name) {
+			return level, nil
+		}
+	}
+	return Guest, fmt.Errorf("unknown role %q", name)
+}
+
 // Account contains a user's account properties for interacting with the system
 type Account struct {
 	domain.Base
@@ -27,6 +51,123 @@ type Account struct {
 	Role      AccessLevel `json:"role" gorm:"not null"`
 	Token     string      `json:"token,omitempty" gorm:"null"`
 	LastLogin time.Time   `json:"last_login,omitempty" gorm:"null"`
+	// PasswordChangedAt is when the password was last set; accounts that never changed it count from CreatedAt.
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty" gorm:"null"`
+	// EmailVerifiedAt is when the account's email address was verified, nil until then.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" gorm:"null"`
+	// PIN is the hash of the PIN the account logs in with on shared devices, empty until one is set.
+	PIN string `json:"-" gorm:"null"`
+}
+
+// HasPIN reports whether the account can log in with a PIN.
+func (a *Account) HasPIN() bool {
+	return a.PIN != ""
+}
+
+// PasswordHistory keeps the hash of a password an account used before, so that it cannot be chosen again.
+type PasswordHistory struct {
+	domain.Base
+	AccountID uuid.UUID `json:"account_id" gorm:"not null;index"`
+	Hash      string    `json:"-" gorm:"not null"`
+}
+
+// TokenPurpose is what a one-time token may be spent on.
+type TokenPurpose string
+
+const (
+	PasswordReset     TokenPurpose = "password_reset"
+	EmailVerification TokenPurpose = "email_verification"
+)
+
+// OneTimeToken is a token mailed to an account's email address, to reset its password or verify the address. Only
+// its hash is stored; it expires at ExpiresAt and can be spent once.
+type OneTimeToken struct {
+	domain.Base
+	AccountID uuid.UUID    `json:"account_id" gorm:"not null;index"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"not null"`
+	TokenHash string       `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time    `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time   `json:"used_at,omitempty" gorm:"null"`
+}
+
+// Usable reports whether the token is neither spent nor expired.
+func (t *OneTimeToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// LoginThrottle counts the recent failed logins of a username or an IP address, under keys such as username:admin
+// or ip:192.0.2.1. Usernames are counted whether or not they have an account, so that a lockout gives nothing away.
+type LoginThrottle struct {
+	Key           string     `json:"key" gorm:"primaryKey;column:throttle_key;size:191"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Locked reports whether logins are locked at the time given.
+func (t *LoginThrottle) Locked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// Events recorded in the audit log.
+const (
+	LoginLocked   = "login.locked"
+	LoginIPLocked = "login.ip_locked"
+	LoginUnlocked = "login.unlocked"
+	// LoginPINLocked is recorded when wrong PINs lock an account's PIN login.
+	LoginPINLocked = "login.pin_locked"
+)
+
+// AuditEntry records a security event, e.g. a username being locked out after too many failed logins. Actor is the
+// username of whoever caused it, when it was not the login attempts themselves.
+type AuditEntry struct {
+	domain.Base
+	Event    string `json:"event" gorm:"not null;index"`
+	Username string `json:"username,omitempty" gorm:"index"`
+	IP       string `json:"ip,omitempty"`
+	Actor    string `json:"actor,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// AuditFilter narrows the audit log down to the entries matching every field given.
+type AuditFilter struct {
+	Username string `form:"username"`
+	Actor    string `form:"actor"`
+	Event    string `form:"event"`
+}
+
+// UnlockRequest is the request struct for lifting the lockout of a username, an IP address, or both.
+type UnlockRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+// PasswordResetRequest is the request struct for resetting a forgotten password with a token mailed to the account.
+type PasswordResetRequest struct {
+	Token           string `json:"token" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"required"`
+}
+
+// ChangePasswordRequest is the request struct for changing the password of the account logged in.
+type ChangePasswordRequest struct {
+	OldPassword     string `json:"old_password" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"required"`
+}
+
+// PINRequest sets the PIN of the account logged in, given its password.
+type PINRequest struct {
+	Password   string `json:"password" binding:"required"`
+	PIN        string `json:"pin" binding:"required"`
+	PINConfirm string `json:"pin_confirm" binding:"required"`
+}
+
+// PINLoginRequest logs in on a shared device.
+type PINLoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	PIN      string `json:"pin" binding:"required"`
 }
 
 // NewAccountRequest represent the request struct for Create endpoint
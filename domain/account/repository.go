@@ -2,6 +2,7 @@ package account
 
 import (
 	"context"
+	"time"
 
 	"github.com/coquizen/servercarte/domain/user"
 	"github.com/google/uuid"
@@ -15,4 +16,42 @@ type Repository interface {
 	Find(ctx context.Context, username string) (Account, error)
 	Update(ctx context.Context, account *Account) error
 	Delete(ctx context.Context, accountID uuid.UUID) error
+	// FindByID looks an account up along with its user.
+	FindByID(ctx context.Context, accountID uuid.UUID) (Account, error)
+	// FindByEmail looks up the account of the user with the email address.
+	FindByEmail(ctx context.Context, email string) (Account, error)
+	// SetPassword replaces the password, moving the old hash into the password history and keeping only the latest
+	// keep entries there.
+	SetPassword(ctx context.Context, accountID uuid.UUID, passwordHash string, at time.Time, keep int) error
+	// SetPIN replaces the hash of the account's PIN; an empty hash removes it.
+	SetPIN(ctx context.Context, accountID uuid.UUID, pinHash string) error
+	// RehashPassword swaps the hash of the current password for a new hash of the same password, unless the password
+	// was changed in the meantime.
+	RehashPassword(ctx context.Context, accountID uuid.UUID, oldHash, newHash string) error
+	// PasswordHistory returns the hashes of the latest passwords the account used before its current one, newest
+	// first.
+	PasswordHistory(ctx context.Context, accountID uuid.UUID, limit int) ([]string, error)
+	SetEmailVerified(ctx context.Context, accountID uuid.UUID, at time.Time) error
+
+	CreateToken(ctx context.Context, token *OneTimeToken) error
+	FindToken(ctx context.Context, tokenHash string, purpose TokenPurpose) (OneTimeToken, error)
+	// UseToken marks the token used, reporting false when it was used already, so that two requests racing with the
+	// same token cannot both spend it.
+	UseToken(ctx context.Context, tokenID uuid.UUID, at time.Time) (bool, error)
+	// ExpireTokens expires the account's unused tokens for the purpose, e.g. the other reset tokens once one is spent.
+	ExpireTokens(ctx context.Context, accountID uuid.UUID, purpose TokenPurpose, at time.Time) error
+
+	FindThrottle(ctx context.Context, key string) (LoginThrottle, error)
+	// CountFailure adds a failed login to the key's count in a single statement, so that failures made at the same time
+	// are all counted, and returns the throttle as it then is. The count starts over when the last failure is older
+	// than the window or the lockout has lapsed.
+	CountFailure(ctx context.Context, key string, now time.Time, window time.Duration) (LoginThrottle, error)
+	// LockThrottle locks the key until the time given, reporting false when it was locked already.
+	LockThrottle(ctx context.Context, key string, now, until time.Time) (bool, error)
+	// RefundFailure takes one failure off the key's count.
+	RefundFailure(ctx context.Context, key string) error
+	DeleteThrottle(ctx context.Context, key string) error
+	CreateAuditEntry(ctx context.Context, entry *AuditEntry) error
+	// ListAuditEntries returns the latest entries matching the filter, newest first.
+	ListAuditEntries(ctx context.Context, filter AuditFilter, limit int) ([]AuditEntry, error)
 }
@@ -2,9 +2,18 @@ package account
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/coquizen/servercarte/domain"
+	"github.com/coquizen/servercarte/domain/mail"
+
 	"github.com/coquizen/servercarte/domain/security"
 	"github.com/coquizen/servercarte/internal/helpers"
 
@@ -27,8 +36,159 @@ type Service interface {
 	Accounts(ctx context.Context) ([]Account, error)
 	Update(ctx context.Context, request UpdateAccountRequest) error
 	Find(ctx context.Context, username string) (Account, error)
+	FindByID(ctx context.Context, accountID uuid.UUID) (Account, error)
 	Delete(ctx context.Context, accountID uuid.UUID) error
-	Authenticate(ctx context.Context, username, password string) (Account, error)
+	// Authenticate checks a login from the IP address given, counting failures against both the username and the
+	// address. While they are held back it returns a *ThrottledError without checking the password.
+	Authenticate(ctx context.Context, username, password, ip string) (Account, error)
+	// Unlock lifts the lockout of a username, an IP address, or both, on behalf of the actor.
+	Unlock(ctx context.Context, actor string, request UnlockRequest) error
+	// AuditLog returns the latest entries of the audit log matching the filter, newest first.
+	AuditLog(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+	// Audit records a security event in the audit log as well as the application log.
+	Audit(ctx context.Context, entry AuditEntry)
+	// PasswordExpired reports whether the account must choose a new password before doing anything else.
+	PasswordExpired(acct *Account) bool
+	Refresh(ctx context.Context, refreshToken string) (authentication.Tokens, error)
+	ChangePassword(ctx context.Context, username, oldPassword, newPassword, confirmNewPassword string) error
+	// SetPIN sets the PIN the account logs in with on shared devices, checked against the PIN policy.
+	SetPIN(ctx context.Context, accountID uuid.UUID, request PINRequest) error
+	ClearPIN(ctx context.Context, accountID uuid.UUID) error
+	// PINLogin swaps a PIN for a short-lived access token only granting the scopes of the PIN policy. Wrong PINs are
+	// counted apart from failed logins; while they hold the account back it returns a *ThrottledError.
+	PINLogin(ctx context.Context, request PINLoginRequest, ip string) (authentication.Tokens, error)
+	// ForgotPassword mails a password reset link to the account with the email address. It does not tell whether
+	// there is such an account, so that it cannot be used to find out who has one.
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword sets a new password with a token from ForgotPassword, logging the account out everywhere.
+	ResetPassword(ctx context.Context, request PasswordResetRequest) error
+	// SendVerification mails a link to verify the account's email address.
+	SendVerification(ctx context.Context, accountID uuid.UUID) error
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+// How long the tokens mailed to account holders stay valid.
+const (
+	PasswordResetPeriod     = time.Hour
+	EmailVerificationPeriod = 48 * time.Hour
+)
+
+// LockoutPolicy throttles failed logins; see config.Lockout. Zero fields take their value from DefaultLockoutPolicy.
+type LockoutPolicy struct {
+	MaxFailures      int
+	MaxFailuresPerIP int
+	Window           time.Duration
+	LockoutPeriod    time.Duration
+	InitialDelay     time.Duration
+	MaxDelay         time.Duration
+}
+
+var DefaultLockoutPolicy = LockoutPolicy{
+	MaxFailures:      5,
+	MaxFailuresPerIP: 20,
+	Window:           15 * time.Minute,
+	LockoutPeriod:    15 * time.Minute,
+	InitialDelay:     time.Second,
+	MaxDelay:         30 * time.Second,
+}
+
+func (p LockoutPolicy) withDefaults() LockoutPolicy {
+	if p.MaxFailures <= 0 {
+		p.MaxFailures = DefaultLockoutPolicy.MaxFailures
+	}
+	if p.MaxFailuresPerIP <= 0 {
+		p.MaxFailuresPerIP = DefaultLockoutPolicy.MaxFailuresPerIP
+	}
+	if p.Window <= 0 {
+		p.Window = DefaultLockoutPolicy.Window
+	}
+	if p.LockoutPeriod <= 0 {
+		p.LockoutPeriod = DefaultLockoutPolicy.LockoutPeriod
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = DefaultLockoutPolicy.InitialDelay
+	}
+	if p.MaxDelay < p.InitialDelay {
+		p.MaxDelay = DefaultLockoutPolicy.MaxDelay
+	}
+	return p
+}
+
+// delay is how long a username waits after its latest failure: the initial delay, doubled for each failure before.
+func (p LockoutPolicy) delay(failures int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// PasswordPolicy governs how passwords are rotated. The last History passwords of an account, its current one
+// included, cannot be chosen again; zero turns the check off. Passwords older than MaxAge expire; zero means never.
+type PasswordPolicy struct {
+	History int
+	MaxAge  time.Duration
+}
+
+// PINPolicy governs PIN logins on shared devices; see config.PIN. Zero fields take their value from DefaultPINPolicy.
+type PINPolicy struct {
+	MaxFailures int
+	TokenPeriod time.Duration
+	Scopes      []string
+}
+
+var DefaultPINPolicy = PINPolicy{
+	MaxFailures: 5,
+	TokenPeriod: 15 * time.Minute,
+	Scopes:      []string{"menu:read_draft", "menu:toggle_active"},
+}
+
+func (p PINPolicy) withDefaults() PINPolicy {
+	if p.MaxFailures <= 0 {
+		p.MaxFailures = DefaultPINPolicy.MaxFailures
+	}
+	if p.TokenPeriod <= 0 {
+		p.TokenPeriod = DefaultPINPolicy.TokenPeriod
+	}
+	if len(p.Scopes) == 0 {
+		p.Scopes = DefaultPINPolicy.Scopes
+	}
+	return p
+}
+
+// DefaultPasswordHistory is how many passwords are remembered when the history is turned on without a count.
+const DefaultPasswordHistory = 5
+
+// auditLogLimit is how many entries AuditLog returns.
+const auditLogLimit = 200
+
+// Links are the links mailed to account holders, with {token} standing in for the token, e.g.
+// https://example.com/reset?token={token}.
+type Links struct {
+	PasswordReset     string
+	EmailVerification string
+}
+
+// Events passed on to the notifier when accounts change.
+const (
+	AccountCreated = "account.created"
+	AccountUpdated = "account.updated"
+	AccountDeleted = "account.deleted"
+)
+
+// Notifier is told about changes to accounts, e.g. to pass them on to webhook subscribers.
+type Notifier interface {
+	Notify(ctx context.Context, eventType string, data interface{}) error
+}
+
+// accountEvent is what a notifier is told about an account; it leaves out the password and token.
+type accountEvent struct {
+	ID       uuid.UUID    `json:"id"`
+	Username string       `json:"username,omitempty"`
+	Role     *AccessLevel `json:"role,omitempty"`
 }
 
 // service are the contracted methods to interact with GORM
@@ -37,12 +197,29 @@ type service struct {
 	userSvc     user.Service
 	secSvc      security.Service
 	authSvc     authentication.Service
+	notifier    Notifier
+	mailer      mail.Mailer
+	links       Links
+	lockout     LockoutPolicy
+	passwords   PasswordPolicy
+	pins        PINPolicy
+	log         domain.Logger
 }
 
 // NewService returns a new instance of service
 func NewService(accountRepo Repository, userSvc user.Service, secSvc security.Service,
-	authSvc authentication.Service) Service {
-	return &service{accountRepo, userSvc, secSvc, authSvc}
+	authSvc authentication.Service, notifier Notifier, mailer mail.Mailer, links Links,
+	lockout LockoutPolicy, passwords PasswordPolicy, pins PINPolicy, log domain.Logger) Service {
+	return &service{accountRepo, userSvc, secSvc, authSvc, notifier, mailer, links, lockout.withDefaults(), passwords,
+		pins.withDefaults(), log}
+}
+
+// notify passes an account change on to the notifier. The change has already been saved, so a notifier that fails
+// only gets logged.
+func (a *service) notify(ctx context.Context, eventType string, event accountEvent) {
+	if err := a.notifier.Notify(ctx, eventType, event); err != nil {
+		a.log.Errorf("could not notify %s for account %s: %v", eventType, event.ID, err)
+	}
 }
 
 // New creates a new account (bringing in the user model).
@@ -66,7 +243,13 @@ func (a *service) New(ctx context.Context, req NewAccountRequest) (*Account, err
 		return &NullAccount, err
 	}
 
-	newAccount.Password = a.secSvc.Hash(req.Password)
+	hashedPassword, err := a.secSvc.Hash(req.Password)
+	if err != nil {
+		return &NullAccount, err
+	}
+	newAccount.Password = hashedPassword
+	now := time.Now().UTC()
+	newAccount.PasswordChangedAt = &now
 
 	if err := a.userSvc.Create(ctx, &newUser); err != nil {
 		return &NullAccount, err
@@ -75,6 +258,11 @@ func (a *service) New(ctx context.Context, req NewAccountRequest) (*Account, err
 	if err := a.accountRepo.Create(ctx, &newAccount, &newUser); err != nil {
 		return &NullAccount, err
 	}
+	a.notify(ctx, AccountCreated, accountEvent{newAccount.ID, newAccount.Username, &newAccount.Role})
+	if err := a.sendVerification(ctx, &newAccount, newUser.Email); err != nil {
+		// The account exists either way; its holder can ask for another link.
+		a.log.Errorf("could not send verification email for account %s: %v", newAccount.ID, err)
+	}
 	return &newAccount, nil
 }
 
@@ -118,23 +306,250 @@ func (a *service) Find(ctx context.Context, username string) (Account, error) {
 	return account, nil
 }
 
+func (a *service) FindByID(ctx context.Context, accountID uuid.UUID) (Account, error) {
+	acct, err := a.accountRepo.FindByID(ctx, accountID)
+	if err != nil {
+		return NullAccount, ErrAccountNotFound
+	}
+	return acct, nil
+}
+
 func (a *service) ChangePassword(ctx context.Context, username, oldPassword, newPassword, confirmNewPassword string) error {
-	if err := a.secSvc.ConfirmationChecker(newPassword, confirmNewPassword); err != nil {
+	if err := a.checkPassword(newPassword, confirmNewPassword); err != nil {
+		return err
+	}
+
+	acct, err := a.Find(ctx, username)
+	if err != nil {
+		return ErrNotAuthorized
+	}
+	if err := a.secSvc.VerifyPasswordMatches(acct.Password, oldPassword); err != nil {
+		return ErrNotAuthorized
+	}
+	return a.setPassword(ctx, &acct, newPassword)
+}
+
+func (a *service) ForgotPassword(ctx context.Context, email string) error {
+	acct, err := a.accountRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		a.log.Infof("password reset requested for unknown email address")
+		return nil
+	}
+	token, err := a.issueToken(ctx, acct.ID, PasswordReset, PasswordResetPeriod)
+	if err != nil {
+		return err
+	}
+	a.send(mail.Message{
+		To:      acct.User.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password of your account. To choose a new one, "+
+			"follow this link within the next %s:\n\n%s\n\nIf it was not you, ignore this email; your password "+
+			"stays as it is.\n", acct.Username, hours(PasswordResetPeriod), link(a.links.PasswordReset, token)),
+	})
+	return nil
+}
+
+func (a *service) ResetPassword(ctx context.Context, req PasswordResetRequest) error {
+	if err := a.checkPassword(req.Password, req.PasswordConfirm); err != nil {
+		return err
+	}
+	token, err := a.findToken(ctx, req.Token, PasswordReset)
+	if err != nil {
+		return err
+	}
+	acct, err := a.accountRepo.FindByID(ctx, token.AccountID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	// A reused password is turned down before the token is spent, so that the link can be tried again.
+	if err := a.checkReuse(ctx, &acct, req.Password); err != nil {
+		return err
+	}
+	if err := a.spendToken(ctx, &token); err != nil {
+		return err
+	}
+	if err := a.setPassword(ctx, &acct, req.Password); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if err := a.accountRepo.ExpireTokens(ctx, token.AccountID, PasswordReset, now); err != nil {
+		return err
+	}
+	// The reset link reached the mailbox, which verifies the address as well.
+	if err := a.accountRepo.SetEmailVerified(ctx, token.AccountID, now); err != nil {
+		return err
+	}
+	// Whoever knew the old password may still be logged in.
+	return a.authSvc.EndAccountSessions(ctx, token.AccountID)
+}
+
+func (a *service) SendVerification(ctx context.Context, accountID uuid.UUID) error {
+	acct, err := a.accountRepo.FindByID(ctx, accountID)
+	if err != nil {
+		return ErrAccountNotFound
+	}
+	return a.sendVerification(ctx, &acct, acct.User.Email)
+}
+
+func (a *service) sendVerification(ctx context.Context, acct *Account, email string) error {
+	if acct.EmailVerifiedAt != nil {
+		return nil
+	}
+	token, err := a.issueToken(ctx, acct.ID, EmailVerification, EmailVerificationPeriod)
+	if err != nil {
+		return err
+	}
+	a.send(mail.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm that this is your email address by following this link "+
+			"within the next %s:\n\n%s\n", acct.Username, hours(EmailVerificationPeriod),
+			link(a.links.EmailVerification, token)),
+	})
+	return nil
+}
+
+func (a *service) VerifyEmail(ctx context.Context, token string) error {
+	spent, err := a.findToken(ctx, token, EmailVerification)
+	if err != nil {
+		return err
+	}
+	if err := a.spendToken(ctx, &spent); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if err := a.accountRepo.SetEmailVerified(ctx, spent.AccountID, now); err != nil {
+		return err
+	}
+	return a.accountRepo.ExpireTokens(ctx, spent.AccountID, EmailVerification, now)
+}
+
+// issueToken stores a new one-time token for the account and returns it; only its hash is kept.
+func (a *service) issueToken(ctx context.Context, accountID uuid.UUID, purpose TokenPurpose,
+	period time.Duration) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	if err := a.accountRepo.CreateToken(ctx, &OneTimeToken{
+		AccountID: accountID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().UTC().Add(period),
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findToken looks up a one-time token that is neither spent nor expired.
+func (a *service) findToken(ctx context.Context, token string, purpose TokenPurpose) (OneTimeToken, error) {
+	found, err := a.accountRepo.FindToken(ctx, hashToken(token), purpose)
+	if err != nil || !found.Usable(time.Now().UTC()) {
+		return OneTimeToken{}, ErrInvalidToken
+	}
+	return found, nil
+}
+
+// spendToken marks a one-time token used, failing if another request got to it first.
+func (a *service) spendToken(ctx context.Context, token *OneTimeToken) error {
+	fresh, err := a.accountRepo.UseToken(ctx, token.ID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// setPassword stores a new password, after checking that it is not one the account used recently.
+func (a *service) setPassword(ctx context.Context, acct *Account, password string) error {
+	if err := a.checkReuse(ctx, acct, password); err != nil {
+		return err
+	}
+	hashedPassword, err := a.secSvc.Hash(password)
+	if err != nil {
 		return err
 	}
+	keep := a.passwords.History - 1
+	return a.accountRepo.SetPassword(ctx, acct.ID, hashedPassword, time.Now().UTC(), keep)
+}
 
-	acct, err := a.Authenticate(ctx, username, oldPassword)
+// checkReuse turns down the account's current password and the ones in its history.
+func (a *service) checkReuse(ctx context.Context, acct *Account, password string) error {
+	if a.passwords.History <= 0 {
+		return nil
+	}
+	previous, err := a.accountRepo.PasswordHistory(ctx, acct.ID, a.passwords.History-1)
 	if err != nil {
 		return err
 	}
-	acct.Password = a.secSvc.Hash(newPassword)
+	for _, hash := range append([]string{acct.Password}, previous...) {
+		if a.secSvc.VerifyPasswordMatches(hash, password) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+func (a *service) PasswordExpired(acct *Account) bool {
+	if a.passwords.MaxAge <= 0 {
+		return false
+	}
+	changed := acct.CreatedAt
+	if acct.PasswordChangedAt != nil {
+		changed = *acct.PasswordChangedAt
+	}
+	return time.Since(changed) > a.passwords.MaxAge
+}
+
+// send mails the message in the background, so that a slow mail server neither holds up the request nor gives away
+// through its timing whether an email address has an account.
+func (a *service) send(message mail.Message) {
+	go func() {
+		if err := a.mailer.Send(context.Background(), message); err != nil {
+			a.log.Errorf("could not send %q: %v", message.Subject, err)
+		}
+	}()
+}
+
+// link puts the token in the link template, or appends it when the template has no placeholder.
+func link(template, token string) string {
+	if strings.Contains(template, "{token}") {
+		return strings.ReplaceAll(template, "{token}", token)
+	}
+	if template == "" {
+		return token
+	}
+	return template + token
+}
 
-	return a.accountRepo.Update(ctx, &acct)
+// hours spells out a period in whole hours for an email, e.g. "hour" or "48 hours".
+func hours(period time.Duration) string {
+	if n := int(period.Hours()); n != 1 {
+		return fmt.Sprintf("%d hours", n)
+	}
+	return "hour"
+}
+
+// hashToken returns the hash a one-time token is stored and looked up under.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // Delete will delete the intended account
 func (a *service) Delete(ctx context.Context, id uuid.UUID) error {
-	return a.accountRepo.Delete(ctx, id)
+	if err := a.accountRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := a.authSvc.EndAccountSessions(ctx, id); err != nil {
+		return err
+	}
+	a.notify(ctx, AccountDeleted, accountEvent{ID: id})
+	return nil
 }
 
 func (a *service) List(ctx context.Context) ([]Account, error) {
@@ -152,20 +567,264 @@ func (a *service) Update(ctx context.Context, request UpdateAccountRequest) erro
 	if err := a.userSvc.Update(ctx, updatingUser); err != nil {
 		return err
 	}
+	a.notify(ctx, AccountUpdated, accountEvent{ID: updatingAccount.ID, Role: &updatingAccount.Role})
 	return nil
 }
 
-func (a *service) Authenticate(ctx context.Context, username, password string) (Account, error) {
+func (a *service) Authenticate(ctx context.Context, username, password, ip string) (Account, error) {
+	now := time.Now().UTC()
+	userKey := usernameKey(username)
+	if err := a.checkThrottle(ctx, userKey, true, now); err != nil {
+		return NullAccount, err
+	}
+	if ip != "" {
+		if err := a.checkThrottle(ctx, ipKey(ip), false, now); err != nil {
+			return NullAccount, err
+		}
+	}
+
+	userLockout := AuditEntry{Event: LoginLocked, Username: username, IP: ip}
+	userThrottle, err := a.countAttempt(ctx, userKey, a.lockout.MaxFailures, userLockout, now)
+	if err != nil {
+		return NullAccount, err
+	}
+	ipLockout := AuditEntry{Event: LoginIPLocked, Username: username, IP: ip}
+	var ipThrottle LoginThrottle
+	if ip != "" {
+		if ipThrottle, err = a.countAttempt(ctx, ipKey(ip), a.lockout.MaxFailuresPerIP, ipLockout, now); err != nil {
+			a.refundAttempt(ctx, userKey)
+			return NullAccount, err
+		}
+	}
+
 	acct, err := a.Find(ctx, username)
 	if err != nil {
-		return NullAccount, ErrAccountNotFound
+		err = ErrAccountNotFound
+	} else {
+		err = a.secSvc.VerifyPasswordMatches(acct.Password, password)
 	}
-	if err := a.secSvc.VerifyPasswordMatches(acct.Password, password); err != nil {
+	if err != nil {
+		a.lockSpent(ctx, userKey, userThrottle, a.lockout.MaxFailures, userLockout, now)
+		if ip != "" {
+			a.lockSpent(ctx, ipKey(ip), ipThrottle, a.lockout.MaxFailuresPerIP, ipLockout, now)
+		}
 		return NullAccount, err
 	}
-	acct.LastLogin = time.Now().UTC()
+	// The address is only given back this attempt rather than let off, as an attacker could otherwise log into an
+	// account of their own between guesses.
+	if err := a.accountRepo.DeleteThrottle(ctx, userKey); err != nil {
+		a.log.Errorf("could not reset failed logins of %s: %v", username, err)
+	}
+	if ip != "" {
+		a.refundAttempt(ctx, ipKey(ip))
+	}
+	a.rehash(ctx, &acct, password)
+
+	acct.LastLogin = now
 	if err := a.accountRepo.Update(ctx, &acct); err != nil {
 		return NullAccount, err
 	}
 	return acct, nil
 }
+
+// rehash replaces a password hash made by another algorithm, or with other parameters, than the configured ones,
+// now that the password is at hand. Failing to is logged, as the old hash still works.
+func (a *service) rehash(ctx context.Context, acct *Account, password string) {
+	if !a.secSvc.NeedsRehash(acct.Password) {
+		return
+	}
+	hashedPassword, err := a.secSvc.Hash(password)
+	if err != nil {
+		a.log.Errorf("could not rehash the password of %s: %v", acct.Username, err)
+		return
+	}
+	if err := a.accountRepo.RehashPassword(ctx, acct.ID, acct.Password, hashedPassword); err != nil {
+		a.log.Errorf("could not rehash the password of %s: %v", acct.Username, err)
+		return
+	}
+	acct.Password = hashedPassword
+}
+
+func (a *service) SetPIN(ctx context.Context, accountID uuid.UUID, req PINRequest) error {
+	acct, err := a.FindByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if err := a.secSvc.VerifyPasswordMatches(acct.Password, req.Password); err != nil {
+		return ErrNotAuthorized
+	}
+	if err := a.secSvc.ConfirmationChecker(req.PIN, req.PINConfirm); err != nil {
+		return err
+	}
+	if err := a.secSvc.IsValidPIN(req.PIN); err != nil {
+		return err
+	}
+	hashedPIN, err := a.secSvc.Hash(req.PIN)
+	if err != nil {
+		return err
+	}
+	return a.accountRepo.SetPIN(ctx, accountID, hashedPIN)
+}
+
+func (a *service) ClearPIN(ctx context.Context, accountID uuid.UUID) error {
+	return a.accountRepo.SetPIN(ctx, accountID, "")
+}
+
+func (a *service) PINLogin(ctx context.Context, req PINLoginRequest, ip string) (authentication.Tokens, error) {
+	now := time.Now().UTC()
+	key := pinKey(req.Username)
+	if err := a.checkThrottle(ctx, key, false, now); err != nil {
+		return authentication.Tokens{}, err
+	}
+
+	lockout := AuditEntry{Event: LoginPINLocked, Username: req.Username, IP: ip}
+	throttle, err := a.countAttempt(ctx, key, a.pins.MaxFailures, lockout, now)
+	if err != nil {
+		return authentication.Tokens{}, err
+	}
+
+	acct, err := a.Find(ctx, req.Username)
+	if err != nil || !acct.HasPIN() || a.secSvc.VerifyPasswordMatches(acct.PIN, req.PIN) != nil {
+		a.lockSpent(ctx, key, throttle, a.pins.MaxFailures, lockout, now)
+		return authentication.Tokens{}, ErrInvalidPIN
+	}
+	if err := a.accountRepo.DeleteThrottle(ctx, key); err != nil {
+		a.log.Errorf("could not reset wrong PINs of %s: %v", req.Username, err)
+	}
+	return a.authSvc.StartScopedSession(ctx, acct.ID, acct.Username, int(acct.Role), a.PasswordExpired(&acct),
+		a.pins.Scopes, a.pins.TokenPeriod)
+}
+
+// checkThrottle holds back a login while its username or address is locked, and while a username waits out the
+// delay after its latest failure.
+func (a *service) checkThrottle(ctx context.Context, key string, delayed bool, now time.Time) error {
+	throttle, err := a.accountRepo.FindThrottle(ctx, key)
+	if err != nil {
+		return nil
+	}
+	if throttle.Locked(now) {
+		return &ThrottledError{RetryAfter: throttle.LockedUntil.Sub(now), Locked: true}
+	}
+	if delayed && throttle.LockedUntil == nil && throttle.Failures > 0 {
+		if wait := throttle.LastFailureAt.Add(a.lockout.delay(throttle.Failures)).Sub(now); wait > 0 {
+			return &ThrottledError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// countAttempt counts a login against the key as failed before its password or PIN is checked, so that attempts made
+// at the same time cannot all get past the limit; a login that succeeds then has it taken back. An attempt beyond
+// maxFailures within the window is held back without being checked, and locks the key. Failing to count it is logged
+// rather than returned, so that the login still goes ahead.
+func (a *service) countAttempt(ctx context.Context, key string, maxFailures int, lockout AuditEntry,
+	now time.Time) (LoginThrottle, error) {
+	throttle, err := a.accountRepo.CountFailure(ctx, key, now, a.lockout.Window)
+	if err != nil {
+		a.log.Errorf("could not record failed login for %s: %v", key, err)
+		return LoginThrottle{Key: key}, nil
+	}
+	if throttle.Failures > maxFailures {
+		until := a.lock(ctx, key, throttle, lockout, now)
+		return throttle, &ThrottledError{RetryAfter: until.Sub(now), Locked: true}
+	}
+	return throttle, nil
+}
+
+// lockSpent locks the key once a failed login, counted as the throttle says, reached maxFailures.
+func (a *service) lockSpent(ctx context.Context, key string, throttle LoginThrottle, maxFailures int,
+	lockout AuditEntry, now time.Time) {
+	if throttle.Failures >= maxFailures {
+		a.lock(ctx, key, throttle, lockout, now)
+	}
+}
+
+// lock locks the key for the lockout period, unless it is locked already, and returns until when it is. Of the logins
+// locking it at the same time, only the one that did records it in the audit log.
+func (a *service) lock(ctx context.Context, key string, throttle LoginThrottle, lockout AuditEntry,
+	now time.Time) time.Time {
+	if throttle.Locked(now) {
+		return *throttle.LockedUntil
+	}
+	until := now.Add(a.lockout.LockoutPeriod)
+	locked, err := a.accountRepo.LockThrottle(ctx, key, now, until)
+	if err != nil {
+		a.log.Errorf("could not lock %s: %v", key, err)
+		return until
+	}
+	if locked {
+		lockout.Detail = fmt.Sprintf("%d failed logins; locked until %s", throttle.Failures,
+			until.Format(time.RFC3339))
+		a.Audit(ctx, lockout)
+	}
+	return until
+}
+
+// refundAttempt takes back an attempt counted against the key that turned out not to fail.
+func (a *service) refundAttempt(ctx context.Context, key string) {
+	if err := a.accountRepo.RefundFailure(ctx, key); err != nil {
+		a.log.Errorf("could not take back login attempt for %s: %v", key, err)
+	}
+}
+
+func (a *service) Unlock(ctx context.Context, actor string, req UnlockRequest) error {
+	if req.Username == "" && req.IP == "" {
+		return ErrNothingToUnlock
+	}
+	if req.Username != "" {
+		if err := a.accountRepo.DeleteThrottle(ctx, usernameKey(req.Username)); err != nil {
+			return err
+		}
+		if err := a.accountRepo.DeleteThrottle(ctx, pinKey(req.Username)); err != nil {
+			return err
+		}
+	}
+	if req.IP != "" {
+		if err := a.accountRepo.DeleteThrottle(ctx, ipKey(req.IP)); err != nil {
+			return err
+		}
+	}
+	a.Audit(ctx, AuditEntry{Event: LoginUnlocked, Username: req.Username, IP: req.IP, Actor: actor})
+	return nil
+}
+
+func (a *service) AuditLog(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	return a.accountRepo.ListAuditEntries(ctx, filter, auditLogLimit)
+}
+
+func (a *service) Audit(ctx context.Context, entry AuditEntry) {
+	a.log.Infof("audit: %s username=%q ip=%q actor=%q %s", entry.Event, entry.Username, entry.IP,
+		entry.Actor, entry.Detail)
+	if err := a.accountRepo.CreateAuditEntry(ctx, &entry); err != nil {
+		a.log.Errorf("could not record %s in the audit log: %v", entry.Event, err)
+	}
+}
+
+func usernameKey(username string) string {
+	return "username:" + strings.ToLower(username)
+}
+
+func pinKey(username string) string {
+	return "pin:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Refresh swaps a refresh token for new tokens. The account is looked up again so that the new access token carries
+// its current role, and so that a deleted account cannot be refreshed back to life.
+func (a *service) Refresh(ctx context.Context, refreshToken string) (authentication.Tokens, error) {
+	session, err := a.authSvc.Rotate(ctx, refreshToken)
+	if err != nil {
+		return authentication.Tokens{}, err
+	}
+	acct, err := a.Find(ctx, session.Username)
+	if err != nil || acct.ID != session.AccountID {
+		if err := a.authSvc.EndSession(ctx, session.ID); err != nil {
+			return authentication.Tokens{}, err
+		}
+		return authentication.Tokens{}, ErrAccountNotFound
+	}
+	return a.authSvc.Issue(ctx, session, int(acct.Role), a.PasswordExpired(&acct))
+}
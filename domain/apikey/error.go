@@ -0,0 +1,9 @@
+package apikey
+
+import "errors"
+
+var (
+	ErrKeyNotFound    = errors.New("api key not found")
+	ErrInvalidKey     = errors.New("api key is invalid, expired or revoked")
+	ErrAlreadyRevoked = errors.New("api key is already revoked")
+)
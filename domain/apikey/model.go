@@ -0,0 +1,98 @@
+package apikey
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coquizen/servercarte/domain"
+	"github.com/coquizen/servercarte/domain/authorization"
+)
+
+// APIKey lets a kiosk, a POS terminal or another service call the API without an account. The key itself is only
+// shown once, when it is created; it is stored as a hash and found again through its prefix, which is safe to show
+// and tells keys apart in lists and logs.
+type APIKey struct {
+	domain.Base
+	Name    string `json:"name" gorm:"not null"`
+	Prefix  string `json:"prefix" gorm:"not null;uniqueIndex;size:32"`
+	KeyHash string `json:"-" gorm:"not null"`
+	Scopes  Scopes `json:"scopes" gorm:"not null"`
+	// Device keys belong to a shared device, such as the kitchen tablet, on which staff log in with their PIN.
+	Device     bool       `json:"device" gorm:"not null;default:false"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Usable reports whether the key may still be used.
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Allows reports whether the key was granted the permission.
+func (k *APIKey) Allows(permission authorization.Permission) bool {
+	for _, scope := range k.Scopes {
+		if scope == string(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes lists the permissions a key was granted; a key can do nothing else.
+type Scopes []string
+
+func (s Scopes) Validate() error {
+	for _, scope := range s {
+		if _, err := authorization.PermissionFromText(scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GormDataType stores the scopes as a comma-separated string.
+func (s Scopes) GormDataType() string {
+	return "string"
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+func (s *Scopes) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into api key scopes", value)
+	}
+	*s = nil
+	if text != "" {
+		*s = strings.Split(text, ",")
+	}
+	return nil
+}
+
+// CreateRequest names a new key and grants it its scopes. Keys without an expiry last until they are revoked; device
+// keys need no scopes of their own.
+type CreateRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    Scopes     `json:"scopes"`
+	Device    bool       `json:"device"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreatedKey is a new key along with the key itself, which is not shown again.
+type CreatedKey struct {
+	APIKey
+	Key string `json:"key"`
+}
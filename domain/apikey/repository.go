@@ -0,0 +1,20 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository describes the expected behavior for the data persistence of API keys.
+type Repository interface {
+	List(ctx context.Context) ([]APIKey, error)
+	Find(ctx context.Context, id uuid.UUID) (*APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	Create(ctx context.Context, key *APIKey) error
+	// Revoke marks the key revoked unless it already was, and reports whether it did.
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// Touch records when, and from where, the key was last used.
+	Touch(ctx context.Context, id uuid.UUID, at time.Time, ip string) error
+}
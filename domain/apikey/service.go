@@ -0,0 +1,196 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/authorization"
+)
+
+// KeyPrefix starts every key, so that keys are told apart from access tokens and found by secret scanners.
+const KeyPrefix = "sck_"
+
+// Header carries an API key, as an alternative to sending it as a Bearer token.
+const Header = "X-API-Key"
+
+// Events recorded in the audit log. Entries name the key as "apikey:<prefix>" under username.
+const (
+	KeyCreated = "apikey.created"
+	KeyRevoked = "apikey.revoked"
+	KeyUsed    = "apikey.used"
+)
+
+// encoding writes keys in lowercase letters and digits only, so that they survive being typed into a terminal.
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Auditor records security events; the account service keeps the audit log.
+type Auditor interface {
+	Audit(ctx context.Context, entry account.AuditEntry)
+}
+
+// Service describes the expected behavior for managing API keys and authenticating requests made with them.
+type Service interface {
+	Keys(ctx context.Context) ([]APIKey, error)
+	// Create issues a key with scopes that the creator's role holds itself.
+	Create(ctx context.Context, req CreateRequest, creator string, creatorRole account.AccessLevel) (*CreatedKey, error)
+	Revoke(ctx context.Context, rawID string, actor string) error
+	// Authenticate finds the key a request was made with and records its use, described by detail.
+	Authenticate(ctx context.Context, key string, ip string, detail string) (*APIKey, error)
+}
+
+type service struct {
+	repo     Repository
+	authzSvc authorization.Service
+	auditor  Auditor
+}
+
+// NewService returns a Service that keeps keys in the repository, checking the scopes granted against the roles of
+// authzSvc and recording their use with the auditor.
+func NewService(repo Repository, authzSvc authorization.Service, auditor Auditor) *service {
+	return &service{repo, authzSvc, auditor}
+}
+
+func (s *service) Keys(ctx context.Context) ([]APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *service) Create(ctx context.Context, req CreateRequest, creator string,
+	creatorRole account.AccessLevel) (*CreatedKey, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return &CreatedKey{}, fmt.Errorf("api key name is empty")
+	}
+	if len(req.Scopes) == 0 && !req.Device {
+		return &CreatedKey{}, fmt.Errorf("api key must be granted at least one scope, or be a device key")
+	}
+	if err := req.Scopes.Validate(); err != nil {
+		return &CreatedKey{}, err
+	}
+	for _, scope := range req.Scopes {
+		if !s.authzSvc.Allowed(creatorRole, authorization.Permission(scope)) {
+			return &CreatedKey{}, fmt.Errorf("role %s cannot grant the %s permission it lacks", creatorRole, scope)
+		}
+	}
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return &CreatedKey{}, fmt.Errorf("api key expiry %s is in the past", req.ExpiresAt.Format(time.RFC3339))
+	}
+
+	prefix, err := randomString(5)
+	if err != nil {
+		return &CreatedKey{}, err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return &CreatedKey{}, err
+	}
+	created := CreatedKey{
+		APIKey: APIKey{Name: req.Name, Prefix: KeyPrefix + prefix, Scopes: req.Scopes, Device: req.Device,
+			CreatedBy: creator, ExpiresAt: req.ExpiresAt},
+		Key: KeyPrefix + prefix + "_" + secret,
+	}
+	created.KeyHash = hashKey(created.Key)
+	if err := s.repo.Create(ctx, &created.APIKey); err != nil {
+		return &CreatedKey{}, err
+	}
+	s.auditor.Audit(ctx, account.AuditEntry{Event: KeyCreated, Username: subject(&created.APIKey), Actor: creator,
+		Detail: fmt.Sprintf("%q scopes=%s device=%t", created.Name, strings.Join(created.Scopes, ","), created.Device)})
+	return &created, nil
+}
+
+func (s *service) Revoke(ctx context.Context, rawID string, actor string) error {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return err
+	}
+	key, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	revoked, err := s.repo.Revoke(ctx, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAlreadyRevoked
+	}
+	s.auditor.Audit(ctx, account.AuditEntry{Event: KeyRevoked, Username: subject(key), Actor: actor,
+		Detail: fmt.Sprintf("%q", key.Name)})
+	return nil
+}
+
+func (s *service) Authenticate(ctx context.Context, key string, ip string, detail string) (*APIKey, error) {
+	prefix, ok := prefixOf(key)
+	if !ok {
+		return &APIKey{}, ErrInvalidKey
+	}
+	found, err := s.repo.FindByPrefix(ctx, prefix)
+	if err != nil {
+		return &APIKey{}, ErrInvalidKey
+	}
+	if subtle.ConstantTimeCompare([]byte(found.KeyHash), []byte(hashKey(key))) != 1 {
+		return &APIKey{}, ErrInvalidKey
+	}
+	now := time.Now().UTC()
+	if !found.Usable(now) {
+		return &APIKey{}, ErrInvalidKey
+	}
+	if err := s.repo.Touch(ctx, found.ID, now, ip); err != nil {
+		return &APIKey{}, err
+	}
+	found.LastUsedAt, found.LastUsedIP = &now, ip
+	s.auditor.Audit(ctx, account.AuditEntry{Event: KeyUsed, Username: subject(found), IP: ip, Detail: detail})
+	return found, nil
+}
+
+// FromRequest returns the API key a request was made with, sent as X-API-Key or as a Bearer token.
+func FromRequest(req *http.Request) (string, bool) {
+	if key := req.Header.Get(Header); key != "" {
+		return key, true
+	}
+	bearer := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return bearer, IsKey(bearer)
+}
+
+// IsKey reports whether a credential looks like an API key rather than an access token.
+func IsKey(credential string) bool {
+	return strings.HasPrefix(credential, KeyPrefix)
+}
+
+// prefixOf returns the prefix of a key of the form sck_<prefix>_<secret>.
+func prefixOf(key string) (string, bool) {
+	parts := strings.Split(strings.TrimPrefix(key, KeyPrefix), "_")
+	if !IsKey(key) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return KeyPrefix + parts[0], true
+}
+
+// subject names the key in the audit log.
+func subject(key *APIKey) string {
+	return "apikey:" + key.Prefix
+}
+
+func randomString(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(raw), nil
+}
+
+// hashKey returns the hash a key is stored under.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,198 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/authorization"
+)
+
+// memoryRepository keeps keys in a map by prefix.
+type memoryRepository struct {
+	keys map[string]*APIKey
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{keys: make(map[string]*APIKey)}
+}
+
+func (r *memoryRepository) List(context.Context) ([]APIKey, error) {
+	keys := make([]APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (r *memoryRepository) Find(_ context.Context, id uuid.UUID) (*APIKey, error) {
+	for _, key := range r.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (r *memoryRepository) FindByPrefix(_ context.Context, prefix string) (*APIKey, error) {
+	key, ok := r.keys[prefix]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	stored := *key
+	return &stored, nil
+}
+
+func (r *memoryRepository) Create(_ context.Context, key *APIKey) error {
+	key.ID = uuid.New()
+	stored := *key
+	r.keys[key.Prefix] = &stored
+	return nil
+}
+
+func (r *memoryRepository) Revoke(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	key, err := r.Find(context.Background(), id)
+	if err != nil || key.RevokedAt != nil {
+		return false, err
+	}
+	key.RevokedAt = &at
+	return true, nil
+}
+
+func (r *memoryRepository) Touch(context.Context, uuid.UUID, time.Time, string) error {
+	return nil
+}
+
+// auditLog keeps the entries recorded.
+type auditLog []account.AuditEntry
+
+func (l *auditLog) Audit(_ context.Context, entry account.AuditEntry) {
+	*l = append(*l, entry)
+}
+
+func newTestService(t *testing.T) (*service, *memoryRepository, *auditLog) {
+	authzSvc, err := authorization.NewService(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, audit := newMemoryRepository(), &auditLog{}
+	return NewService(repo, authzSvc, audit), repo, audit
+}
+
+func TestCreate(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		req     CreateRequest
+		role    account.AccessLevel
+		wantErr bool
+	}{
+		{"kiosk key", CreateRequest{Name: "Kiosk", Scopes: Scopes{"order:place"}}, account.Admin, false},
+		{"expiring key", CreateRequest{Name: "Kiosk", Scopes: Scopes{"order:place"}, ExpiresAt: &future},
+			account.Admin, false},
+		{"device key without scopes", CreateRequest{Name: "Kitchen tablet", Device: true}, account.Admin, false},
+		{"scope the creator holds", CreateRequest{Name: "KDS", Scopes: Scopes{"kitchen:operate"}}, account.Employee,
+			false},
+		{"scope broader than the creator's role", CreateRequest{Name: "POS bridge",
+			Scopes: Scopes{"account:manage"}}, account.Employee, true},
+		{"one of the scopes broader than the creator's role", CreateRequest{Name: "POS bridge",
+			Scopes: Scopes{"menu:read_draft", "menu:publish"}}, account.Employee, true},
+		{"unknown scope", CreateRequest{Name: "POS bridge", Scopes: Scopes{"menu:everything"}}, account.Admin, true},
+		{"no scopes", CreateRequest{Name: "POS bridge"}, account.Admin, true},
+		{"no name", CreateRequest{Name: " ", Scopes: Scopes{"order:place"}}, account.Admin, true},
+		{"expired already", CreateRequest{Name: "Kiosk", Scopes: Scopes{"order:place"}, ExpiresAt: &past},
+			account.Admin, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, audit := newTestService(t)
+			created, err := s.Create(context.Background(), tt.req, "manager", tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(repo.keys) != 0 || len(*audit) != 0 {
+					t.Errorf("a refused key was stored or audited")
+				}
+				return
+			}
+			stored := repo.keys[created.Prefix]
+			if stored == nil || stored.KeyHash == "" || stored.KeyHash == created.Key {
+				t.Errorf("key was not stored as a hash: %+v", stored)
+			}
+			if len(*audit) != 1 || (*audit)[0].Event != KeyCreated {
+				t.Errorf("audit log = %+v, want one %s entry", *audit, KeyCreated)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	ctx := context.Background()
+	created, err := s.Create(ctx, CreateRequest{Name: "Kiosk", Scopes: Scopes{"order:place"}}, "manager",
+		account.Admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := s.Create(ctx, CreateRequest{Name: "Old kiosk", Scopes: Scopes{"order:place"}}, "manager",
+		account.Admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revoke(ctx, repo.keys[revoked.Prefix].ID.String(), "manager"); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.Create(ctx, CreateRequest{Name: "Pop-up", Scopes: Scopes{"order:place"}}, "manager",
+		account.Admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lapsed := time.Now().Add(-time.Minute)
+	repo.keys[expired.Prefix].ExpiresAt = &lapsed
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{"valid key", created.Key, nil},
+		{"wrong secret", created.Prefix + "_" + "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", ErrInvalidKey},
+		{"unknown prefix", KeyPrefix + "zzzzzzzz_secret", ErrInvalidKey},
+		{"malformed", "sck_onlyprefix", ErrInvalidKey},
+		{"access token", "eyJhbGciOiJIUzI1NiJ9.e30.sig", ErrInvalidKey},
+		{"revoked key", revoked.Key, ErrInvalidKey},
+		{"expired key", expired.Key, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := s.Authenticate(ctx, tt.key, "10.0.0.7", "GET /api/v1/menus")
+			if err != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (key.LastUsedAt == nil || key.LastUsedIP != "10.0.0.7") {
+				t.Errorf("use of the key was not recorded: %+v", key)
+			}
+		})
+	}
+}
+
+func TestRevokeTwice(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	ctx := context.Background()
+	created, err := s.Create(ctx, CreateRequest{Name: "Kiosk", Scopes: Scopes{"order:place"}}, "manager",
+		account.Admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := repo.keys[created.Prefix].ID.String()
+	if err := s.Revoke(ctx, id, "manager"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revoke(ctx, id, "manager"); err != ErrAlreadyRevoked {
+		t.Errorf("Revoke() error = %v, want %v", err, ErrAlreadyRevoked)
+	}
+}
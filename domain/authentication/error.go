@@ -1,7 +1,11 @@
 package authentication
 
 import "errors"
+
 var (
-	ErrInvalidAccessToken = errors.New("invalid access token")
-	ErrExpiredToken = errors.New("token expired")
+	ErrInvalidAccessToken  = errors.New("invalid access token")
+	ErrExpiredToken        = errors.New("token expired")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRevokedToken        = errors.New("token has been revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; the session has been revoked")
 )
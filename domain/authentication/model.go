@@ -0,0 +1,66 @@
+package authentication
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+// Session is a login and the family of refresh tokens descending from it. Every access token carries the id of its
+// session, so that revoking the session cuts off the access tokens already handed out along with the refresh tokens.
+type Session struct {
+	domain.Base
+	AccountID uuid.UUID  `json:"account_id" gorm:"not null;index"`
+	Username  string     `json:"username" gorm:"not null"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked reports whether the session was ended.
+func (s *Session) Revoked() bool {
+	return s.RevokedAt != nil
+}
+
+// RefreshToken is one link in a session's chain of refresh tokens. Only a hash of the token is stored. A token is
+// used once: refreshing hands out its successor, and presenting a used token again means it was stolen, which revokes
+// the whole session.
+type RefreshToken struct {
+	domain.Base
+	SessionID uuid.UUID  `json:"session_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// Tokens are handed to a client when it logs in or refreshes.
+type Tokens struct {
+	AccessToken   string `json:"token"`
+	Expiry        int64  `json:"expiry"`
+	RefreshToken  string `json:"refresh_token,omitempty"`
+	RefreshExpiry int64  `json:"refresh_expiry,omitempty"`
+	// PasswordExpired tells the client to have the user choose a new password; until then the access token only
+	// grants routes that need no permission, such as /password/change.
+	PasswordExpired bool `json:"password_expired"`
+	// Scopes are the only permissions a scoped access token grants.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// KeySet lists the public keys access tokens can be verified with, as a JSON Web Key Set (RFC 7517). It is empty when
+// tokens are signed with a shared secret.
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey is a public key in JSON Web Key form.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
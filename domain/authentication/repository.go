@@ -0,0 +1,20 @@
+package authentication
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository describes the expected behavior for the data persistence of sessions and their refresh tokens.
+type Repository interface {
+	CreateSession(ctx context.Context, session *Session) error
+	FindSession(ctx context.Context, session *Session) error
+	RevokeSession(ctx context.Context, sessionID uuid.UUID, at time.Time) error
+	RevokeAccountSessions(ctx context.Context, accountID uuid.UUID, at time.Time) error
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// UseRefreshToken marks the token used unless it already was, and reports whether this call was the one to do so.
+	UseRefreshToken(ctx context.Context, token *RefreshToken, at time.Time) (bool, error)
+}
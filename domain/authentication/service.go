@@ -2,34 +2,220 @@ package authentication
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const CtxAuthenticationKey = "auth"
 
+// DefaultRefreshPeriod is how long a refresh token stays valid when no period is configured.
+const DefaultRefreshPeriod = 7 * 24 * time.Hour
+
 // CustomClaims are the custom Claims that identification authentication mechanism will certify.
 type CustomClaims struct {
-	AccountID 	uuid.UUID
-	Username 	string
-	Role 		int
-	Expiry    	int64
+	AccountID uuid.UUID
+	SessionID uuid.UUID
+	Username  string
+	Role      int
+	Expiry    int64
+	// PasswordExpired is set when the account must choose a new password before doing anything else.
+	PasswordExpired bool
+	// APIKeyID is set when the request was made with an API key rather than an access token, in which case there is
+	// no account or session. It is never part of a token.
+	APIKeyID uuid.UUID `json:"-"`
+	// Scopes, when set, are the only permissions the request may use, e.g. those of a PIN login on a shared device.
+	Scopes []string `json:",omitempty"`
 }
 
-// Service represents the minimum methods that the authentication system must implement
-type Service interface {
-	GenerateToken(ctx context.Context, accountID uuid.UUID, username string, accessLevel int) (string, error)
+// Restricted reports whether the request may only use the permissions its scopes grant, rather than those of its
+// role.
+func (c CustomClaims) Restricted() bool {
+	return c.APIKeyID != uuid.Nil || c.Scopes != nil
+}
+
+// Framework represents the minimum methods that the technology issuing access tokens must implement
+type Framework interface {
+	// GenerateToken signs the claims, setting their expiry unless they already have one.
+	GenerateToken(ctx context.Context, claims *CustomClaims) (string, error)
 	ExtractToken(req *http.Request) (string, error)
 	ParseTokenClaims(tokenString string) (CustomClaims, error)
+	// KeySet lists the public keys that tokens are verified with, for other services to verify them too.
+	KeySet() KeySet
+}
+
+// Service issues short-lived access tokens along with rotating refresh tokens, and keeps track of the sessions they
+// belong to so that they can be revoked before they expire.
+type Service interface {
+	Framework
+	// StartSession logs an account in, starting a new family of refresh tokens.
+	StartSession(ctx context.Context, accountID uuid.UUID, username string, accessLevel int,
+		passwordExpired bool) (Tokens, error)
+	// Rotate spends a refresh token and returns the session it belongs to, to be handed to Issue once the account
+	// has been looked up again. Spending a token twice revokes its session.
+	Rotate(ctx context.Context, refreshToken string) (*Session, error)
+	// Issue hands out a new access token and refresh token for the session.
+	Issue(ctx context.Context, session *Session, accessLevel int, passwordExpired bool) (Tokens, error)
+	// StartScopedSession logs an account in with an access token that only grants the scopes given and lasts the
+	// period given. No refresh token is handed out, so the account has to log in again once it expires.
+	StartScopedSession(ctx context.Context, accountID uuid.UUID, username string, accessLevel int,
+		passwordExpired bool, scopes []string, period time.Duration) (Tokens, error)
+	// Authorize parses an access token and checks that its session has not been revoked.
+	Authorize(ctx context.Context, tokenString string) (CustomClaims, error)
+	EndSession(ctx context.Context, sessionID uuid.UUID) error
+	EndAccountSessions(ctx context.Context, accountID uuid.UUID) error
 }
 
 type authentication struct {
-	Service
+	Framework
+	repo          Repository
+	refreshPeriod time.Duration
 }
 
 // NewService returns an authentication - compliant service instance. Must satisfy the Service interface.
-func NewService(authSvc  Service) *authentication {
-	return &authentication{authSvc}
+func NewService(framework Framework, repo Repository, refreshPeriod time.Duration) *authentication {
+	if refreshPeriod <= 0 {
+		refreshPeriod = DefaultRefreshPeriod
+	}
+	return &authentication{framework, repo, refreshPeriod}
+}
+
+func (a *authentication) StartSession(ctx context.Context, accountID uuid.UUID, username string,
+	accessLevel int, passwordExpired bool) (Tokens, error) {
+	session := Session{AccountID: accountID, Username: username}
+	if err := a.repo.CreateSession(ctx, &session); err != nil {
+		return Tokens{}, err
+	}
+	return a.Issue(ctx, &session, accessLevel, passwordExpired)
+}
+
+func (a *authentication) StartScopedSession(ctx context.Context, accountID uuid.UUID, username string,
+	accessLevel int, passwordExpired bool, scopes []string, period time.Duration) (Tokens, error) {
+	session := Session{AccountID: accountID, Username: username}
+	if err := a.repo.CreateSession(ctx, &session); err != nil {
+		return Tokens{}, err
+	}
+	claims := CustomClaims{
+		AccountID:       session.AccountID,
+		SessionID:       session.ID,
+		Username:        session.Username,
+		Role:            accessLevel,
+		Expiry:          time.Now().Add(period).Unix(),
+		PasswordExpired: passwordExpired,
+		Scopes:          append([]string{}, scopes...),
+	}
+	accessToken, err := a.GenerateToken(ctx, &claims)
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{AccessToken: accessToken, Expiry: claims.Expiry, PasswordExpired: passwordExpired,
+		Scopes: claims.Scopes}, nil
+}
+
+func (a *authentication) Rotate(ctx context.Context, refreshToken string) (*Session, error) {
+	token, err := a.repo.FindRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return &Session{}, ErrInvalidRefreshToken
+	}
+	session := Session{}
+	session.ID = token.SessionID
+	if err := a.repo.FindSession(ctx, &session); err != nil {
+		return &Session{}, ErrInvalidRefreshToken
+	}
+	if session.Revoked() {
+		return &Session{}, ErrRevokedToken
+	}
+
+	now := time.Now().UTC()
+	fresh, err := a.repo.UseRefreshToken(ctx, token, now)
+	if err != nil {
+		return &Session{}, err
+	}
+	if !fresh {
+		// Someone holds on to a token that was already swapped for a new one; there is no telling whether that is
+		// the client or a thief, so neither keeps the session.
+		if err := a.repo.RevokeSession(ctx, session.ID, now); err != nil {
+			return &Session{}, err
+		}
+		return &Session{}, ErrRefreshTokenReused
+	}
+	if now.After(token.ExpiresAt) {
+		return &Session{}, ErrExpiredToken
+	}
+	return &session, nil
+}
+
+func (a *authentication) Issue(ctx context.Context, session *Session, accessLevel int,
+	passwordExpired bool) (Tokens, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Tokens{}, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+	token := RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().UTC().Add(a.refreshPeriod),
+	}
+	if err := a.repo.CreateRefreshToken(ctx, &token); err != nil {
+		return Tokens{}, err
+	}
+
+	claims := CustomClaims{
+		AccountID:       session.AccountID,
+		SessionID:       session.ID,
+		Username:        session.Username,
+		Role:            accessLevel,
+		PasswordExpired: passwordExpired,
+	}
+	accessToken, err := a.GenerateToken(ctx, &claims)
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{
+		AccessToken:     accessToken,
+		Expiry:          claims.Expiry,
+		RefreshToken:    refreshToken,
+		RefreshExpiry:   token.ExpiresAt.Unix(),
+		PasswordExpired: passwordExpired,
+	}, nil
+}
+
+func (a *authentication) Authorize(ctx context.Context, tokenString string) (CustomClaims, error) {
+	claims, err := a.ParseTokenClaims(tokenString)
+	if err != nil {
+		return CustomClaims{}, err
+	}
+	if claims.SessionID == uuid.Nil {
+		return CustomClaims{}, ErrInvalidAccessToken
+	}
+	session := Session{}
+	session.ID = claims.SessionID
+	if err := a.repo.FindSession(ctx, &session); err != nil {
+		return CustomClaims{}, ErrRevokedToken
+	}
+	if session.Revoked() {
+		return CustomClaims{}, ErrRevokedToken
+	}
+	return claims, nil
+}
+
+func (a *authentication) EndSession(ctx context.Context, sessionID uuid.UUID) error {
+	return a.repo.RevokeSession(ctx, sessionID, time.Now().UTC())
 }
 
+// EndAccountSessions logs an account out everywhere, e.g. when it is deleted.
+func (a *authentication) EndAccountSessions(ctx context.Context, accountID uuid.UUID) error {
+	return a.repo.RevokeAccountSessions(ctx, accountID, time.Now().UTC())
+}
+
+// hashToken returns the hash a refresh token is stored and looked up under.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package authorization
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission names an action a route is guarded by, as resource:action.
+type Permission string
+
+const (
+	// ReadDraftMenu allows viewing unpublished menus, their versions and exports.
+	ReadDraftMenu Permission = "menu:read_draft"
+	// WriteMenu allows creating, editing and deleting sections, items and what hangs off them.
+	WriteMenu Permission = "menu:write"
+	// WritePrice allows setting prices, which routes that take a price require on top of WriteMenu.
+	WritePrice Permission = "menu:write_price"
+	// ToggleActive allows marking sections and items active or inactive, e.g. sold out.
+	ToggleActive Permission = "menu:toggle_active"
+	// PublishMenu allows publishing the draft and rolling back to an earlier version.
+	PublishMenu Permission = "menu:publish"
+	// ManageAccounts allows managing accounts and inspecting roles.
+	ManageAccounts Permission = "account:manage"
+	// ManageWebhooks allows managing webhook subscriptions.
+	ManageWebhooks Permission = "webhook:manage"
+	// ManageAPIKeys allows creating and revoking API keys.
+	ManageAPIKeys Permission = "apikey:manage"
+	// PlaceOrder allows filling carts and placing, viewing and cancelling one's own orders.
+	PlaceOrder Permission = "order:place"
+	// ManageOrders allows viewing and cancelling everyone's orders.
+	ManageOrders Permission = "order:manage"
+	// ManagePromotions allows creating, changing and deleting promotions.
+	ManagePromotions Permission = "promotion:manage"
+	// OperateKitchen allows following and moving on the tickets of the kitchen's prep stations.
+	OperateKitchen Permission = "kitchen:operate"
+)
+
+// Permissions lists every known permission.
+var Permissions = []Permission{ReadDraftMenu, WriteMenu, WritePrice, ToggleActive, PublishMenu, ManageAccounts,
+	ManageWebhooks, ManageAPIKeys, PlaceOrder, ManageOrders, ManagePromotions,
+	OperateKitchen}
+
+// PermissionFromText parses the name of a known permission.
+func PermissionFromText(text string) (Permission, error) {
+	for _, permission := range Permissions {
+		if string(permission) == strings.TrimSpace(text) {
+			return permission, nil
+		}
+	}
+	return "", fmt.Errorf("unknown permission %q", text)
+}
+
+// Role is how a role is defined: the permissions granted to it directly, and the roles whose permissions it inherits.
+type Role struct {
+	Inherits    []string     `json:"inherits"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// DefaultRoles are the roles used for any role the configuration does not define. Admins can do everything employees
+// can, employees can do everything guests can as well as look at the draft, mark things sold out, see to orders and
+// work the kitchen, and guests can order.
+var DefaultRoles = map[string]Role{
+	"admin": {
+		Inherits: []string{"employee"},
+		Permissions: []Permission{WriteMenu, WritePrice, PublishMenu, ManageAccounts, ManageWebhooks, ManageAPIKeys,
+			ManagePromotions},
+	},
+	"employee": {
+		Inherits:    []string{"guest"},
+		Permissions: []Permission{ReadDraftMenu, ToggleActive, ManageOrders, OperateKitchen},
+	},
+	"guest": {
+		Permissions: []Permission{PlaceOrder},
+	},
+}
+
+// Grant describes a role as resolved: its definition along with every permission it ends up with.
+type Grant struct {
+	Name        string       `json:"name"`
+	Level       int          `json:"level"`
+	Inherits    []string     `json:"inherits"`
+	Permissions []Permission `json:"permissions"`
+	Effective   []Permission `json:"effective_permissions"`
+}
@@ -0,0 +1,90 @@
+package authorization
+
+import (
+	"fmt"
+
+	"github.com/coquizen/servercarte/domain/account"
+)
+
+// Service decides which roles hold which permissions.
+type Service interface {
+	Allowed(role account.AccessLevel, permission Permission) bool
+	Roles() []Grant
+}
+
+type service struct {
+	grants []Grant
+	// effective holds the resolved permissions of each access level.
+	effective map[account.AccessLevel]map[Permission]bool
+}
+
+// NewService resolves the roles, falling back to DefaultRoles for each role left undefined. It fails on unknown
+// roles and on roles that end up inheriting from themselves.
+func NewService(roles map[string]Role) (*service, error) {
+	definitions := make(map[string]Role, len(DefaultRoles))
+	for name, role := range DefaultRoles {
+		definitions[name] = role
+	}
+	for name, role := range roles {
+		if _, err := account.AccessLevelFromName(name); err != nil {
+			return &service{}, err
+		}
+		definitions[name] = role
+	}
+
+	s := service{effective: make(map[account.AccessLevel]map[Permission]bool)}
+	for _, level := range account.AccessLevels {
+		name := level.String()
+		effective := make(map[Permission]bool)
+		if err := resolve(definitions, name, effective, map[string]bool{}); err != nil {
+			return &service{}, err
+		}
+		s.effective[level] = effective
+
+		grant := Grant{Name: name, Level: int(level), Inherits: definitions[name].Inherits, Effective: []Permission{},
+			Permissions: definitions[name].Permissions}
+		for _, permission := range Permissions {
+			if effective[permission] {
+				grant.Effective = append(grant.Effective, permission)
+			}
+		}
+		s.grants = append(s.grants, grant)
+	}
+	return &s, nil
+}
+
+// resolve adds the permissions of the named role, and of the roles it inherits, to effective.
+func resolve(definitions map[string]Role, name string, effective map[Permission]bool, visiting map[string]bool) error {
+	role, ok := definitions[name]
+	if !ok {
+		return fmt.Errorf("unknown role %q", name)
+	}
+	if visiting[name] {
+		return fmt.Errorf("role %q inherits from itself", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	for _, permission := range role.Permissions {
+		if _, err := PermissionFromText(string(permission)); err != nil {
+			return fmt.Errorf("role %q: %v", name, err)
+		}
+		effective[permission] = true
+	}
+	for _, parent := range role.Inherits {
+		if err := resolve(definitions, parent, effective, visiting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Allowed reports whether the role holds the permission.
+func (s *service) Allowed(role account.AccessLevel, permission Permission) bool {
+	return s.effective[role][permission]
+}
+
+// Roles lists every role with the permissions it holds.
+func (s *service) Roles() []Grant {
+	return s.grants
+}
@@ -15,8 +15,11 @@ type Base struct {
 	DeletedAt *time.Time `gorm:"index"`
 }
 
-// BeforeCreate generated a UUID before the creation of the row.
+// BeforeCreate generated a UUID before the creation of the row, unless one has already been assigned.
 func (b *Base) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID != uuid.Nil {
+		return nil
+	}
 	b.ID, err = uuid.NewRandom()
 	return err
-}
\ No newline at end of file
+}
@@ -0,0 +1,11 @@
+package kitchen
+
+import "errors"
+
+var (
+	ErrTicketNotFound    = errors.New("kitchen ticket not found")
+	ErrUnknownStation    = errors.New("unknown prep station")
+	ErrInvalidTransition = errors.New("ticket cannot move to that status")
+	ErrTicketVoided      = errors.New("ticket was voided")
+	ErrAlreadyRouted     = errors.New("order was already sent to the kitchen")
+)
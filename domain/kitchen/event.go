@@ -0,0 +1,43 @@
+package kitchen
+
+import (
+	"context"
+	"time"
+)
+
+// EventType names a kind of change to the tickets of a station.
+type EventType string
+
+const (
+	TicketCreated EventType = "ticket.created"
+	TicketUpdated EventType = "ticket.updated"
+	TicketVoided  EventType = "ticket.voided"
+	// KitchenReset tells listeners that events they asked to resume from are no longer known, and that they should
+	// fetch the station's tickets again.
+	KitchenReset EventType = "kitchen.reset"
+)
+
+// Event describes a ticket arriving at a station or changing there, and carries the ticket as it was saved.
+type Event struct {
+	// ID increases with every event published, whatever its station, so that a listener can resume after the last
+	// event it saw.
+	ID      uint64    `json:"event_id"`
+	Type    EventType `json:"type"`
+	At      time.Time `json:"at"`
+	Station string    `json:"station"`
+	Ticket  *Ticket   `json:"ticket,omitempty"`
+}
+
+// EventBus fans ticket changes out to the displays of each station. Publish assigns the event its ID and time.
+// Subscribe first replays the station's events published after lastEventID, or a KitchenReset when those are no
+// longer known, then delivers its new events until ctx is done, at which point the channel is closed. A listener that
+// falls too far behind has its channel closed early and is expected to subscribe again from the last event it
+// received.
+type EventBus interface {
+	Publish(Event)
+	Subscribe(ctx context.Context, station string, lastEventID uint64) (<-chan Event, error)
+}
+
+func ticketEvent(eventType EventType, ticket *Ticket) Event {
+	return Event{Type: eventType, Station: ticket.Station, Ticket: ticket}
+}
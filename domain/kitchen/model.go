@@ -0,0 +1,148 @@
+package kitchen
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+// Status is where a ticket stands at its station.
+type Status string
+
+const (
+	// New tickets have just come in and nobody has started on them.
+	New Status = "new"
+	// InProgress tickets are being prepared.
+	InProgress Status = "in_progress"
+	// Ready tickets are waiting to be taken to the guest.
+	Ready Status = "ready"
+	// Bumped tickets were cleared off the station's display.
+	Bumped Status = "bumped"
+)
+
+// stages orders the statuses a ticket moves through.
+var stages = map[Status]int{New: 0, InProgress: 1, Ready: 2, Bumped: 3}
+
+func (s Status) Validate() error {
+	if _, ok := stages[s]; !ok {
+		return fmt.Errorf("unknown ticket status %q", s)
+	}
+	return nil
+}
+
+// Ticket is the part of a placed order one prep station makes. It only moves forward, though it may skip statuses,
+// and records when it reached each for reporting on how long the kitchen takes. Tickets of orders that are cancelled
+// before they are bumped are voided.
+type Ticket struct {
+	domain.Base
+	OrderID    uuid.UUID    `json:"order_id" gorm:"not null;index"`
+	Station    string       `json:"station" gorm:"not null;size:64;index"`
+	Status     Status       `json:"status" gorm:"not null;index"`
+	Notes      *string      `json:"notes,omitempty"`
+	Items      []TicketItem `json:"items" gorm:"foreignKey:TicketID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ReceivedAt time.Time    `json:"received_at" gorm:"not null;index"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	ReadyAt    *time.Time   `json:"ready_at,omitempty"`
+	BumpedAt   *time.Time   `json:"bumped_at,omitempty"`
+	VoidedAt   *time.Time   `json:"voided_at,omitempty"`
+}
+
+// RoutedOrder records that an order was sent to the kitchen, stored along with its tickets, so that an order is never
+// sent twice, even when it makes no ticket at all.
+type RoutedOrder struct {
+	OrderID  uuid.UUID `json:"order_id" gorm:"primaryKey"`
+	RoutedAt time.Time `json:"routed_at" gorm:"not null"`
+}
+
+// TicketItem is a line item of the order as the station needs it: what to make, how many, and with what.
+type TicketItem struct {
+	domain.Base
+	TicketID    uuid.UUID `json:"ticket_id" gorm:"not null;index"`
+	LineItemID  uuid.UUID `json:"line_item_id" gorm:"not null"`
+	ItemID      uuid.UUID `json:"item_id" gorm:"not null"`
+	Title       string    `json:"title" gorm:"not null"`
+	VariantName string    `json:"variant_name"`
+	Quantity    uint      `json:"quantity" gorm:"not null"`
+	Notes       *string   `json:"notes,omitempty"`
+	// Selections lists the add-ons, condiments and modifier options picked, e.g. "Everything, Scallion Cream Cheese".
+	Selections string `json:"selections"`
+}
+
+// advance moves the ticket on to the status, noting the time it got there.
+func (t *Ticket) advance(status Status, at time.Time) error {
+	if err := status.Validate(); err != nil {
+		return err
+	}
+	if t.VoidedAt != nil {
+		return ErrTicketVoided
+	}
+	if stages[status] <= stages[t.Status] {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, t.Status, status)
+	}
+	t.Status = status
+	switch status {
+	case InProgress:
+		t.StartedAt = &at
+	case Ready:
+		t.ReadyAt = &at
+	case Bumped:
+		t.BumpedAt = &at
+	}
+	return nil
+}
+
+// Routing lists the prep stations of the kitchen, and the one items that have none are sent to. Without a default
+// station such items get no ticket, e.g. drinks poured at the counter.
+type Routing struct {
+	Stations       []string
+	DefaultStation string
+}
+
+func (r *Routing) Validate() error {
+	seen := make(map[string]bool, len(r.Stations))
+	for _, station := range r.Stations {
+		if station == "" {
+			return errors.New("prep station needs a name")
+		}
+		if seen[station] {
+			return fmt.Errorf("prep station %q is listed twice", station)
+		}
+		seen[station] = true
+	}
+	if r.DefaultStation != "" && !seen[r.DefaultStation] {
+		return fmt.Errorf("%w: default station %q", ErrUnknownStation, r.DefaultStation)
+	}
+	return nil
+}
+
+// PrepStations lists the stations sections and items may be sent to.
+func (r *Routing) PrepStations() []string {
+	return append([]string{}, r.Stations...)
+}
+
+// known reports whether the station is one of the kitchen's.
+func (r *Routing) known(station string) bool {
+	for _, known := range r.Stations {
+		if known == station {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter narrows a list of tickets down. Without a status it only takes the tickets still on display, that is those
+// neither bumped nor voided; Since limits it to tickets received from then on.
+type Filter struct {
+	Station string     `form:"station"`
+	Status  Status     `form:"status"`
+	Since   *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// StatusRequest moves a ticket on to a status.
+type StatusRequest struct {
+	Status Status `json:"status" binding:"required"`
+}
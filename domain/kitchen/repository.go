@@ -0,0 +1,21 @@
+package kitchen
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository describes the expected behavior for the data persistence of kitchen tickets.
+type Repository interface {
+	// CreateTickets records that the order was routed and stores its tickets together, or none of them. It answers
+	// ErrAlreadyRouted when the order was routed before.
+	CreateTickets(context.Context, uuid.UUID, []Ticket) error
+	// RoutedOrders reports which of the orders were routed already.
+	RoutedOrders(context.Context, []uuid.UUID) (map[uuid.UUID]bool, error)
+	ListTickets(context.Context, Filter, int) ([]Ticket, error)
+	OrderTickets(context.Context, uuid.UUID) ([]Ticket, error)
+	FindTicket(context.Context, *Ticket) error
+	// UpdateTicket saves the status and times of a ticket, leaving its items alone.
+	UpdateTicket(context.Context, *Ticket) error
+}
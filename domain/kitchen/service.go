@@ -0,0 +1,276 @@
+package kitchen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/order"
+)
+
+// listLimit is how many tickets Tickets returns.
+const listLimit = 200
+
+const (
+	// retryInterval is how often Run looks for placed orders that could not be routed when they were placed.
+	retryInterval = time.Minute
+	// retryWindow is how far back Run looks; orders placed before then are left alone, as the kitchen is long past
+	// them.
+	retryWindow = 12 * time.Hour
+)
+
+// Menu is where the kitchen looks up which station makes an item; the menu service provides it.
+type Menu interface {
+	PublishedMenus(ctx context.Context, filter menu.MenuFilter) (*[]menu.Section, error)
+}
+
+// Orders is where the kitchen finds the orders it missed; the order service provides it.
+type Orders interface {
+	Orders(ctx context.Context, filter order.Filter) ([]order.Order, error)
+}
+
+// Service describes the expected behavior for routing orders to the kitchen's prep stations and following the
+// tickets there. It is told about orders as an order.Notifier.
+type Service interface {
+	Stations() []string
+	Tickets(ctx context.Context, filter Filter) ([]Ticket, error)
+	TicketByID(ctx context.Context, rawID string) (*Ticket, error)
+	// Advance moves a ticket on to a later status.
+	Advance(ctx context.Context, rawID string, status Status) (*Ticket, error)
+	// Events streams the ticket changes of a station, for its display.
+	Events(ctx context.Context, station string, lastEventID uint64) (<-chan Event, error)
+	Notify(ctx context.Context, eventType string, data interface{}) error
+	// Run routes the placed orders that could not be routed when they were placed, e.g. while the database was
+	// unavailable, until ctx is done.
+	Run(ctx context.Context, orders Orders)
+}
+
+type service struct {
+	repo    Repository
+	menu    Menu
+	events  EventBus
+	routing Routing
+	log     domain.Logger
+}
+
+// NewService returns a Service that sends the line items of placed orders to the stations the routing and the menu
+// say.
+func NewService(repo Repository, menu Menu, events EventBus, routing Routing, log domain.Logger) (*service, error) {
+	if err := routing.Validate(); err != nil {
+		return nil, err
+	}
+	return &service{repo, menu, events, routing, log}, nil
+}
+
+func (s *service) Stations() []string {
+	return s.routing.PrepStations()
+}
+
+func (s *service) Tickets(ctx context.Context, filter Filter) ([]Ticket, error) {
+	if filter.Station != "" && !s.routing.known(filter.Station) {
+		return []Ticket{}, fmt.Errorf("%w: %q", ErrUnknownStation, filter.Station)
+	}
+	if filter.Status != "" {
+		if err := filter.Status.Validate(); err != nil {
+			return []Ticket{}, err
+		}
+	}
+	return s.repo.ListTickets(ctx, filter, listLimit)
+}
+
+func (s *service) TicketByID(ctx context.Context, rawID string) (*Ticket, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &Ticket{}, err
+	}
+	ticket := Ticket{}
+	ticket.ID = id
+	if err := s.repo.FindTicket(ctx, &ticket); err != nil {
+		return &Ticket{}, err
+	}
+	return &ticket, nil
+}
+
+func (s *service) Advance(ctx context.Context, rawID string, status Status) (*Ticket, error) {
+	ticket, err := s.TicketByID(ctx, rawID)
+	if err != nil {
+		return ticket, err
+	}
+	if err := ticket.advance(status, time.Now().UTC()); err != nil {
+		return &Ticket{}, err
+	}
+	if err := s.repo.UpdateTicket(ctx, ticket); err != nil {
+		return &Ticket{}, err
+	}
+	s.events.Publish(ticketEvent(TicketUpdated, ticket))
+	return ticket, nil
+}
+
+func (s *service) Events(ctx context.Context, station string, lastEventID uint64) (<-chan Event, error) {
+	if !s.routing.known(station) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStation, station)
+	}
+	return s.events.Subscribe(ctx, station, lastEventID)
+}
+
+// Notify sends placed orders to the kitchen and voids the tickets of cancelled ones.
+func (s *service) Notify(ctx context.Context, eventType string, data interface{}) error {
+	placed, ok := data.(*order.Order)
+	if !ok {
+		return nil
+	}
+	switch eventType {
+	case order.OrderPlaced:
+		return s.route(ctx, placed)
+	case order.OrderCancelled:
+		return s.void(ctx, placed)
+	}
+	return nil
+}
+
+func (s *service) Run(ctx context.Context, orders Orders) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+	for {
+		s.retry(ctx, orders)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retry routes the orders placed within the retry window that were not routed yet.
+func (s *service) retry(ctx context.Context, orders Orders) {
+	since := time.Now().UTC().Add(-retryWindow)
+	placed, err := orders.Orders(ctx, order.Filter{Status: order.Placed, PlacedSince: &since})
+	if err != nil {
+		s.log.Errorf("kitchen: could not look up placed orders: %v", err)
+		return
+	}
+	if len(placed) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(placed))
+	for _, o := range placed {
+		ids = append(ids, o.ID)
+	}
+	routed, err := s.repo.RoutedOrders(ctx, ids)
+	if err != nil {
+		s.log.Errorf("kitchen: could not look up routed orders: %v", err)
+		return
+	}
+	// Orders are listed newest first, and are routed oldest first.
+	for i := len(placed) - 1; i >= 0; i-- {
+		if routed[placed[i].ID] {
+			continue
+		}
+		if err := s.route(ctx, &placed[i]); err != nil {
+			s.log.Errorf("kitchen: could not route order %s: %v", placed[i].ID, err)
+		}
+	}
+}
+
+// route splits an order into one ticket per station, each listing the station's line items in the order's order. An
+// order is only routed once, even when Run and Notify route it at the same time.
+func (s *service) route(ctx context.Context, placed *order.Order) error {
+	stations, err := s.itemStations(ctx)
+	if err != nil {
+		return err
+	}
+	receivedAt := time.Now().UTC()
+	var tickets []Ticket
+	index := make(map[string]int)
+	for _, line := range placed.Lines {
+		station, ok := stations[line.ItemID]
+		if !ok || station == "" {
+			station = s.routing.DefaultStation
+		}
+		if station == "" {
+			continue
+		}
+		i, ok := index[station]
+		if !ok {
+			i = len(tickets)
+			index[station] = i
+			tickets = append(tickets, Ticket{OrderID: placed.ID, Station: station, Status: New, Notes: placed.Notes,
+				ReceivedAt: receivedAt})
+		}
+		tickets[i].Items = append(tickets[i].Items, ticketItem(line))
+	}
+	if err := s.repo.CreateTickets(ctx, placed.ID, tickets); err != nil {
+		if errors.Is(err, ErrAlreadyRouted) {
+			return nil
+		}
+		// Storing the tickets fails as well when someone else stored the order's at the same time.
+		if routed, routedErr := s.repo.RoutedOrders(ctx, []uuid.UUID{placed.ID}); routedErr == nil && routed[placed.ID] {
+			return nil
+		}
+		return err
+	}
+	for i := range tickets {
+		s.events.Publish(ticketEvent(TicketCreated, &tickets[i]))
+	}
+	return nil
+}
+
+// itemStations works out the station of every item on the published menu, inherited from the sections above it
+// unless it has its own. Items that cannot be ordered right now are routed all the same, since the order was placed.
+func (s *service) itemStations(ctx context.Context) (map[uuid.UUID]string, error) {
+	menus, err := s.menu.PublishedMenus(ctx, menu.MenuFilter{})
+	if err != nil {
+		return nil, err
+	}
+	stations := make(map[uuid.UUID]string)
+	var walk func(sections []menu.Section, station string)
+	walk = func(sections []menu.Section, station string) {
+		for _, section := range sections {
+			sectionStation := section.StationOr(station)
+			walk(section.SubSections, sectionStation)
+			for _, item := range section.Items {
+				stations[item.ID] = item.StationOr(sectionStation)
+			}
+		}
+	}
+	walk(*menus, "")
+	return stations, nil
+}
+
+// void takes the tickets of a cancelled order off the displays, unless they were bumped already.
+func (s *service) void(ctx context.Context, cancelled *order.Order) error {
+	tickets, err := s.repo.OrderTickets(ctx, cancelled.ID)
+	if err != nil {
+		return err
+	}
+	voidedAt := time.Now().UTC()
+	for i := range tickets {
+		ticket := &tickets[i]
+		if ticket.Status == Bumped || ticket.VoidedAt != nil {
+			continue
+		}
+		ticket.VoidedAt = &voidedAt
+		if err := s.repo.UpdateTicket(ctx, ticket); err != nil {
+			s.log.Errorf("could not void ticket %s of order %s: %v", ticket.ID, cancelled.ID, err)
+			continue
+		}
+		s.events.Publish(ticketEvent(TicketVoided, ticket))
+	}
+	return nil
+}
+
+// ticketItem copies a line item onto a ticket.
+func ticketItem(line order.LineItem) TicketItem {
+	selections := make([]string, 0, len(line.Selections))
+	for _, selection := range line.Selections {
+		selections = append(selections, selection.Title)
+	}
+	return TicketItem{LineItemID: line.ID, ItemID: line.ItemID, Title: line.Title, VariantName: line.VariantName,
+		Quantity: line.Quantity, Notes: line.Notes, Selections: strings.Join(selections, ", ")}
+}
@@ -0,0 +1,266 @@
+package kitchen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/order"
+)
+
+// memoryRepository keeps tickets in memory. createErr, when set, is what storing tickets fails with; routedByOther
+// makes the order count as routed once storing has failed, as when another request routed it at the same time.
+type memoryRepository struct {
+	routed        map[uuid.UUID]bool
+	tickets       []Ticket
+	createErr     error
+	routedByOther bool
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{routed: make(map[uuid.UUID]bool)}
+}
+
+func (r *memoryRepository) CreateTickets(_ context.Context, orderID uuid.UUID, tickets []Ticket) error {
+	if r.createErr != nil {
+		if r.routedByOther {
+			r.routed[orderID] = true
+		}
+		return r.createErr
+	}
+	if r.routed[orderID] {
+		return ErrAlreadyRouted
+	}
+	r.routed[orderID] = true
+	for i := range tickets {
+		tickets[i].ID = uuid.New()
+	}
+	r.tickets = append(r.tickets, tickets...)
+	return nil
+}
+
+func (r *memoryRepository) RoutedOrders(_ context.Context, orderIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	routed := make(map[uuid.UUID]bool)
+	for _, id := range orderIDs {
+		if r.routed[id] {
+			routed[id] = true
+		}
+	}
+	return routed, nil
+}
+
+func (r *memoryRepository) ListTickets(context.Context, Filter, int) ([]Ticket, error) {
+	return r.tickets, nil
+}
+
+func (r *memoryRepository) OrderTickets(_ context.Context, orderID uuid.UUID) ([]Ticket, error) {
+	var tickets []Ticket
+	for _, ticket := range r.tickets {
+		if ticket.OrderID == orderID {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+func (r *memoryRepository) FindTicket(_ context.Context, ticket *Ticket) error {
+	for _, stored := range r.tickets {
+		if stored.ID == ticket.ID {
+			*ticket = stored
+			return nil
+		}
+	}
+	return ErrTicketNotFound
+}
+
+func (r *memoryRepository) UpdateTicket(_ context.Context, ticket *Ticket) error {
+	for i := range r.tickets {
+		if r.tickets[i].ID == ticket.ID {
+			r.tickets[i] = *ticket
+		}
+	}
+	return nil
+}
+
+// publishedMenu has a burger made on the grill and a salad made at the cold station; fries are left to the default
+// station.
+type publishedMenu struct {
+	burger, salad, fries uuid.UUID
+}
+
+func (m publishedMenu) PublishedMenus(context.Context, menu.MenuFilter) (*[]menu.Section, error) {
+	grill, cold := "grill", "cold"
+	burger := menu.Item{Title: "Burger", Active: true}
+	burger.ID = m.burger
+	salad := menu.Item{Title: "Salad", Active: true, Station: &cold}
+	salad.ID = m.salad
+	fries := menu.Item{Title: "Fries", Active: true}
+	fries.ID = m.fries
+	mains := menu.Section{Title: "Mains", Active: true, Station: &grill, Items: []menu.Item{burger, salad}}
+	sides := menu.Section{Title: "Sides", Active: true, Items: []menu.Item{fries}}
+	return &[]menu.Section{mains, sides}, nil
+}
+
+// recordingBus keeps what was published.
+type recordingBus struct {
+	EventBus
+	published []Event
+}
+
+func (b *recordingBus) Publish(event Event) {
+	b.published = append(b.published, event)
+}
+
+// placedOrders lists the orders given, as the order service would.
+type placedOrders []order.Order
+
+func (o placedOrders) Orders(context.Context, order.Filter) ([]order.Order, error) {
+	return o, nil
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Infof(string, ...interface{})  {}
+func (discardLogger) Errorf(string, ...interface{}) {}
+
+func newTestService(t *testing.T, repo Repository) (*service, publishedMenu, *recordingBus) {
+	m := publishedMenu{burger: uuid.New(), salad: uuid.New(), fries: uuid.New()}
+	bus := &recordingBus{}
+	s, err := NewService(repo, m, bus, Routing{Stations: []string{"grill", "cold", "expo"}, DefaultStation: "expo"},
+		discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, m, bus
+}
+
+func placed(items ...uuid.UUID) *order.Order {
+	o := order.Order{Status: order.Placed}
+	o.ID = uuid.New()
+	for _, item := range items {
+		o.Lines = append(o.Lines, order.LineItem{ItemID: item, Title: "Line", Quantity: 1})
+	}
+	return &o
+}
+
+func TestRouteSplitsOrdersByStation(t *testing.T) {
+	repo := newMemoryRepository()
+	s, m, bus := newTestService(t, repo)
+	o := placed(m.burger, m.salad, m.fries, m.burger)
+	if err := s.Notify(context.Background(), order.OrderPlaced, o); err != nil {
+		t.Fatal(err)
+	}
+
+	items := make(map[string]int)
+	for _, ticket := range repo.tickets {
+		items[ticket.Station] = len(ticket.Items)
+	}
+	want := map[string]int{"grill": 2, "cold": 1, "expo": 1}
+	if len(items) != len(want) {
+		t.Fatalf("tickets by station = %v, want %v", items, want)
+	}
+	for station, count := range want {
+		if items[station] != count {
+			t.Errorf("%s got %d items, want %d", station, items[station], count)
+		}
+	}
+	if len(bus.published) != 3 {
+		t.Errorf("published %d events, want one per ticket", len(bus.published))
+	}
+}
+
+func TestRouteNeverRoutesAnOrderTwice(t *testing.T) {
+	storeFailed := errors.New("database is locked")
+	tests := []struct {
+		name          string
+		routedBefore  bool
+		createErr     error
+		routedByOther bool
+		wantErr       error
+		wantTickets   int
+	}{
+		{"first time", false, nil, false, nil, 1},
+		{"routed before", true, nil, false, nil, 0},
+		{"routed by another request at the same time", false, storeFailed, true, nil, 0},
+		{"storing the tickets fails", false, storeFailed, false, storeFailed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepository()
+			s, m, bus := newTestService(t, repo)
+			o := placed(m.burger)
+			repo.routed[o.ID] = tt.routedBefore
+			repo.createErr, repo.routedByOther = tt.createErr, tt.routedByOther
+
+			if err := s.Notify(context.Background(), order.OrderPlaced, o); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Notify() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.tickets) != tt.wantTickets || len(bus.published) != tt.wantTickets {
+				t.Errorf("stored %d tickets and published %d events, want %d", len(repo.tickets),
+					len(bus.published), tt.wantTickets)
+			}
+		})
+	}
+}
+
+func TestRetryRoutesOnlyMissedOrders(t *testing.T) {
+	repo := newMemoryRepository()
+	s, m, _ := newTestService(t, repo)
+	ctx := context.Background()
+	routed, missed := placed(m.burger), placed(m.salad)
+	if err := s.Notify(ctx, order.OrderPlaced, routed); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := placedOrders{*missed, *routed}
+	s.retry(ctx, orders)
+	s.retry(ctx, orders)
+
+	perOrder := make(map[uuid.UUID]int)
+	for _, ticket := range repo.tickets {
+		perOrder[ticket.OrderID]++
+	}
+	if perOrder[routed.ID] != 1 || perOrder[missed.ID] != 1 {
+		t.Errorf("tickets per order = %v, want one each for %v and %v", perOrder, routed.ID, missed.ID)
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    Status
+		voided  bool
+		to      Status
+		wantErr error
+	}{
+		{"start", New, false, InProgress, nil},
+		{"skip to bumped", New, false, Bumped, nil},
+		{"back to in progress", Ready, false, InProgress, ErrInvalidTransition},
+		{"same status", Ready, false, Ready, ErrInvalidTransition},
+		{"voided ticket", New, true, InProgress, ErrTicketVoided},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepository()
+			s, _, _ := newTestService(t, repo)
+			ticket := Ticket{Station: "grill", Status: tt.from, ReceivedAt: time.Now()}
+			ticket.ID = uuid.New()
+			if tt.voided {
+				now := time.Now()
+				ticket.VoidedAt = &now
+			}
+			repo.tickets = []Ticket{ticket}
+
+			if _, err := s.Advance(context.Background(), ticket.ID.String(), tt.to); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Advance() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && repo.tickets[0].Status != tt.to {
+				t.Errorf("ticket is %s, want %s", repo.tickets[0].Status, tt.to)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package domain
+
+// Logger is where services report what they cannot hand back to their caller, such as failures of work done in the
+// background or after the change asked for has been saved.
+type Logger interface {
+	Infof(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
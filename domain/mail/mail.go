@@ -0,0 +1,29 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+)
+
+var ErrNoRecipient = errors.New("message has no recipient")
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+func (m *Message) Validate() error {
+	if m.To == "" {
+		return ErrNoRecipient
+	}
+	_, err := mail.ParseAddress(m.To)
+	return err
+}
+
+// Mailer sends email, e.g. over SMTP, or to files during development.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
@@ -0,0 +1,219 @@
+package menu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+const (
+	clockLayout = "15:04"
+	dateLayout  = "2006-01-02"
+)
+
+// Weekdays is a set of days of the week, stored as a bitmask indexed by time.Weekday. The empty set means every day.
+type Weekdays uint8
+
+// EveryDay contains all seven days of the week.
+const EveryDay Weekdays = 1<<7 - 1
+
+// NewWeekdays builds a set from the given days.
+func NewWeekdays(days ...time.Weekday) Weekdays {
+	var w Weekdays
+	for _, day := range days {
+		w |= 1 << uint(day)
+	}
+	return w
+}
+
+// Has reports whether the day is part of the set.
+func (w Weekdays) Has(day time.Weekday) bool {
+	return w == 0 || w&(1<<uint(day)) != 0
+}
+
+func (w Weekdays) MarshalJSON() ([]byte, error) {
+	days := make([]string, 0, 7)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if w != 0 && w.Has(day) {
+			days = append(days, strings.ToLower(day.String()))
+		}
+	}
+	return json.Marshal(days)
+}
+
+func (w *Weekdays) UnmarshalJSON(data []byte) error {
+	var days []string
+	if err := json.Unmarshal(data, &days); err != nil {
+		return err
+	}
+	*w = 0
+	for _, text := range days {
+		day, err := WeekdayFromText(text)
+		if err != nil {
+			return err
+		}
+		*w |= NewWeekdays(day)
+	}
+	return nil
+}
+
+// WeekdayFromText parses a full or three-letter English day name.
+func WeekdayFromText(text string) (time.Weekday, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if text == name || text == name[:3] {
+			return day, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("%q is not a day of the week", text)
+}
+
+// AvailabilityWindow describes when a section or item can be ordered. Times are wall-clock times in the restaurant's
+// time zone; a window whose end is before its start runs past midnight. Dates optionally restrict the window to a
+// season and are inclusive.
+type AvailabilityWindow struct {
+	domain.Base
+	SectionID *uuid.UUID `json:"section_id,omitempty"`
+	ItemID    *uuid.UUID `json:"item_id,omitempty"`
+	Days      Weekdays   `json:"days" gorm:"default:0"`
+	StartTime string     `json:"start_time"`
+	EndTime   string     `json:"end_time"`
+	TimeZone  string     `json:"time_zone" gorm:"default:UTC"`
+	StartDate *string    `json:"start_date,omitempty"`
+	EndDate   *string    `json:"end_date,omitempty"`
+}
+
+func (w *AvailabilityWindow) Validate() error {
+	if (w.SectionID == nil) == (w.ItemID == nil) {
+		return errors.New("availability window must belong to either a section or an item")
+	}
+	return w.ValidateSchedule()
+}
+
+// ValidateSchedule checks the days, times, dates and time zone of the window, leaving out what it belongs to, so that
+// windows can be used for more than sections and items.
+func (w *AvailabilityWindow) ValidateSchedule() error {
+	if (w.StartTime == "") != (w.EndTime == "") {
+		return errors.New("availability window needs both a start and an end time")
+	}
+	for _, clock := range []string{w.StartTime, w.EndTime} {
+		if clock == "" {
+			continue
+		}
+		if _, err := time.Parse(clockLayout, clock); err != nil {
+			return fmt.Errorf("invalid time %q; expected HH:MM", clock)
+		}
+	}
+	for _, date := range []*string{w.StartDate, w.EndDate} {
+		if date == nil {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, *date); err != nil {
+			return fmt.Errorf("invalid date %q; expected YYYY-MM-DD", *date)
+		}
+	}
+	if w.StartDate != nil && w.EndDate != nil && *w.StartDate > *w.EndDate {
+		return errors.New("availability window ends before it starts")
+	}
+	if _, err := time.LoadLocation(w.TimeZone); err != nil {
+		return fmt.Errorf("unknown time zone %q", w.TimeZone)
+	}
+	return nil
+}
+
+// OpenAt reports whether the window is open at the given instant.
+func (w *AvailabilityWindow) OpenAt(at time.Time) bool {
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := at.In(location)
+
+	if w.StartTime == "" || w.StartTime == w.EndTime {
+		return w.openOn(local)
+	}
+	start, end := minuteOfDay(w.StartTime), minuteOfDay(w.EndTime)
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return start <= minute && minute < end && w.openOn(local)
+	}
+	// The window runs past midnight, so early in the morning it belongs to the previous day.
+	if minute >= start {
+		return w.openOn(local)
+	}
+	return minute < end && w.openOn(local.AddDate(0, 0, -1))
+}
+
+// openOn reports whether the window applies on the calendar day of the given local time.
+func (w *AvailabilityWindow) openOn(local time.Time) bool {
+	date := local.Format(dateLayout)
+	if w.StartDate != nil && date < *w.StartDate {
+		return false
+	}
+	if w.EndDate != nil && date > *w.EndDate {
+		return false
+	}
+	return w.Days.Has(local.Weekday())
+}
+
+func minuteOfDay(clock string) int {
+	t, _ := time.Parse(clockLayout, clock)
+	return t.Hour()*60 + t.Minute()
+}
+
+// openAt reports whether any of the windows is open. Having no windows at all means always open.
+func openAt(windows []AvailabilityWindow, at time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for i := range windows {
+		if windows[i].OpenAt(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableAt reports whether the section is active and within one of its availability windows.
+func (s *Section) AvailableAt(at time.Time) bool {
+	return s.Active && openAt(s.Availability, at)
+}
+
+// AvailableAt reports whether the item is active and within one of its availability windows.
+func (i *Item) AvailableAt(at time.Time) bool {
+	return i.Active && openAt(i.Availability, at)
+}
+
+// AvailableAt prunes a menu tree down to the sections and items that can be ordered at the given instant.
+func AvailableAt(sections []Section, at time.Time) []Section {
+	available := make([]Section, 0, len(sections))
+	for _, section := range sections {
+		if !section.AvailableAt(at) {
+			continue
+		}
+		section.SubSections = AvailableAt(section.SubSections, at)
+		section.Items = availableItems(section.Items, at)
+		available = append(available, section)
+	}
+	return available
+}
+
+func availableItems(items []Item, at time.Time) []Item {
+	available := make([]Item, 0, len(items))
+	for _, item := range items {
+		if !item.AvailableAt(at) {
+			continue
+		}
+		item.AddOns.Items = availableItems(item.AddOns.Items, at)
+		item.Condiments.Items = availableItems(item.Condiments.Items, at)
+		available = append(available, item)
+	}
+	return available
+}
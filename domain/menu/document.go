@@ -0,0 +1,633 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+// DocumentVersion is the version of the import/export format written by NewDocument.
+const DocumentVersion = 1
+
+// ImportMode decides what happens to the existing menus when a document is imported.
+type ImportMode string
+
+const (
+	// MergeImport matches sections and items to the existing ones by title under the same parent. Matched records
+	// are updated, the rest are created, and nothing is deleted.
+	MergeImport ImportMode = "merge"
+	// ReplaceImport deletes the existing menus before recreating them from the document.
+	ReplaceImport ImportMode = "replace"
+)
+
+func (m ImportMode) Validate() error {
+	switch m {
+	case MergeImport, ReplaceImport:
+		return nil
+	default:
+		return fmt.Errorf("unknown import mode %q; expected merge or replace", string(m))
+	}
+}
+
+// errDryRun rolls back the transaction of an import that was only meant to be checked.
+var errDryRun = errors.New("dry run")
+
+// Document is the portable form of the whole menu tree, meant to be kept under version control and moved between
+// databases. Sections, items, variants and modifiers carry a ref, the ID of the record they were exported from, so
+// that a replace import recreates them with the same identity. Tags are referred to by name.
+type Document struct {
+	Version int               `json:"version" yaml:"version"`
+	Tags    []TagDocument     `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Menus   []SectionDocument `json:"menus" yaml:"menus"`
+}
+
+type TagDocument struct {
+	Name  string `json:"name" yaml:"name"`
+	Label string `json:"label,omitempty" yaml:"label,omitempty"`
+	Kind  string `json:"kind" yaml:"kind"`
+}
+
+// SectionDocument describes a section. Active and Visible default to true when left out.
+type SectionDocument struct {
+	Ref          string                 `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Title        string                 `json:"title" yaml:"title"`
+	Description  *string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Type         string                 `json:"type" yaml:"type"`
+	Active       *bool                  `json:"active,omitempty" yaml:"active,omitempty"`
+	Visible      *bool                  `json:"visible,omitempty" yaml:"visible,omitempty"`
+	ListOrder    uint                   `json:"list_order" yaml:"list_order"`
+	Tags         []string               `json:"tags,omitempty" yaml:"tags,omitempty"`
+	TaxCategory  *string                `json:"tax_category,omitempty" yaml:"tax_category,omitempty"`
+	Station      *string                `json:"station,omitempty" yaml:"station,omitempty"`
+	Availability []AvailabilityDocument `json:"availability,omitempty" yaml:"availability,omitempty"`
+	SubSections  []SectionDocument      `json:"subsections,omitempty" yaml:"subsections,omitempty"`
+	Items        []ItemDocument         `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// ItemDocument describes an item along with its add-ons and condiments. Active defaults to true when left out.
+type ItemDocument struct {
+	Ref            string                  `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Title          string                  `json:"title" yaml:"title"`
+	Description    *string                 `json:"description,omitempty" yaml:"description,omitempty"`
+	Price          uint64                  `json:"price" yaml:"price"`
+	Active         *bool                   `json:"active,omitempty" yaml:"active,omitempty"`
+	Type           string                  `json:"type" yaml:"type"`
+	ListOrder      uint                    `json:"list_order" yaml:"list_order"`
+	Tags           []string                `json:"tags,omitempty" yaml:"tags,omitempty"`
+	TaxCategory    *string                 `json:"tax_category,omitempty" yaml:"tax_category,omitempty"`
+	Station        *string                 `json:"station,omitempty" yaml:"station,omitempty"`
+	Availability   []AvailabilityDocument  `json:"availability,omitempty" yaml:"availability,omitempty"`
+	Variants       []VariantDocument       `json:"variants,omitempty" yaml:"variants,omitempty"`
+	ModifierGroups []ModifierGroupDocument `json:"modifier_groups,omitempty" yaml:"modifier_groups,omitempty"`
+	AddOns         *SectionDocument        `json:"add_ons,omitempty" yaml:"add_ons,omitempty"`
+	Condiments     *SectionDocument        `json:"condiments,omitempty" yaml:"condiments,omitempty"`
+}
+
+type VariantDocument struct {
+	Ref       string  `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Name      string  `json:"name" yaml:"name"`
+	Price     uint64  `json:"price" yaml:"price"`
+	ListOrder uint    `json:"list_order" yaml:"list_order"`
+	Active    *bool   `json:"active,omitempty" yaml:"active,omitempty"`
+	SKU       *string `json:"sku,omitempty" yaml:"sku,omitempty"`
+}
+
+type ModifierGroupDocument struct {
+	Ref           string                   `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Title         string                   `json:"title" yaml:"title"`
+	MinSelections uint                     `json:"min_selections" yaml:"min_selections"`
+	MaxSelections uint                     `json:"max_selections" yaml:"max_selections"`
+	ListOrder     uint                     `json:"list_order" yaml:"list_order"`
+	Options       []ModifierOptionDocument `json:"options" yaml:"options"`
+}
+
+type ModifierOptionDocument struct {
+	Ref        string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Title      string `json:"title" yaml:"title"`
+	PriceDelta int64  `json:"price_delta" yaml:"price_delta"`
+	Default    bool   `json:"default,omitempty" yaml:"default,omitempty"`
+	Active     *bool  `json:"active,omitempty" yaml:"active,omitempty"`
+	ListOrder  uint   `json:"list_order" yaml:"list_order"`
+}
+
+type AvailabilityDocument struct {
+	Days      []string `json:"days,omitempty" yaml:"days,omitempty"`
+	StartTime string   `json:"start_time,omitempty" yaml:"start_time,omitempty"`
+	EndTime   string   `json:"end_time,omitempty" yaml:"end_time,omitempty"`
+	TimeZone  string   `json:"time_zone,omitempty" yaml:"time_zone,omitempty"`
+	StartDate *string  `json:"start_date,omitempty" yaml:"start_date,omitempty"`
+	EndDate   *string  `json:"end_date,omitempty" yaml:"end_date,omitempty"`
+}
+
+// NewDocument exports the menus and the tag vocabulary. Siblings are written in list order so that exporting the same
+// menu twice produces the same document.
+func NewDocument(menus []Section, tags []Tag) *Document {
+	doc := Document{Version: DocumentVersion, Menus: make([]SectionDocument, 0, len(menus))}
+	for _, tag := range tags {
+		doc.Tags = append(doc.Tags, TagDocument{Name: tag.Name, Label: tag.Label, Kind: tag.Kind.String()})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	for _, section := range sortedSections(menus) {
+		doc.Menus = append(doc.Menus, exportSection(section))
+	}
+	return &doc
+}
+
+func exportSection(s Section) SectionDocument {
+	active, visible := s.Active, s.Visible
+	doc := SectionDocument{
+		Ref:          s.ID.String(),
+		Title:        s.Title,
+		Description:  s.Description,
+		Type:         s.Type.String(),
+		Active:       &active,
+		Visible:      &visible,
+		ListOrder:    s.ListOrder,
+		Tags:         tagNames(s.Tags),
+		TaxCategory:  s.TaxCategory,
+		Station:      s.Station,
+		Availability: exportAvailability(s.Availability),
+	}
+	for _, sub := range sortedSections(s.SubSections) {
+		doc.SubSections = append(doc.SubSections, exportSection(sub))
+	}
+	for _, item := range sortedItems(s.Items) {
+		doc.Items = append(doc.Items, exportItem(item))
+	}
+	return doc
+}
+
+func exportItem(i Item) ItemDocument {
+	active := i.Active
+	doc := ItemDocument{
+		Ref:          i.ID.String(),
+		Title:        i.Title,
+		Description:  i.Description,
+		Price:        i.Price,
+		Active:       &active,
+		Type:         i.Type.String(),
+		ListOrder:    i.ListOrder,
+		Tags:         tagNames(i.Tags),
+		TaxCategory:  i.TaxCategory,
+		Station:      i.Station,
+		Availability: exportAvailability(i.Availability),
+	}
+	for _, variant := range i.Variants {
+		active := variant.Active
+		doc.Variants = append(doc.Variants, VariantDocument{
+			Ref:       variant.ID.String(),
+			Name:      variant.Name,
+			Price:     variant.Price,
+			ListOrder: variant.ListOrder,
+			Active:    &active,
+			SKU:       variant.SKU,
+		})
+	}
+	for _, group := range i.ModifierGroups {
+		groupDoc := ModifierGroupDocument{
+			Ref:           group.ID.String(),
+			Title:         group.Title,
+			MinSelections: group.MinSelections,
+			MaxSelections: group.MaxSelections,
+			ListOrder:     group.ListOrder,
+			Options:       make([]ModifierOptionDocument, 0, len(group.Options)),
+		}
+		for _, option := range group.Options {
+			active := option.Active
+			groupDoc.Options = append(groupDoc.Options, ModifierOptionDocument{
+				Ref:        option.ID.String(),
+				Title:      option.Title,
+				PriceDelta: option.PriceDelta,
+				Default:    option.IsDefault,
+				Active:     &active,
+				ListOrder:  option.ListOrder,
+			})
+		}
+		doc.ModifierGroups = append(doc.ModifierGroups, groupDoc)
+	}
+	if i.AddOns.ID != uuid.Nil {
+		addOns := exportSection(i.AddOns)
+		doc.AddOns = &addOns
+	}
+	if i.Condiments.ID != uuid.Nil {
+		condiments := exportSection(i.Condiments)
+		doc.Condiments = &condiments
+	}
+	return doc
+}
+
+func exportAvailability(windows []AvailabilityWindow) []AvailabilityDocument {
+	var docs []AvailabilityDocument
+	for _, window := range windows {
+		doc := AvailabilityDocument{
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+			TimeZone:  window.TimeZone,
+			StartDate: window.StartDate,
+			EndDate:   window.EndDate,
+		}
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if window.Days != 0 && window.Days.Has(day) {
+				doc.Days = append(doc.Days, strings.ToLower(day.String()))
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func sortedSections(sections []Section) []Section {
+	sorted := append([]Section{}, sections...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ListOrder != sorted[j].ListOrder {
+			return sorted[i].ListOrder < sorted[j].ListOrder
+		}
+		return sorted[i].Title < sorted[j].Title
+	})
+	return sorted
+}
+
+func sortedItems(items []Item) []Item {
+	sorted := append([]Item{}, items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ListOrder != sorted[j].ListOrder {
+			return sorted[i].ListOrder < sorted[j].ListOrder
+		}
+		return sorted[i].Title < sorted[j].Title
+	})
+	return sorted
+}
+
+// Sections validates the document and builds the menu tree it describes. Tag names are resolved against the given
+// vocabulary. Records are given the ID held in their ref, or a new one when they have none.
+func (d *Document) Sections(vocabulary []Tag) ([]Section, error) {
+	if d.Version > DocumentVersion {
+		return nil, fmt.Errorf("unsupported document version %d", d.Version)
+	}
+	b := documentBuilder{tags: make(map[string]Tag, len(vocabulary)), refs: make(map[uuid.UUID]bool)}
+	for _, tag := range vocabulary {
+		b.tags[tag.Name] = tag
+	}
+
+	menus := make([]Section, 0, len(d.Menus))
+	for _, doc := range d.Menus {
+		section, err := b.section(doc, nil)
+		if err != nil {
+			return nil, err
+		}
+		if section.Type != Meal {
+			return nil, fmt.Errorf("section %q: top level sections must be meals", section.Title)
+		}
+		menus = append(menus, section)
+	}
+	return menus, nil
+}
+
+// documentBuilder turns a document into a menu tree, checking that refs are unique and tags are known on the way.
+type documentBuilder struct {
+	tags map[string]Tag
+	refs map[uuid.UUID]bool
+}
+
+func (b *documentBuilder) id(ref string) (uuid.UUID, error) {
+	if ref == "" {
+		return uuid.New(), nil
+	}
+	id, err := uuid.Parse(ref)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid ref %q", ref)
+	}
+	if b.refs[id] {
+		return uuid.Nil, fmt.Errorf("ref %q is used more than once", ref)
+	}
+	b.refs[id] = true
+	return id, nil
+}
+
+func (b *documentBuilder) resolveTags(names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		tag, ok := b.tags[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tag %q", name)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (b *documentBuilder) section(doc SectionDocument, parentID *uuid.UUID) (Section, error) {
+	section := Section{
+		Title:       doc.Title,
+		Description: doc.Description,
+		Active:      orTrue(doc.Active),
+		Type:        SectionTypeFromText(doc.Type),
+		Visible:     orTrue(doc.Visible),
+		ListOrder:   doc.ListOrder,
+		SectionID:   parentID,
+		TaxCategory: doc.TaxCategory,
+		Station:     doc.Station,
+	}
+	id, err := b.id(doc.Ref)
+	if err != nil {
+		return section, fmt.Errorf("section %q: %v", doc.Title, err)
+	}
+	section.ID = id
+	if section.Type == UndefinedSection {
+		return section, fmt.Errorf("section %q: unknown section type %q", doc.Title, doc.Type)
+	}
+	if section.Tags, err = b.resolveTags(doc.Tags); err != nil {
+		return section, fmt.Errorf("section %q: %v", doc.Title, err)
+	}
+	if section.Availability, err = b.availability(doc.Availability, &section.ID, nil); err != nil {
+		return section, fmt.Errorf("section %q: %v", doc.Title, err)
+	}
+	for _, subDoc := range doc.SubSections {
+		sub, err := b.section(subDoc, &section.ID)
+		if err != nil {
+			return section, err
+		}
+		section.SubSections = append(section.SubSections, sub)
+	}
+	for _, itemDoc := range doc.Items {
+		item, err := b.item(itemDoc, &section.ID)
+		if err != nil {
+			return section, err
+		}
+		section.Items = append(section.Items, item)
+	}
+	if err := section.Validate(); err != nil {
+		return section, fmt.Errorf("section %q: %v", doc.Title, err)
+	}
+	return section, nil
+}
+
+func (b *documentBuilder) item(doc ItemDocument, sectionID *uuid.UUID) (Item, error) {
+	item := Item{
+		Title:       doc.Title,
+		Description: doc.Description,
+		Price:       doc.Price,
+		Active:      orTrue(doc.Active),
+		Type:        ItemTypeFromText(doc.Type),
+		ListOrder:   doc.ListOrder,
+		SectionID:   sectionID,
+		TaxCategory: doc.TaxCategory,
+		Station:     doc.Station,
+	}
+	id, err := b.id(doc.Ref)
+	if err != nil {
+		return item, fmt.Errorf("item %q: %v", doc.Title, err)
+	}
+	item.ID = id
+	if err := item.Validate(); err != nil {
+		return item, fmt.Errorf("item %q: %v", doc.Title, err)
+	}
+	if item.Tags, err = b.resolveTags(doc.Tags); err != nil {
+		return item, fmt.Errorf("item %q: %v", doc.Title, err)
+	}
+	if item.Availability, err = b.availability(doc.Availability, nil, &item.ID); err != nil {
+		return item, fmt.Errorf("item %q: %v", doc.Title, err)
+	}
+	for _, variantDoc := range doc.Variants {
+		variant := Variant{
+			ItemID:    &item.ID,
+			Name:      variantDoc.Name,
+			Price:     variantDoc.Price,
+			ListOrder: variantDoc.ListOrder,
+			Active:    orTrue(variantDoc.Active),
+			SKU:       variantDoc.SKU,
+		}
+		if variant.ID, err = b.id(variantDoc.Ref); err != nil {
+			return item, fmt.Errorf("item %q: %v", doc.Title, err)
+		}
+		if err := variant.Validate(); err != nil {
+			return item, fmt.Errorf("item %q: %v", doc.Title, err)
+		}
+		item.Variants = append(item.Variants, variant)
+	}
+	for _, groupDoc := range doc.ModifierGroups {
+		group := ModifierGroup{
+			Title:         groupDoc.Title,
+			ItemID:        &item.ID,
+			MinSelections: groupDoc.MinSelections,
+			MaxSelections: groupDoc.MaxSelections,
+			ListOrder:     groupDoc.ListOrder,
+		}
+		if group.ID, err = b.id(groupDoc.Ref); err != nil {
+			return item, fmt.Errorf("item %q: %v", doc.Title, err)
+		}
+		for _, optionDoc := range groupDoc.Options {
+			option := ModifierOption{
+				ModifierGroupID: &group.ID,
+				Title:           optionDoc.Title,
+				PriceDelta:      optionDoc.PriceDelta,
+				IsDefault:       optionDoc.Default,
+				Active:          orTrue(optionDoc.Active),
+				ListOrder:       optionDoc.ListOrder,
+			}
+			if option.ID, err = b.id(optionDoc.Ref); err != nil {
+				return item, fmt.Errorf("item %q: %v", doc.Title, err)
+			}
+			group.Options = append(group.Options, option)
+		}
+		if err := group.Validate(); err != nil {
+			return item, fmt.Errorf("item %q: %v", doc.Title, err)
+		}
+		item.ModifierGroups = append(item.ModifierGroups, group)
+	}
+	if doc.AddOns != nil {
+		if item.AddOns, err = b.section(*doc.AddOns, nil); err != nil {
+			return item, err
+		}
+	}
+	if doc.Condiments != nil {
+		if item.Condiments, err = b.section(*doc.Condiments, nil); err != nil {
+			return item, err
+		}
+	}
+	return item, nil
+}
+
+func (b *documentBuilder) availability(docs []AvailabilityDocument, sectionID, itemID *uuid.UUID) ([]AvailabilityWindow,
+	error) {
+	var windows []AvailabilityWindow
+	for _, doc := range docs {
+		window := AvailabilityWindow{
+			SectionID: sectionID,
+			ItemID:    itemID,
+			StartTime: doc.StartTime,
+			EndTime:   doc.EndTime,
+			TimeZone:  doc.TimeZone,
+			StartDate: doc.StartDate,
+			EndDate:   doc.EndDate,
+		}
+		if window.TimeZone == "" {
+			window.TimeZone = "UTC"
+		}
+		for _, text := range doc.Days {
+			day, err := WeekdayFromText(text)
+			if err != nil {
+				return nil, err
+			}
+			window.Days |= NewWeekdays(day)
+		}
+		if err := window.Validate(); err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+func orTrue(b *bool) bool {
+	return b == nil || *b
+}
+
+// ImportCounts tallies the sections and items touched by an import.
+type ImportCounts struct {
+	Sections int `json:"sections"`
+	Items    int `json:"items"`
+}
+
+// ImportReport summarises what an import did, or would have done in the case of a dry run.
+type ImportReport struct {
+	Mode    ImportMode   `json:"mode"`
+	DryRun  bool         `json:"dry_run"`
+	Created ImportCounts `json:"created"`
+	Updated ImportCounts `json:"updated"`
+	Deleted ImportCounts `json:"deleted"`
+}
+
+// replace counts the existing menus as deleted and the incoming ones as created.
+func (r *ImportReport) replace(existing, incoming []Section) {
+	countSections(existing, &r.Deleted)
+	countSections(incoming, &r.Created)
+}
+
+func countSections(sections []Section, counts *ImportCounts) {
+	for _, section := range sections {
+		counts.Sections++
+		countSections(section.SubSections, counts)
+		countItems(section.Items, counts)
+	}
+}
+
+func countItems(items []Item, counts *ImportCounts) {
+	for _, item := range items {
+		counts.Items++
+		for _, container := range []Section{item.AddOns, item.Condiments} {
+			if container.ID != uuid.Nil {
+				countSections([]Section{container}, counts)
+			}
+		}
+	}
+}
+
+// mergeSections gives each incoming section the identity of the existing sibling with the same title, if there is
+// one, and a new identity otherwise. Refs are only honoured by replace imports, as they may clash with unrelated
+// records in a merge.
+func (r *ImportReport) mergeSections(existing, incoming []Section) {
+	for i := range incoming {
+		section := &incoming[i]
+		match := sectionByTitle(existing, section.Title)
+		if match == nil {
+			section.Base = domain.Base{}
+			r.Created.Sections++
+			r.mergeSections(nil, section.SubSections)
+			r.mergeItems(nil, section.Items)
+			section.renewOwned()
+			continue
+		}
+		section.Base = match.Base
+		r.Updated.Sections++
+		r.mergeSections(match.SubSections, section.SubSections)
+		r.mergeItems(match.Items, section.Items)
+		section.renewOwned()
+	}
+}
+
+func (r *ImportReport) mergeItems(existing, incoming []Item) {
+	for i := range incoming {
+		item := &incoming[i]
+		match := itemByTitle(existing, item.Title)
+		var addOns, condiments Section
+		if match == nil {
+			item.Base = domain.Base{}
+			r.Created.Items++
+		} else {
+			item.Base = match.Base
+			addOns, condiments = match.AddOns, match.Condiments
+			r.Updated.Items++
+		}
+		r.mergeContainer(addOns, &item.AddOns)
+		r.mergeContainer(condiments, &item.Condiments)
+		item.renewOwned()
+	}
+}
+
+// mergeContainer merges the add-ons or condiments of an item into those it already has.
+func (r *ImportReport) mergeContainer(existing Section, incoming *Section) {
+	if incoming.ID == uuid.Nil {
+		return
+	}
+	if existing.ID == uuid.Nil {
+		incoming.Base = domain.Base{}
+		r.Created.Sections++
+		r.mergeItems(nil, incoming.Items)
+		incoming.renewOwned()
+		return
+	}
+	incoming.Base = existing.Base
+	r.Updated.Sections++
+	r.mergeItems(existing.Items, incoming.Items)
+	incoming.renewOwned()
+}
+
+// renewOwned gives the availability windows of a merged section new identities, since they replace the ones it
+// already has.
+func (s *Section) renewOwned() {
+	for i := range s.Availability {
+		s.Availability[i].Base = domain.Base{}
+	}
+}
+
+// renewOwned gives the variants, modifiers and availability windows of a merged item new identities, since they
+// replace the ones it already has.
+func (i *Item) renewOwned() {
+	for v := range i.Variants {
+		i.Variants[v].Base = domain.Base{}
+	}
+	for g := range i.ModifierGroups {
+		i.ModifierGroups[g].Base = domain.Base{}
+		for o := range i.ModifierGroups[g].Options {
+			i.ModifierGroups[g].Options[o].Base = domain.Base{}
+		}
+	}
+	for w := range i.Availability {
+		i.Availability[w].Base = domain.Base{}
+	}
+}
+
+func sectionByTitle(sections []Section, title string) *Section {
+	for i := range sections {
+		if strings.EqualFold(sections[i].Title, title) {
+			return &sections[i]
+		}
+	}
+	return nil
+}
+
+func itemByTitle(items []Item, title string) *Item {
+	for i := range items {
+		if strings.EqualFold(items[i].Title, title) {
+			return &items[i]
+		}
+	}
+	return nil
+}
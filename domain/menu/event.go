@@ -0,0 +1,57 @@
+package menu
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType names a kind of change to the menu.
+type EventType string
+
+const (
+	SectionCreated    EventType = "section.created"
+	SectionUpdated    EventType = "section.updated"
+	SectionReParented EventType = "section.reparented"
+	SectionDeleted    EventType = "section.deleted"
+	ItemCreated       EventType = "item.created"
+	ItemUpdated       EventType = "item.updated"
+	ItemReParented    EventType = "item.reparented"
+	ItemDeleted       EventType = "item.deleted"
+	// MenuReset tells listeners that changes were made that cannot be replayed one by one, e.g. a whole menu import or
+	// a resume from an event that has since been forgotten, and that they should fetch the menus again.
+	MenuReset EventType = "menu.reset"
+)
+
+// Event describes a single change to the sections and items of the draft menus. Created and updated events carry the
+// record as it was saved; reparented events carry the new parent; deleted events only carry the id. Changes to what
+// hangs off an item or section, i.e. its variants, modifier groups, availability windows and tags, are published as
+// an update of that item or section.
+type Event struct {
+	// ID increases with every event published, so that a listener can resume after the last event it saw.
+	ID       uint64     `json:"event_id"`
+	Type     EventType  `json:"type"`
+	At       time.Time  `json:"at"`
+	EntityID *uuid.UUID `json:"id,omitempty"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Section  *Section   `json:"section,omitempty"`
+	Item     *Item      `json:"item,omitempty"`
+}
+
+// EventBus fans menu changes out to listeners. Publish assigns the event its ID and time. Subscribe first replays the
+// events published after lastEventID, or a MenuReset when those are no longer known, then delivers new events until
+// ctx is done, at which point the channel is closed. A listener that falls too far behind has its channel closed
+// early and is expected to subscribe again from the last event it received.
+type EventBus interface {
+	Publish(Event)
+	Subscribe(ctx context.Context, lastEventID uint64) (<-chan Event, error)
+}
+
+func sectionEvent(eventType EventType, section *Section) Event {
+	return Event{Type: eventType, EntityID: &section.ID, Section: section}
+}
+
+func itemEvent(eventType EventType, item *Item) Event {
+	return Event{Type: eventType, EntityID: &item.ID, Item: item}
+}
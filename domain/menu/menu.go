@@ -31,17 +31,21 @@ func (s *SectionType) UnmarshalText(text []byte) error {
 // Section struct defines the service structure.
 type Section struct {
 	domain.Base
-	Title       string      `json:"title" gorm:"unique,not null"`
-	Description *string     `json:"description"`
-	Active      bool        `json:"active" gorm:"default:true"`
-	Type        SectionType `json:"type" gorm:"not null, default: 0"`
-	Visible     bool        `json:"visible" gorm:"default:true"`
-	ListOrder   uint        `json:"list_order" gorm:"default:0"`
-	SectionID   *uuid.UUID  `json:"section_id"`
-	SubSections []Section   `json:"subsections" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	Items       []Item      `json:"items" gorm:"foreignKey:SectionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	AddOnsID     *uuid.UUID `json:"add_ons_id"`
-	CondimentsID *uuid.UUID `json:"condiments_id"`
+	Title        string               `json:"title" gorm:"unique,not null"`
+	Description  *string              `json:"description"`
+	Active       bool                 `json:"active" gorm:"default:true"`
+	Type         SectionType          `json:"type" gorm:"not null, default: 0"`
+	Visible      bool                 `json:"visible" gorm:"default:true"`
+	ListOrder    uint                 `json:"list_order" gorm:"default:0"`
+	SectionID    *uuid.UUID           `json:"section_id"`
+	SubSections  []Section            `json:"subsections" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Items        []Item               `json:"items" gorm:"foreignKey:SectionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	AddOnsID     *uuid.UUID           `json:"add_ons_id"`
+	CondimentsID *uuid.UUID           `json:"condiments_id"`
+	Availability []AvailabilityWindow `json:"availability" gorm:"foreignKey:SectionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Tags         []Tag                `json:"tags" gorm:"many2many:section_tags;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TaxCategory  *string              `json:"tax_category,omitempty" gorm:"size:64"`
+	Station      *string              `json:"station,omitempty" gorm:"size:64"`
 }
 
 func (s *Section) Validate() error {
@@ -85,22 +89,28 @@ func (i *ItemType) UnmarshalText(text []byte) error {
 // Item struct defines service items.
 type Item struct {
 	domain.Base
-	Title        string     `json:"title" gorm:"not null"`
-	Description  *string    `json:"description"`
-	Price        uint64     `json:"price" gorm:"default:000"`
-	Active       bool       `json:"active" gorm:"default:true"`
-	Type         ItemType   `json:"type" gorm:"default:0"`
-	ListOrder    uint       `json:"list_order" gorm:"default:0"`
-	SectionID    *uuid.UUID `json:"section_id"`
-	AddOns       Section    `json:"add_ons" gorm:"foreignKey:AddOnsID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	Condiments   Section    `json:"condiments" gorm:"foreignKey:CondimentsID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Title          string               `json:"title" gorm:"not null"`
+	Description    *string              `json:"description"`
+	Price          uint64               `json:"price" gorm:"default:000"`
+	Active         bool                 `json:"active" gorm:"default:true"`
+	Type           ItemType             `json:"type" gorm:"default:0"`
+	ListOrder      uint                 `json:"list_order" gorm:"default:0"`
+	SectionID      *uuid.UUID           `json:"section_id"`
+	AddOns         Section              `json:"add_ons" gorm:"foreignKey:AddOnsID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Condiments     Section              `json:"condiments" gorm:"foreignKey:CondimentsID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ModifierGroups []ModifierGroup      `json:"modifier_groups" gorm:"foreignKey:ItemID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Variants       []Variant            `json:"variants" gorm:"foreignKey:ItemID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Availability   []AvailabilityWindow `json:"availability" gorm:"foreignKey:ItemID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Tags           []Tag                `json:"tags" gorm:"many2many:item_tags;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TaxCategory    *string              `json:"tax_category,omitempty" gorm:"size:64"`
+	Station        *string              `json:"station,omitempty" gorm:"size:64"`
 }
 
 func (i *Item) Validate() error {
 	if i.Title == "" {
 		return errors.New("item is empty")
 	}
-	if *i.SectionID == uuid.Nil {
+	if i.SectionID == nil || *i.SectionID == uuid.Nil {
 		return errors.New("item must have a section parent")
 	}
 	return nil
@@ -126,7 +136,7 @@ func ItemTypeFromText(text string) ItemType {
 		return Snack
 	case "side":
 		return Side
-	case "add_on":
+	case "add_on", "addon":
 		return AddOn
 	case "condiment":
 		return Condiment
@@ -0,0 +1,129 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+var (
+	ErrTooFewSelections  = errors.New("too few options selected")
+	ErrTooManySelections = errors.New("too many options selected")
+	ErrUnknownOption     = errors.New("option does not belong to this modifier group")
+	ErrInactiveOption    = errors.New("option is not currently available")
+)
+
+// ModifierGroup is a set of options a guest picks from when ordering an item, e.g. "Choose your bagel" or "Add
+// cream cheese". MinSelections and MaxSelections bound the number of options that may be picked; a MaxSelections of
+// zero means there is no upper bound.
+type ModifierGroup struct {
+	domain.Base
+	Title         string           `json:"title" gorm:"not null"`
+	ItemID        *uuid.UUID       `json:"item_id"`
+	MinSelections uint             `json:"min_selections" gorm:"default:0"`
+	MaxSelections uint             `json:"max_selections" gorm:"default:0"`
+	ListOrder     uint             `json:"list_order" gorm:"default:0"`
+	Options       []ModifierOption `json:"options" gorm:"foreignKey:ModifierGroupID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// ModifierOption is a single choice within a ModifierGroup. PriceDelta (in cents) is added to the item's price
+// when the option is picked and may be negative.
+type ModifierOption struct {
+	domain.Base
+	ModifierGroupID *uuid.UUID `json:"modifier_group_id"`
+	Title           string     `json:"title" gorm:"not null"`
+	PriceDelta      int64      `json:"price_delta" gorm:"default:0"`
+	IsDefault       bool       `json:"default" gorm:"default:false"`
+	Active          bool       `json:"active"`
+	ListOrder       uint       `json:"list_order" gorm:"default:0"`
+}
+
+func (g *ModifierGroup) Validate() error {
+	if g.Title == "" {
+		return errors.New("modifier group title is empty")
+	}
+	if g.ItemID == nil || *g.ItemID == uuid.Nil {
+		return errors.New("modifier group must belong to an item")
+	}
+	if g.MaxSelections > 0 && g.MinSelections > g.MaxSelections {
+		return errors.New("minimum selections cannot exceed maximum selections")
+	}
+
+	var defaults, active uint
+	for _, option := range g.Options {
+		if option.Title == "" {
+			return errors.New("modifier option title is empty")
+		}
+		if option.IsDefault {
+			defaults++
+		}
+		if option.Active {
+			active++
+		}
+	}
+	if g.MaxSelections > 0 && defaults > g.MaxSelections {
+		return fmt.Errorf("%d default options exceed the maximum of %d selections", defaults, g.MaxSelections)
+	}
+	if active < g.MinSelections {
+		return fmt.Errorf("%d active options cannot satisfy the minimum of %d selections", active, g.MinSelections)
+	}
+	return nil
+}
+
+// Required reports whether the guest must pick at least one option from the group.
+func (g *ModifierGroup) Required() bool {
+	return g.MinSelections > 0
+}
+
+// Defaults returns the options that are pre-selected for the guest.
+func (g *ModifierGroup) Defaults() []ModifierOption {
+	var defaults []ModifierOption
+	for _, option := range g.Options {
+		if option.IsDefault && option.Active {
+			defaults = append(defaults, option)
+		}
+	}
+	return defaults
+}
+
+// ValidateSelection checks the given option IDs against the group's rules and returns the selected options along
+// with the sum of their price deltas.
+func (g *ModifierGroup) ValidateSelection(optionIDs []uuid.UUID) ([]ModifierOption, int64, error) {
+	options := make(map[uuid.UUID]ModifierOption, len(g.Options))
+	for _, option := range g.Options {
+		options[option.ID] = option
+	}
+
+	var (
+		selected []ModifierOption
+		delta    int64
+		seen     = make(map[uuid.UUID]bool, len(optionIDs))
+	)
+	for _, id := range optionIDs {
+		option, ok := options[id]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: %v", ErrUnknownOption, id)
+		}
+		if !option.Active {
+			return nil, 0, fmt.Errorf("%w: %s", ErrInactiveOption, option.Title)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		selected = append(selected, option)
+		delta += option.PriceDelta
+	}
+
+	count := uint(len(selected))
+	if count < g.MinSelections {
+		return nil, 0, fmt.Errorf("%w: %q requires at least %d", ErrTooFewSelections, g.Title, g.MinSelections)
+	}
+	if g.MaxSelections > 0 && count > g.MaxSelections {
+		return nil, 0, fmt.Errorf("%w: %q allows at most %d", ErrTooManySelections, g.Title, g.MaxSelections)
+	}
+	return selected, delta, nil
+}
@@ -0,0 +1,224 @@
+package menu
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// priceSheetColumns are the columns of a price sheet, in the order they are exported. The section column is the path
+// of titles leading to the item and is ignored on import.
+var priceSheetColumns = []string{"id", "section", "title", "type", "price", "active", "list_order"}
+
+// sectionPathSeparator joins the titles of a section path.
+const sectionPathSeparator = " / "
+
+// RowError reports why a row of a price sheet was rejected. Rows are numbered the way a spreadsheet numbers them, so
+// the header is row 1.
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// PriceSheetReport summarises the import of a price sheet. Changes are only applied when there are no errors.
+type PriceSheetReport struct {
+	Rows      int        `json:"rows"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Errors    []RowError `json:"errors,omitempty"`
+}
+
+// WritePriceSheet writes every item of the menus as a CSV row, in the order they are listed on the menu.
+func WritePriceSheet(w io.Writer, menus []Section) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(priceSheetColumns); err != nil {
+		return err
+	}
+	var writeSection func(Section, []string) error
+	writeSection = func(s Section, path []string) error {
+		path = append(path[:len(path):len(path)], s.Title)
+		for _, sub := range sortedSections(s.SubSections) {
+			if err := writeSection(sub, path); err != nil {
+				return err
+			}
+		}
+		for _, item := range sortedItems(s.Items) {
+			if err := out.Write([]string{
+				item.ID.String(),
+				strings.Join(path, sectionPathSeparator),
+				item.Title,
+				item.Type.String(),
+				strconv.FormatUint(item.Price, 10),
+				strconv.FormatBool(item.Active),
+				strconv.FormatUint(uint64(item.ListOrder), 10),
+			}); err != nil {
+				return err
+			}
+			itemPath := append(path[:len(path):len(path)], item.Title)
+			for _, container := range []Section{item.AddOns, item.Condiments} {
+				if container.ID == uuid.Nil {
+					continue
+				}
+				if err := writeSection(container, itemPath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, section := range sortedSections(menus) {
+		if err := writeSection(section, nil); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// priceSheetRow is a parsed row of a price sheet. Fields whose column is missing from the sheet are left nil and do
+// not change the item.
+type priceSheetRow struct {
+	row       int
+	id        uuid.UUID
+	title     *string
+	itemType  *ItemType
+	price     *uint64
+	active    *bool
+	listOrder *uint
+}
+
+// apply copies the row onto the item and reports whether anything changed.
+func (r *priceSheetRow) apply(item *Item) bool {
+	before := *item
+	if r.title != nil {
+		item.Title = *r.title
+	}
+	if r.itemType != nil {
+		item.Type = *r.itemType
+	}
+	if r.price != nil {
+		item.Price = *r.price
+	}
+	if r.active != nil {
+		item.Active = *r.active
+	}
+	if r.listOrder != nil {
+		item.ListOrder = *r.listOrder
+	}
+	return item.Title != before.Title || item.Type != before.Type || item.Price != before.Price ||
+		item.Active != before.Active || item.ListOrder != before.ListOrder
+}
+
+// readPriceSheet parses a price sheet. The header decides which columns are present and in which order; only the id
+// column is required. Rows that cannot be parsed are reported as row errors rather than failing the whole sheet.
+func readPriceSheet(r io.Reader) ([]priceSheetRow, []RowError, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+	in.TrimLeadingSpace = true
+
+	header, err := in.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("price sheet is empty")
+	} else if err != nil {
+		return nil, nil, err
+	}
+	// Spreadsheets often save CSV files with a byte order mark in front of the first column.
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := columns[name]; ok {
+			return nil, nil, fmt.Errorf("column %q appears more than once", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, nil, errors.New("price sheet has no id column")
+	}
+
+	var rows []priceSheetRow
+	var rowErrors []RowError
+	for number := 2; ; number++ {
+		record, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		row, err := parsePriceSheetRow(record, columns)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: number, Error: err.Error()})
+			continue
+		}
+		row.row = number
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+func parsePriceSheetRow(record []string, columns map[string]int) (priceSheetRow, error) {
+	var row priceSheetRow
+	field := func(name string) (string, bool) {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return "", false
+		}
+		return strings.TrimSpace(record[i]), true
+	}
+
+	rawID, _ := field("id")
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return row, fmt.Errorf("invalid id %q", rawID)
+	}
+	row.id = id
+	if title, ok := field("title"); ok {
+		row.title = &title
+	}
+	if text, ok := field("type"); ok {
+		itemType := ItemTypeFromText(text)
+		if itemType == UndefinedItem {
+			return row, fmt.Errorf("unknown item type %q", text)
+		}
+		row.itemType = &itemType
+	}
+	if text, ok := field("price"); ok {
+		price, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return row, fmt.Errorf("price %q must be a whole number of cents", text)
+		}
+		row.price = &price
+	}
+	if text, ok := field("active"); ok {
+		active, err := strconv.ParseBool(text)
+		if err != nil {
+			return row, fmt.Errorf("active %q must be true or false", text)
+		}
+		row.active = &active
+	}
+	if text, ok := field("list_order"); ok {
+		listOrder, err := strconv.ParseUint(text, 10, 32)
+		if err != nil {
+			return row, fmt.Errorf("list_order %q must be a whole number", text)
+		}
+		order := uint(listOrder)
+		row.listOrder = &order
+	}
+	return row, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
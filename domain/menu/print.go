@@ -0,0 +1,78 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrMenuNotFound is returned when no published menu has the requested id.
+var ErrMenuNotFound = errors.New("no published menu with that id")
+
+// PrintFormat is the kind of document a menu is printed to.
+type PrintFormat string
+
+const (
+	HTMLPrint PrintFormat = "html"
+	PDFPrint  PrintFormat = "pdf"
+)
+
+// PrintFormatFromText parses the name of a print format, defaulting to HTML.
+func PrintFormatFromText(text string) (PrintFormat, error) {
+	switch strings.ToLower(text) {
+	case "", "html":
+		return HTMLPrint, nil
+	case "pdf":
+		return PDFPrint, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q; expected html or pdf", text)
+	}
+}
+
+// Printer renders a menu as a page meant to be printed and laid on the table. It is handed a menu that has already
+// been trimmed down to what guests should see and sorted in list order.
+type Printer interface {
+	HTML(io.Writer, *Section) error
+	PDF(io.Writer, *Section) error
+}
+
+// Printable returns a copy of the section with its hidden or inactive subsections, add-ons and condiments and its
+// inactive items left out, and everything sorted in list order.
+func (s Section) Printable() Section {
+	printable := s
+	printable.SubSections = nil
+	for _, sub := range sortedSections(s.SubSections) {
+		if sub.Visible && sub.Active {
+			printable.SubSections = append(printable.SubSections, sub.Printable())
+		}
+	}
+	printable.Items = nil
+	for _, item := range sortedItems(s.Items) {
+		if !item.Active {
+			continue
+		}
+		var variants []Variant
+		for _, variant := range item.Variants {
+			if variant.Active {
+				variants = append(variants, variant)
+			}
+		}
+		item.Variants = variants
+		item.AddOns = item.AddOns.printableContainer()
+		item.Condiments = item.Condiments.printableContainer()
+		printable.Items = append(printable.Items, item)
+	}
+	return printable
+}
+
+// printableContainer returns what is printed of an item's add-ons or condiments section: nothing when the item has
+// none or the section is hidden or inactive, as with subsections, and otherwise its printable copy.
+func (s Section) printableContainer() Section {
+	if s.ID == uuid.Nil || !s.Visible || !s.Active {
+		return Section{}
+	}
+	return s.Printable()
+}
@@ -7,19 +7,52 @@ import (
 // Repository represents the expected methods that a database implementation must have to satisfy the contracts of
 // this application
 type Repository interface {
-	ListMenus(context.Context) (*[]Section, error)
+	ListMenus(context.Context, MenuFilter) (*[]Section, error)
 	ListSections(context.Context) (*[]Section, error)
 	FindSection(context.Context, *Section) error
 	CreateSection(context.Context, *Section) error
 	UpdateSection(context.Context, *Section) error
+	SetSectionActive(context.Context, *Section, bool) error
+	SetSectionTaxCategory(context.Context, *Section, *string) error
+	SetSectionStation(context.Context, *Section, *string) error
 	UpdateSectionParent(context.Context, *Section, *Section) error
 	DeleteSection(context.Context, *Section) error
 	ListItems(context.Context) (*[]Item, error)
 	FindItem(context.Context, *Item) error
 	CreateItem(context.Context, *Item) error
 	UpdateItem(context.Context, *Item) error
+	UpdateItemListing(context.Context, *Item) error
+	SetItemActive(context.Context, *Item, bool) error
+	SetItemTaxCategory(context.Context, *Item, *string) error
+	SetItemStation(context.Context, *Item, *string) error
 	UpdateItemParent(context.Context, *Item, *Section) error
 	DeleteItem(context.Context, *Item) error
+	ListModifierGroups(context.Context, *Item) (*[]ModifierGroup, error)
+	FindModifierGroup(context.Context, *ModifierGroup) error
+	CreateModifierGroup(context.Context, *ModifierGroup) error
+	UpdateModifierGroup(context.Context, *ModifierGroup) error
+	DeleteModifierGroup(context.Context, *ModifierGroup) error
+	ListVariants(context.Context, *Item) (*[]Variant, error)
+	FindVariant(context.Context, *Variant) error
+	CreateVariant(context.Context, *Variant) error
+	UpdateVariant(context.Context, *Variant) error
+	DeleteVariant(context.Context, *Variant) error
+	FindAvailabilityWindow(context.Context, *AvailabilityWindow) error
+	CreateAvailabilityWindow(context.Context, *AvailabilityWindow) error
+	UpdateAvailabilityWindow(context.Context, *AvailabilityWindow) error
+	DeleteAvailabilityWindow(context.Context, *AvailabilityWindow) error
+	ListTags(context.Context) (*[]Tag, error)
+	FindTags(context.Context, []string) (*[]Tag, error)
+	CreateTag(context.Context, *Tag) error
+	UpdateTag(context.Context, *Tag) error
+	DeleteTag(context.Context, *Tag) error
+	ReplaceItemTags(context.Context, *Item, []Tag) error
+	ReplaceSectionTags(context.Context, *Section, []Tag) error
+	PublishSnapshot(context.Context, *Snapshot) error
+	ListSnapshots(context.Context) (*[]Snapshot, error)
+	FindSnapshot(context.Context, *Snapshot) error
+	LatestSnapshot(context.Context, *Snapshot) error
+	SaveMenus(context.Context, []Section) error
+	DeleteMenus(context.Context) error
+	Transaction(context.Context, func(Repository) error) error
 }
-
-
@@ -2,17 +2,36 @@ package menu
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
 
 	"github.com/google/uuid"
 )
 
 // Service describes the expected behavior for manipulating service data.
 type Service interface {
-	Menus(context.Context) (*[]Section, error)
+	Menus(context.Context, MenuFilter) (*[]Section, error)
+	PublishedMenus(context.Context, MenuFilter) (*[]Section, error)
+	Publish(context.Context, *uuid.UUID, string) (*Snapshot, error)
+	Snapshots(context.Context) (*[]Snapshot, error)
+	SnapshotMenus(context.Context, uint) (*[]Section, error)
+	DiffSnapshots(context.Context, uint, uint) (*SnapshotDiff, error)
+	Rollback(context.Context, uint, *uuid.UUID) (*Snapshot, error)
+	Export(context.Context) (*Document, error)
+	Import(context.Context, *Document, ImportMode, bool) (*ImportReport, error)
+	ExportPriceSheet(context.Context, io.Writer) error
+	ImportPriceSheet(context.Context, io.Reader) (*PriceSheetReport, error)
+	PrintMenu(context.Context, string, PrintFormat, io.Writer) error
+	Events(context.Context, uint64) (<-chan Event, error)
 	Sections(context.Context) (*[]Section, error)
 	SectionByID(context.Context, string) (*Section, error)
 	NewSection(context.Context, *Section) error
 	UpdateSectionContent(context.Context, *Section) error
+	SetSectionActive(context.Context, string, bool) (*Section, error)
+	SetSectionTaxCategory(context.Context, string, *string) (*Section, error)
+	SetSectionStation(context.Context, string, *string) (*Section, error)
 	ReParentSection(context.Context, *Section, uuid.UUID) error
 	DeleteSection(context.Context, string) error
 	Items(context.Context) (*[]Item, error)
@@ -20,28 +39,363 @@ type Service interface {
 	NewItem(context.Context, *Item) error
 	ReParentItem(context.Context, *Item, uuid.UUID) error
 	UpdateItemContent(context.Context, *Item) error
+	SetItemActive(context.Context, string, bool) (*Item, error)
+	SetItemTaxCategory(context.Context, string, *string) (*Item, error)
+	SetItemStation(context.Context, string, *string) (*Item, error)
 	DeleteItem(context.Context, string) error
+	ModifierGroups(context.Context, string) (*[]ModifierGroup, error)
+	NewModifierGroup(context.Context, *ModifierGroup) error
+	UpdateModifierGroup(context.Context, *ModifierGroup) error
+	DeleteModifierGroup(context.Context, string) error
+	Variants(context.Context, string) (*[]Variant, error)
+	NewVariant(context.Context, *Variant) error
+	UpdateVariant(context.Context, *Variant) error
+	DeleteVariant(context.Context, string, string) error
+	NewAvailabilityWindow(context.Context, *AvailabilityWindow) error
+	UpdateAvailabilityWindow(context.Context, *AvailabilityWindow) error
+	DeleteAvailabilityWindow(context.Context, string) error
+	Tags(context.Context) (*[]Tag, error)
+	NewTag(context.Context, *Tag) error
+	UpdateTag(context.Context, *Tag) error
+	DeleteTag(context.Context, string) error
+	TagItem(context.Context, string, []string) (*Item, error)
+	TagSection(context.Context, string, []string) (*Section, error)
 }
 
 var (
-	NullItem    = Item{}
-	NullSection = Section{}
+	NullItem          = Item{}
+	NullSection       = Section{}
+	NullModifierGroup = ModifierGroup{}
+	NullVariant       = Variant{}
 )
 
 type service struct {
-	repo Repository
+	repo          Repository
+	printer       Printer
+	events        EventBus
+	taxCategories TaxCategories
+	stations      PrepStations
 }
 
-func NewService(menuRepo Repository) *service {
-	return &service{menuRepo}
+func NewService(menuRepo Repository, printer Printer, events EventBus, taxCategories TaxCategories,
+	stations PrepStations) *service {
+	return &service{menuRepo, printer, events, taxCategories, stations}
+}
+
+// Events streams the changes made to sections and items after lastEventID.
+func (m *service) Events(ctx context.Context, lastEventID uint64) (<-chan Event, error) {
+	return m.events.Subscribe(ctx, lastEventID)
 }
 
 func (m *service) NewSection(ctx context.Context, section *Section) error {
-	return m.repo.CreateSection(ctx, section)
+	if err := m.repo.CreateSection(ctx, section); err != nil {
+		return err
+	}
+	m.events.Publish(sectionEvent(SectionCreated, section))
+	return nil
+}
+
+// Menus returns the draft menus narrowed down by the given filter; an empty filter returns everything.
+func (m *service) Menus(ctx context.Context, filter MenuFilter) (*[]Section, error) {
+	menus, err := m.repo.ListMenus(ctx, filter)
+	if err != nil {
+		return menus, err
+	}
+	for i := range *menus {
+		(*menus)[i].withDefaultVariants()
+	}
+	return menus, nil
+}
+
+// PublishedMenus returns the latest published menus narrowed down by the given filter. Until the first publish the
+// draft is served instead so that guests never see an empty menu.
+func (m *service) PublishedMenus(ctx context.Context, filter MenuFilter) (*[]Section, error) {
+	var snapshot Snapshot
+	if err := m.repo.LatestSnapshot(ctx, &snapshot); errors.Is(err, ErrNoPublishedMenu) {
+		return m.Menus(ctx, filter)
+	} else if err != nil {
+		return &[]Section{}, err
+	}
+	menus, err := snapshot.Menus()
+	if err != nil {
+		return &[]Section{}, err
+	}
+	menus = filter.Apply(menus)
+	return &menus, nil
+}
+
+// Publish records the current draft as the next published version of the menu.
+func (m *service) Publish(ctx context.Context, publishedBy *uuid.UUID, note string) (*Snapshot, error) {
+	draft, err := m.Menus(ctx, MenuFilter{})
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := NewSnapshot(*draft, publishedBy, note)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.repo.PublishSnapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Snapshots lists every published version, newest first.
+func (m *service) Snapshots(ctx context.Context) (*[]Snapshot, error) {
+	return m.repo.ListSnapshots(ctx)
+}
+
+// SnapshotMenus returns the menus as they were published in the given version.
+func (m *service) SnapshotMenus(ctx context.Context, version uint) (*[]Section, error) {
+	snapshot, err := m.snapshot(ctx, version)
+	if err != nil {
+		return &[]Section{}, err
+	}
+	menus, err := snapshot.Menus()
+	if err != nil {
+		return &[]Section{}, err
+	}
+	return &menus, nil
+}
+
+// DiffSnapshots lists what changed between two published versions.
+func (m *service) DiffSnapshots(ctx context.Context, from, to uint) (*SnapshotDiff, error) {
+	fromSnapshot, err := m.snapshot(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toSnapshot, err := m.snapshot(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(fromSnapshot, toSnapshot)
+}
+
+// Rollback republishes an earlier version as the newest one. History is never rewritten, and the draft is left
+// untouched.
+func (m *service) Rollback(ctx context.Context, version uint, publishedBy *uuid.UUID) (*Snapshot, error) {
+	previous, err := m.snapshot(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := Snapshot{
+		Note:           fmt.Sprintf("rollback to version %d", version),
+		PublishedBy:    publishedBy,
+		RolledBackFrom: &previous.Version,
+		Content:        previous.Content,
+	}
+	if err := m.repo.PublishSnapshot(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Export writes the draft menus and the tag vocabulary to a document.
+func (m *service) Export(ctx context.Context) (*Document, error) {
+	menus, err := m.repo.ListMenus(ctx, MenuFilter{})
+	if err != nil {
+		return nil, err
+	}
+	tags, err := m.repo.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewDocument(*menus, *tags), nil
+}
+
+// Import loads a document into the draft menus in a single transaction. The tags it lists are created or updated
+// first. A dry run reports what the import would do and then rolls it back.
+func (m *service) Import(ctx context.Context, doc *Document, mode ImportMode, dryRun bool) (*ImportReport, error) {
+	if err := mode.Validate(); err != nil {
+		return nil, err
+	}
+	report := ImportReport{Mode: mode, DryRun: dryRun}
+	err := m.repo.Transaction(ctx, func(repo Repository) error {
+		vocabulary, err := importTags(ctx, repo, doc.Tags)
+		if err != nil {
+			return err
+		}
+		incoming, err := doc.Sections(vocabulary)
+		if err != nil {
+			return err
+		}
+		if err := m.checkTaxCategories(incoming); err != nil {
+			return err
+		}
+		if err := m.checkStations(incoming); err != nil {
+			return err
+		}
+		existing, err := repo.ListMenus(ctx, MenuFilter{})
+		if err != nil {
+			return err
+		}
+
+		switch mode {
+		case ReplaceImport:
+			report.replace(*existing, incoming)
+			if err := repo.DeleteMenus(ctx); err != nil {
+				return err
+			}
+		case MergeImport:
+			report.mergeSections(*existing, incoming)
+		}
+		if err := repo.SaveMenus(ctx, incoming); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	if !dryRun {
+		m.events.Publish(Event{Type: MenuReset})
+	}
+	return &report, nil
+}
+
+// importTags creates or updates the tags listed in a document and returns the resulting vocabulary.
+func importTags(ctx context.Context, repo Repository, docs []TagDocument) ([]Tag, error) {
+	for _, doc := range docs {
+		tag := Tag{Name: doc.Name, Label: doc.Label, Kind: TagKindFromText(doc.Kind)}
+		if err := tag.Validate(); err != nil {
+			return nil, fmt.Errorf("tag %q: %v", doc.Name, err)
+		}
+		existing, err := repo.FindTags(ctx, []string{tag.Name})
+		if err != nil {
+			return nil, err
+		}
+		if len(*existing) == 0 {
+			err = repo.CreateTag(ctx, &tag)
+		} else {
+			tag.ID = (*existing)[0].ID
+			err = repo.UpdateTag(ctx, &tag)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	vocabulary, err := repo.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return *vocabulary, nil
+}
+
+// ExportPriceSheet writes the items of the draft menus as a CSV price sheet.
+func (m *service) ExportPriceSheet(ctx context.Context, w io.Writer) error {
+	menus, err := m.repo.ListMenus(ctx, MenuFilter{})
+	if err != nil {
+		return err
+	}
+	return WritePriceSheet(w, *menus)
+}
+
+// ImportPriceSheet updates the title, type, price, active flag and list order of the items listed in a CSV price
+// sheet. Every row is checked before anything is written, and the changes are applied in a single transaction only if
+// no row has errors.
+func (m *service) ImportPriceSheet(ctx context.Context, r io.Reader) (*PriceSheetReport, error) {
+	rows, rowErrors, err := readPriceSheet(r)
+	if err != nil {
+		return nil, err
+	}
+	report := PriceSheetReport{Rows: len(rows) + len(rowErrors), Errors: rowErrors}
+	var changed []*Item
+	err = m.repo.Transaction(ctx, func(repo Repository) error {
+		items, err := repo.ListItems(ctx)
+		if err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]*Item, len(*items))
+		for i := range *items {
+			byID[(*items)[i].ID] = &(*items)[i]
+		}
+
+		seen := make(map[uuid.UUID]int, len(rows))
+		for _, row := range rows {
+			if first, ok := seen[row.id]; ok {
+				report.Errors = append(report.Errors, RowError{Row: row.row, Error: fmt.Sprintf(
+					"item %v is already listed on row %d", row.id, first)})
+				continue
+			}
+			seen[row.id] = row.row
+			item, ok := byID[row.id]
+			if !ok {
+				report.Errors = append(report.Errors, RowError{Row: row.row, Error: fmt.Sprintf(
+					"no item with id %v", row.id)})
+				continue
+			}
+			if !row.apply(item) {
+				report.Unchanged++
+				continue
+			}
+			if err := item.Validate(); err != nil {
+				report.Errors = append(report.Errors, RowError{Row: row.row, Error: err.Error()})
+				continue
+			}
+			changed = append(changed, item)
+		}
+		if len(report.Errors) > 0 {
+			changed = nil
+			return nil
+		}
+
+		for _, item := range changed {
+			if err := repo.UpdateItemListing(ctx, item); err != nil {
+				return err
+			}
+		}
+		report.Updated = len(changed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+	for _, item := range changed {
+		m.events.Publish(itemEvent(ItemUpdated, item))
+	}
+	return &report, nil
+}
+
+// PrintMenu renders one of the published menus for print, leaving out whatever guests are not meant to see.
+func (m *service) PrintMenu(ctx context.Context, rawID string, format PrintFormat, w io.Writer) error {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return err
+	}
+	menus, err := m.PublishedMenus(ctx, MenuFilter{})
+	if err != nil {
+		return err
+	}
+	var printable *Section
+	for _, section := range *menus {
+		if section.ID == id && section.Visible && section.Active {
+			found := section.Printable()
+			printable = &found
+			break
+		}
+	}
+	if printable == nil {
+		return ErrMenuNotFound
+	}
+
+	switch format {
+	case PDFPrint:
+		return m.printer.PDF(w, printable)
+	default:
+		return m.printer.HTML(w, printable)
+	}
 }
 
-func (m *service) Menus(ctx context.Context) (*[]Section, error) {
-	return m.repo.ListMenus(ctx)
+func (m *service) snapshot(ctx context.Context, version uint) (*Snapshot, error) {
+	snapshot := Snapshot{Version: version}
+	if err := m.repo.FindSnapshot(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
 }
 
 func (m *service) Sections(ctx context.Context) (*[]Section, error) {
@@ -62,7 +416,29 @@ func (m *service) SectionByID(ctx context.Context, rawID string) (*Section, erro
 }
 
 func (m *service) UpdateSectionContent(ctx context.Context, section *Section) error {
-	return m.repo.UpdateSection(ctx, section)
+	if err := m.repo.UpdateSection(ctx, section); err != nil {
+		return err
+	}
+	m.events.Publish(sectionEvent(SectionUpdated, section))
+	return nil
+}
+
+// SetSectionActive activates or deactivates a section without touching the rest of it.
+func (m *service) SetSectionActive(ctx context.Context, rawID string, active bool) (*Section, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &NullSection, err
+	}
+	var section Section
+	section.ID = id
+	if err := m.repo.SetSectionActive(ctx, &section, active); err != nil {
+		return &NullSection, err
+	}
+	if err := m.repo.FindSection(ctx, &section); err != nil {
+		return &NullSection, err
+	}
+	m.events.Publish(sectionEvent(SectionUpdated, &section))
+	return &section, nil
 }
 
 func (m *service) ReParentSection(ctx context.Context, section *Section, newParentID uuid.UUID) error {
@@ -72,7 +448,11 @@ func (m *service) ReParentSection(ctx context.Context, section *Section, newPare
 		return err
 	}
 
-	return m.repo.UpdateSectionParent(ctx, section, &newParentSection)
+	if err := m.repo.UpdateSectionParent(ctx, section, &newParentSection); err != nil {
+		return err
+	}
+	m.events.Publish(Event{Type: SectionReParented, EntityID: &section.ID, ParentID: &newParentSection.ID})
+	return nil
 }
 
 func (m *service) DeleteSection(ctx context.Context, rawID string) error {
@@ -82,15 +462,30 @@ func (m *service) DeleteSection(ctx context.Context, rawID string) error {
 	}
 	var deletingSection Section
 	deletingSection.ID = deletingSectionID
-	return m.repo.DeleteSection(ctx, &deletingSection)
+	if err := m.repo.DeleteSection(ctx, &deletingSection); err != nil {
+		return err
+	}
+	m.events.Publish(Event{Type: SectionDeleted, EntityID: &deletingSectionID})
+	return nil
 }
 
 func (m *service) NewItem(ctx context.Context, item *Item) error {
-	return m.repo.CreateItem(ctx, item)
+	if err := m.repo.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	m.events.Publish(itemEvent(ItemCreated, item))
+	return nil
 }
 
 func (m *service) Items(ctx context.Context) (*[]Item, error) {
-	return m.repo.ListItems(ctx)
+	items, err := m.repo.ListItems(ctx)
+	if err != nil {
+		return items, err
+	}
+	for i := range *items {
+		(*items)[i].withDefaultVariants()
+	}
+	return items, nil
 }
 
 func (m *service) ItemByID(ctx context.Context, rawID string) (*Item, error) {
@@ -103,6 +498,7 @@ func (m *service) ItemByID(ctx context.Context, rawID string) (*Item, error) {
 	if err := m.repo.FindItem(ctx, &item); err != nil {
 		return &NullItem, err
 	}
+	item.withDefaultVariants()
 
 	return &item, nil
 }
@@ -113,11 +509,123 @@ func (m *service) ReParentItem(ctx context.Context, item *Item, newSectionParent
 	if err := m.repo.FindSection(ctx, &newParentSection); err != nil {
 		return err
 	}
-	return m.repo.UpdateItemParent(ctx, item, &newParentSection)
+	if err := m.repo.UpdateItemParent(ctx, item, &newParentSection); err != nil {
+		return err
+	}
+	m.events.Publish(Event{Type: ItemReParented, EntityID: &item.ID, ParentID: &newParentSection.ID})
+	return nil
+}
+
+// SetSectionTaxCategory gives a section the tax category its items fall under unless they have their own; nil makes
+// it inherit the category of the section above it again.
+func (m *service) SetSectionTaxCategory(ctx context.Context, rawID string, category *string) (*Section, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &NullSection, err
+	}
+	if err := m.checkTaxCategory(category); err != nil {
+		return &NullSection, err
+	}
+	var section Section
+	section.ID = id
+	if err := m.repo.SetSectionTaxCategory(ctx, &section, category); err != nil {
+		return &NullSection, err
+	}
+	if err := m.repo.FindSection(ctx, &section); err != nil {
+		return &NullSection, err
+	}
+	m.events.Publish(sectionEvent(SectionUpdated, &section))
+	return &section, nil
+}
+
+// SetSectionStation sends the items beneath a section to a prep station unless they have their own; nil makes it
+// inherit the station of the section above it again.
+func (m *service) SetSectionStation(ctx context.Context, rawID string, station *string) (*Section, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &NullSection, err
+	}
+	if err := m.checkStation(station); err != nil {
+		return &NullSection, err
+	}
+	var section Section
+	section.ID = id
+	if err := m.repo.SetSectionStation(ctx, &section, station); err != nil {
+		return &NullSection, err
+	}
+	if err := m.repo.FindSection(ctx, &section); err != nil {
+		return &NullSection, err
+	}
+	m.events.Publish(sectionEvent(SectionUpdated, &section))
+	return &section, nil
 }
 
 func (m *service) UpdateItemContent(ctx context.Context, item *Item) error {
-	return m.repo.UpdateItem(ctx, item)
+	if err := m.repo.UpdateItem(ctx, item); err != nil {
+		return err
+	}
+	m.events.Publish(itemEvent(ItemUpdated, item))
+	return nil
+}
+
+// SetItemActive activates or deactivates an item without touching the rest of it, e.g. to mark it sold out.
+func (m *service) SetItemActive(ctx context.Context, rawID string, active bool) (*Item, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &NullItem, err
+	}
+	var item Item
+	item.ID = id
+	if err := m.repo.SetItemActive(ctx, &item, active); err != nil {
+		return &NullItem, err
+	}
+	if err := m.repo.FindItem(ctx, &item); err != nil {
+		return &NullItem, err
+	}
+	m.events.Publish(itemEvent(ItemUpdated, &item))
+	return &item, nil
+}
+
+// SetItemTaxCategory gives an item its own tax category; nil makes it inherit its section's again.
+func (m *service) SetItemTaxCategory(ctx context.Context, rawID string, category *string) (*Item, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &NullItem, err
+	}
+	if err := m.checkTaxCategory(category); err != nil {
+		return &NullItem, err
+	}
+	var item Item
+	item.ID = id
+	if err := m.repo.SetItemTaxCategory(ctx, &item, category); err != nil {
+		return &NullItem, err
+	}
+	if err := m.repo.FindItem(ctx, &item); err != nil {
+		return &NullItem, err
+	}
+	m.events.Publish(itemEvent(ItemUpdated, &item))
+	return &item, nil
+}
+
+// SetItemStation sends an item to its own prep station; nil makes it inherit its section's again.
+func (m *service) SetItemStation(ctx context.Context, rawID string, station *string) (*Item, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &NullItem, err
+	}
+	if err := m.checkStation(station); err != nil {
+		return &NullItem, err
+	}
+	var item Item
+	item.ID = id
+	if err := m.repo.SetItemStation(ctx, &item, station); err != nil {
+		return &NullItem, err
+	}
+	if err := m.repo.FindItem(ctx, &item); err != nil {
+		return &NullItem, err
+	}
+	m.events.Publish(itemEvent(ItemUpdated, &item))
+	return &item, nil
 }
 
 func (m *service) DeleteItem(ctx context.Context, rawID string) error {
@@ -127,5 +635,318 @@ func (m *service) DeleteItem(ctx context.Context, rawID string) error {
 	}
 	var item Item
 	item.ID = id
-	return m.repo.DeleteItem(ctx, &item)
+	if err := m.repo.DeleteItem(ctx, &item); err != nil {
+		return err
+	}
+	m.events.Publish(Event{Type: ItemDeleted, EntityID: &id})
+	return nil
+}
+
+func (m *service) ModifierGroups(ctx context.Context, rawItemID string) (*[]ModifierGroup, error) {
+	item, err := m.ItemByID(ctx, rawItemID)
+	if err != nil {
+		return &[]ModifierGroup{}, err
+	}
+	return m.repo.ListModifierGroups(ctx, item)
+}
+
+// NewModifierGroup attaches a new modifier group, along with its options, to an existing item.
+func (m *service) NewModifierGroup(ctx context.Context, group *ModifierGroup) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	var item Item
+	item.ID = *group.ItemID
+	if err := m.repo.FindItem(ctx, &item); err != nil {
+		return err
+	}
+	if err := m.repo.CreateModifierGroup(ctx, group); err != nil {
+		return err
+	}
+	m.itemChanged(ctx, item.ID)
+	return nil
+}
+
+// UpdateModifierGroup replaces the rules and options of an existing modifier group. Options without an ID are
+// created and existing options that are not listed are removed.
+func (m *service) UpdateModifierGroup(ctx context.Context, group *ModifierGroup) error {
+	var existing ModifierGroup
+	existing.ID = group.ID
+	if err := m.repo.FindModifierGroup(ctx, &existing); err != nil {
+		return err
+	}
+	group.ItemID = existing.ItemID
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	if err := m.repo.UpdateModifierGroup(ctx, group); err != nil {
+		return err
+	}
+	m.itemChanged(ctx, *group.ItemID)
+	return nil
+}
+
+func (m *service) DeleteModifierGroup(ctx context.Context, rawID string) error {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return err
+	}
+	var group ModifierGroup
+	group.ID = id
+	if err := m.repo.FindModifierGroup(ctx, &group); err != nil {
+		return err
+	}
+	if err := m.repo.DeleteModifierGroup(ctx, &group); err != nil {
+		return err
+	}
+	m.itemChanged(ctx, *group.ItemID)
+	return nil
+}
+
+// Variants lists the variants of an item. Items without stored variants report their default variant.
+func (m *service) Variants(ctx context.Context, rawItemID string) (*[]Variant, error) {
+	item, err := m.ItemByID(ctx, rawItemID)
+	if err != nil {
+		return &[]Variant{}, err
+	}
+	variants := item.PriceVariants()
+	return &variants, nil
+}
+
+func (m *service) NewVariant(ctx context.Context, variant *Variant) error {
+	if err := variant.Validate(); err != nil {
+		return err
+	}
+	var item Item
+	item.ID = *variant.ItemID
+	if err := m.repo.FindItem(ctx, &item); err != nil {
+		return err
+	}
+	if err := m.repo.CreateVariant(ctx, variant); err != nil {
+		return err
+	}
+	m.itemChanged(ctx, item.ID)
+	return nil
+}
+
+func (m *service) UpdateVariant(ctx context.Context, variant *Variant) error {
+	if err := variant.Validate(); err != nil {
+		return err
+	}
+	existing := Variant{ItemID: variant.ItemID}
+	existing.ID = variant.ID
+	if err := m.repo.FindVariant(ctx, &existing); err != nil {
+		return err
+	}
+	if err := m.repo.UpdateVariant(ctx, variant); err != nil {
+		return err
+	}
+	m.itemChanged(ctx, *existing.ItemID)
+	return nil
+}
+
+func (m *service) DeleteVariant(ctx context.Context, rawItemID, rawID string) error {
+	itemID, err := uuid.Parse(rawItemID)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return err
+	}
+	variant := Variant{ItemID: &itemID}
+	variant.ID = id
+	if err := m.repo.DeleteVariant(ctx, &variant); err != nil {
+		return err
+	}
+	m.itemChanged(ctx, itemID)
+	return nil
+}
+
+// NewAvailabilityWindow attaches an availability window to an existing section or item.
+func (m *service) NewAvailabilityWindow(ctx context.Context, window *AvailabilityWindow) error {
+	if window.TimeZone == "" {
+		window.TimeZone = "UTC"
+	}
+	if err := window.Validate(); err != nil {
+		return err
+	}
+	if window.SectionID != nil {
+		var section Section
+		section.ID = *window.SectionID
+		if err := m.repo.FindSection(ctx, &section); err != nil {
+			return err
+		}
+	} else {
+		var item Item
+		item.ID = *window.ItemID
+		if err := m.repo.FindItem(ctx, &item); err != nil {
+			return err
+		}
+	}
+	if err := m.repo.CreateAvailabilityWindow(ctx, window); err != nil {
+		return err
+	}
+	m.windowChanged(ctx, window)
+	return nil
+}
+
+// UpdateAvailabilityWindow changes the days, times, time zone and dates of a window. The window stays attached to
+// the section or item it was created for.
+func (m *service) UpdateAvailabilityWindow(ctx context.Context, window *AvailabilityWindow) error {
+	var existing AvailabilityWindow
+	existing.ID = window.ID
+	if err := m.repo.FindAvailabilityWindow(ctx, &existing); err != nil {
+		return err
+	}
+	window.SectionID = existing.SectionID
+	window.ItemID = existing.ItemID
+	if window.TimeZone == "" {
+		window.TimeZone = "UTC"
+	}
+	if err := window.Validate(); err != nil {
+		return err
+	}
+	if err := m.repo.UpdateAvailabilityWindow(ctx, window); err != nil {
+		return err
+	}
+	m.windowChanged(ctx, window)
+	return nil
+}
+
+func (m *service) DeleteAvailabilityWindow(ctx context.Context, rawID string) error {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return err
+	}
+	var window AvailabilityWindow
+	window.ID = id
+	if err := m.repo.FindAvailabilityWindow(ctx, &window); err != nil {
+		return err
+	}
+	if err := m.repo.DeleteAvailabilityWindow(ctx, &window); err != nil {
+		return err
+	}
+	m.windowChanged(ctx, &window)
+	return nil
+}
+
+func (m *service) Tags(ctx context.Context) (*[]Tag, error) {
+	return m.repo.ListTags(ctx)
+}
+
+func (m *service) NewTag(ctx context.Context, tag *Tag) error {
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+	return m.repo.CreateTag(ctx, tag)
+}
+
+func (m *service) UpdateTag(ctx context.Context, tag *Tag) error {
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+	if err := m.repo.UpdateTag(ctx, tag); err != nil {
+		return err
+	}
+	// Every section and item carrying the tag changed with it.
+	m.events.Publish(Event{Type: MenuReset})
+	return nil
+}
+
+func (m *service) DeleteTag(ctx context.Context, rawID string) error {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return err
+	}
+	var tag Tag
+	tag.ID = id
+	if err := m.repo.DeleteTag(ctx, &tag); err != nil {
+		return err
+	}
+	m.events.Publish(Event{Type: MenuReset})
+	return nil
+}
+
+// TagItem replaces the tags of an item with the named tags.
+func (m *service) TagItem(ctx context.Context, rawItemID string, names []string) (*Item, error) {
+	item, err := m.ItemByID(ctx, rawItemID)
+	if err != nil {
+		return item, err
+	}
+	tags, err := m.tagsByName(ctx, names)
+	if err != nil {
+		return item, err
+	}
+	if err := m.repo.ReplaceItemTags(ctx, item, tags); err != nil {
+		return item, err
+	}
+	item.Tags = tags
+	m.events.Publish(itemEvent(ItemUpdated, item))
+	return item, nil
+}
+
+// TagSection replaces the tags of a section with the named tags. Items beneath the section inherit them.
+func (m *service) TagSection(ctx context.Context, rawSectionID string, names []string) (*Section, error) {
+	section, err := m.SectionByID(ctx, rawSectionID)
+	if err != nil {
+		return section, err
+	}
+	tags, err := m.tagsByName(ctx, names)
+	if err != nil {
+		return section, err
+	}
+	if err := m.repo.ReplaceSectionTags(ctx, section, tags); err != nil {
+		return section, err
+	}
+	section.Tags = tags
+	m.events.Publish(sectionEvent(SectionUpdated, section))
+	return section, nil
+}
+
+// itemChanged publishes the item as it now stands after something attached to it changed, e.g. a variant, a
+// modifier group or an availability window.
+func (m *service) itemChanged(ctx context.Context, itemID uuid.UUID) {
+	var item Item
+	item.ID = itemID
+	if err := m.repo.FindItem(ctx, &item); err != nil {
+		m.events.Publish(Event{Type: ItemUpdated, EntityID: &itemID})
+		return
+	}
+	m.events.Publish(itemEvent(ItemUpdated, &item))
+}
+
+// windowChanged publishes the section or item the availability window belongs to.
+func (m *service) windowChanged(ctx context.Context, window *AvailabilityWindow) {
+	if window.ItemID != nil {
+		m.itemChanged(ctx, *window.ItemID)
+		return
+	}
+	var section Section
+	section.ID = *window.SectionID
+	if err := m.repo.FindSection(ctx, &section); err != nil {
+		m.events.Publish(Event{Type: SectionUpdated, EntityID: window.SectionID})
+		return
+	}
+	m.events.Publish(sectionEvent(SectionUpdated, &section))
+}
+
+func (m *service) tagsByName(ctx context.Context, names []string) ([]Tag, error) {
+	if len(names) == 0 {
+		return []Tag{}, nil
+	}
+	tags, err := m.repo.FindTags(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(*tags))
+	for _, tag := range *tags {
+		found[tag.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, fmt.Errorf("unknown tag %q", name)
+		}
+	}
+	return *tags, nil
 }
@@ -0,0 +1,101 @@
+package menu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// attachmentRepository stores nothing; it only knows that every variant, modifier group and availability window
+// belongs to item. The rest of Repository is left unimplemented.
+type attachmentRepository struct {
+	Repository
+	item uuid.UUID
+}
+
+func (r attachmentRepository) FindItem(_ context.Context, item *Item) error {
+	item.Title = "Burger"
+	return nil
+}
+func (r attachmentRepository) FindModifierGroup(_ context.Context, group *ModifierGroup) error {
+	group.ItemID = &r.item
+	return nil
+}
+func (r attachmentRepository) CreateModifierGroup(context.Context, *ModifierGroup) error { return nil }
+func (r attachmentRepository) UpdateModifierGroup(context.Context, *ModifierGroup) error { return nil }
+func (r attachmentRepository) DeleteModifierGroup(context.Context, *ModifierGroup) error { return nil }
+func (r attachmentRepository) FindVariant(_ context.Context, variant *Variant) error {
+	variant.ItemID = &r.item
+	return nil
+}
+func (r attachmentRepository) CreateVariant(context.Context, *Variant) error { return nil }
+func (r attachmentRepository) UpdateVariant(context.Context, *Variant) error { return nil }
+func (r attachmentRepository) DeleteVariant(context.Context, *Variant) error { return nil }
+func (r attachmentRepository) FindAvailabilityWindow(_ context.Context, window *AvailabilityWindow) error {
+	window.ItemID = &r.item
+	return nil
+}
+func (r attachmentRepository) DeleteAvailabilityWindow(context.Context, *AvailabilityWindow) error {
+	return nil
+}
+
+// recordingBus keeps what was published.
+type recordingBus struct {
+	EventBus
+	published []Event
+}
+
+func (b *recordingBus) Publish(event Event) {
+	b.published = append(b.published, event)
+}
+
+func TestChangesToWhatHangsOffAnItemPublishTheItem(t *testing.T) {
+	item := uuid.New()
+	tests := []struct {
+		name   string
+		change func(context.Context, *service) error
+	}{
+		{"new modifier group", func(ctx context.Context, s *service) error {
+			return s.NewModifierGroup(ctx, &ModifierGroup{Title: "Sides", ItemID: &item})
+		}},
+		{"modifier group updated", func(ctx context.Context, s *service) error {
+			return s.UpdateModifierGroup(ctx, &ModifierGroup{Title: "Sides", Options: []ModifierOption{
+				{Title: "Fries", PriceDelta: 150, Active: true}}})
+		}},
+		{"modifier group deleted", func(ctx context.Context, s *service) error {
+			return s.DeleteModifierGroup(ctx, uuid.New().String())
+		}},
+		{"new variant", func(ctx context.Context, s *service) error {
+			return s.NewVariant(ctx, &Variant{Name: "Large", ItemID: &item, Price: 1800})
+		}},
+		{"variant repriced", func(ctx context.Context, s *service) error {
+			return s.UpdateVariant(ctx, &Variant{Name: "Large", ItemID: &item, Price: 1900})
+		}},
+		{"variant deleted", func(ctx context.Context, s *service) error {
+			return s.DeleteVariant(ctx, item.String(), uuid.New().String())
+		}},
+		{"availability window deleted", func(ctx context.Context, s *service) error {
+			return s.DeleteAvailabilityWindow(ctx, uuid.New().String())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &recordingBus{}
+			s := NewService(attachmentRepository{item: item}, nil, bus, nil, nil)
+			if err := tt.change(context.Background(), s); err != nil {
+				t.Fatal(err)
+			}
+			if len(bus.published) != 1 {
+				t.Fatalf("published %d events, want 1", len(bus.published))
+			}
+			event := bus.published[0]
+			if event.Type != ItemUpdated || event.EntityID == nil || *event.EntityID != item {
+				t.Errorf("published %s for %v, want %s for %v", event.Type, event.EntityID, ItemUpdated, item)
+			}
+			if event.Item == nil || event.Item.Title != "Burger" {
+				t.Errorf("event carries %+v, want the item as it now stands", event.Item)
+			}
+		})
+	}
+}
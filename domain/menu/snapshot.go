@@ -0,0 +1,186 @@
+package menu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+var ErrNoPublishedMenu = errors.New("no menu has been published yet")
+
+// Snapshot is an immutable, published copy of the whole menu tree. Editors work on the draft held in the sections
+// and items tables, and every publish records the draft as the next version.
+type Snapshot struct {
+	domain.Base
+	Version        uint       `json:"version" gorm:"uniqueIndex;not null"`
+	Note           string     `json:"note"`
+	PublishedBy    *uuid.UUID `json:"published_by"`
+	RolledBackFrom *uint      `json:"rolled_back_from,omitempty"`
+	Content        string     `json:"-" gorm:"not null"`
+}
+
+// NewSnapshot serialises a menu tree so that it can be published.
+func NewSnapshot(menus []Section, publishedBy *uuid.UUID, note string) (*Snapshot, error) {
+	content, err := json.Marshal(menus)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Note: note, PublishedBy: publishedBy, Content: string(content)}, nil
+}
+
+// Menus deserialises the menu tree recorded in the snapshot.
+func (s *Snapshot) Menus() ([]Section, error) {
+	var menus []Section
+	if err := json.Unmarshal([]byte(s.Content), &menus); err != nil {
+		return nil, fmt.Errorf("snapshot %d is corrupt: %v", s.Version, err)
+	}
+	return menus, nil
+}
+
+// Change describes a section or item that differs between two snapshots.
+type Change struct {
+	ID     uuid.UUID `json:"id"`
+	Kind   string    `json:"kind"`
+	Title  string    `json:"title"`
+	Fields []string  `json:"fields,omitempty"`
+}
+
+// SnapshotDiff lists the sections and items added, removed and changed from one snapshot to another.
+type SnapshotDiff struct {
+	From    uint     `json:"from"`
+	To      uint     `json:"to"`
+	Added   []Change `json:"added"`
+	Removed []Change `json:"removed"`
+	Changed []Change `json:"changed"`
+}
+
+// Diff compares two snapshots section by section and item by item.
+func Diff(from, to *Snapshot) (*SnapshotDiff, error) {
+	fromMenus, err := from.Menus()
+	if err != nil {
+		return nil, err
+	}
+	toMenus, err := to.Menus()
+	if err != nil {
+		return nil, err
+	}
+	before, after := flatten(fromMenus), flatten(toMenus)
+
+	diff := SnapshotDiff{From: from.Version, To: to.Version, Added: []Change{}, Removed: []Change{}, Changed: []Change{}}
+	for id, node := range after {
+		previous, ok := before[id]
+		if !ok {
+			diff.Added = append(diff.Added, Change{ID: id, Kind: node.kind, Title: node.title})
+			continue
+		}
+		var fields []string
+		for field, value := range node.fields {
+			if previous.fields[field] != value {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			sort.Strings(fields)
+			diff.Changed = append(diff.Changed, Change{ID: id, Kind: node.kind, Title: node.title, Fields: fields})
+		}
+	}
+	for id, node := range before {
+		if _, ok := after[id]; !ok {
+			diff.Removed = append(diff.Removed, Change{ID: id, Kind: node.kind, Title: node.title})
+		}
+	}
+	for _, changes := range [][]Change{diff.Added, diff.Removed, diff.Changed} {
+		sortChanges(changes)
+	}
+	return &diff, nil
+}
+
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind > changes[j].Kind
+		}
+		return changes[i].Title < changes[j].Title
+	})
+}
+
+// node is a single section or item with its comparable fields encoded as JSON.
+type node struct {
+	kind   string
+	title  string
+	fields map[string]string
+}
+
+func flatten(sections []Section) map[uuid.UUID]node {
+	nodes := make(map[uuid.UUID]node)
+	var walkSection func(Section, *uuid.UUID)
+	var walkItem func(Item, *uuid.UUID)
+
+	walkSection = func(s Section, parent *uuid.UUID) {
+		if s.ID == uuid.Nil {
+			return
+		}
+		nodes[s.ID] = node{kind: "section", title: s.Title, fields: encodeFields(map[string]interface{}{
+			"title":        s.Title,
+			"description":  s.Description,
+			"active":       s.Active,
+			"visible":      s.Visible,
+			"type":         s.Type,
+			"list_order":   s.ListOrder,
+			"parent":       parent,
+			"availability": s.Availability,
+			"tags":         tagNames(s.Tags),
+		})}
+		for _, sub := range s.SubSections {
+			walkSection(sub, &s.ID)
+		}
+		for _, item := range s.Items {
+			walkItem(item, &s.ID)
+		}
+	}
+	walkItem = func(i Item, parent *uuid.UUID) {
+		nodes[i.ID] = node{kind: "item", title: i.Title, fields: encodeFields(map[string]interface{}{
+			"title":           i.Title,
+			"description":     i.Description,
+			"price":           i.Price,
+			"active":          i.Active,
+			"type":            i.Type,
+			"list_order":      i.ListOrder,
+			"parent":          parent,
+			"variants":        i.Variants,
+			"modifier_groups": i.ModifierGroups,
+			"availability":    i.Availability,
+			"tags":            tagNames(i.Tags),
+		})}
+		walkSection(i.AddOns, &i.ID)
+		walkSection(i.Condiments, &i.ID)
+	}
+
+	for _, section := range sections {
+		walkSection(section, nil)
+	}
+	return nodes
+}
+
+func encodeFields(fields map[string]interface{}) map[string]string {
+	encoded := make(map[string]string, len(fields))
+	for name, value := range fields {
+		raw, _ := json.Marshal(value)
+		encoded[name] = string(raw)
+	}
+	return encoded
+}
+
+func tagNames(tags []Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	return names
+}
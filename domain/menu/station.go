@@ -0,0 +1,61 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownStation = errors.New("unknown prep station")
+
+// PrepStations lists the kitchen prep stations sections and items may be sent to; the kitchen's routing provides it.
+type PrepStations interface {
+	PrepStations() []string
+}
+
+// StationOr returns the section's prep station, or the one inherited from above it when it has none.
+func (s *Section) StationOr(inherited string) string {
+	if s.Station != nil {
+		return *s.Station
+	}
+	return inherited
+}
+
+// StationOr returns the item's prep station, or the one inherited from its section when it has none.
+func (i *Item) StationOr(inherited string) string {
+	if i.Station != nil {
+		return *i.Station
+	}
+	return inherited
+}
+
+// checkStation makes sure a prep station is one of those configured. A nil station is always fine, since it inherits
+// one instead.
+func (m *service) checkStation(station *string) error {
+	if station == nil {
+		return nil
+	}
+	for _, known := range m.stations.PrepStations() {
+		if known == *station {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownStation, *station)
+}
+
+// checkStations makes sure every prep station in a menu tree is one of those configured, e.g. before it is imported.
+func (m *service) checkStations(sections []Section) error {
+	for _, section := range sections {
+		if err := m.checkStation(section.Station); err != nil {
+			return fmt.Errorf("section %q: %w", section.Title, err)
+		}
+		if err := m.checkStations(section.SubSections); err != nil {
+			return err
+		}
+		for _, item := range section.Items {
+			if err := m.checkStation(item.Station); err != nil {
+				return fmt.Errorf("item %q: %w", item.Title, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package menu
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+//go:generate stringer -type=TagKind
+type TagKind int
+
+const (
+	UndefinedTag TagKind = iota
+	Allergen
+	Dietary
+	Custom
+)
+
+func (k TagKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *TagKind) UnmarshalText(text []byte) error {
+	*k = TagKindFromText(string(text))
+	return nil
+}
+
+func TagKindFromText(text string) TagKind {
+	switch strings.ToLower(text) {
+	case "allergen":
+		return Allergen
+	case "dietary":
+		return Dietary
+	case "custom":
+		return Custom
+	default:
+		return UndefinedTag
+	}
+}
+
+var tagNameRegex = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+
+// Tag labels items with allergens, dietary properties or anything else the restaurant wants guests to filter on.
+// Name is the stable, lower-case identifier used in queries, e.g. "shellfish" or "gluten-free".
+type Tag struct {
+	domain.Base
+	Name  string  `json:"name" gorm:"uniqueIndex;not null"`
+	Label string  `json:"label"`
+	Kind  TagKind `json:"kind" gorm:"not null;default:0"`
+}
+
+func (t *Tag) Validate() error {
+	if !tagNameRegex.MatchString(t.Name) {
+		return errors.New("tag name must be lower case letters and digits separated by dashes")
+	}
+	if t.Kind == UndefinedTag {
+		return errors.New("tag must be an allergen, dietary or custom tag")
+	}
+	return nil
+}
+
+// DefaultTags is the vocabulary the database is seeded with: the nine major food allergens and the common dietary
+// labels.
+var DefaultTags = []Tag{
+	{Name: "dairy", Label: "Milk", Kind: Allergen},
+	{Name: "eggs", Label: "Eggs", Kind: Allergen},
+	{Name: "fish", Label: "Fish", Kind: Allergen},
+	{Name: "shellfish", Label: "Crustacean Shellfish", Kind: Allergen},
+	{Name: "tree-nuts", Label: "Tree Nuts", Kind: Allergen},
+	{Name: "peanuts", Label: "Peanuts", Kind: Allergen},
+	{Name: "wheat", Label: "Wheat", Kind: Allergen},
+	{Name: "soy", Label: "Soybeans", Kind: Allergen},
+	{Name: "sesame", Label: "Sesame", Kind: Allergen},
+	{Name: "vegetarian", Label: "Vegetarian", Kind: Dietary},
+	{Name: "vegan", Label: "Vegan", Kind: Dietary},
+	{Name: "gluten-free", Label: "Gluten Free", Kind: Dietary},
+}
+
+// MenuFilter narrows a menu down to what a guest can order. Tags attached to a section are inherited by every
+// section and item beneath it, including the add-ons and condiments hung off an item, so tagging the "Bagel
+// Condiments" section with dairy marks every cream cheese as dairy.
+type MenuFilter struct {
+	// At leaves out sections and items that are inactive or outside of their availability windows.
+	At *time.Time
+	// ExcludeAllergens leaves out items carrying any of the named allergen tags.
+	ExcludeAllergens []string
+	// Diets leaves out items that do not carry every one of the named dietary tags.
+	Diets []string
+}
+
+// IsEmpty reports whether the filter lets everything through.
+func (f MenuFilter) IsEmpty() bool {
+	return f.At == nil && len(f.ExcludeAllergens) == 0 && len(f.Diets) == 0
+}
+
+// Apply prunes a menu tree down to what passes the filter.
+func (f MenuFilter) Apply(sections []Section) []Section {
+	if f.At != nil {
+		sections = AvailableAt(sections, *f.At)
+	}
+	if len(f.ExcludeAllergens) == 0 && len(f.Diets) == 0 {
+		return sections
+	}
+	return f.applySections(sections, nil)
+}
+
+func (f MenuFilter) applySections(sections []Section, inherited []Tag) []Section {
+	filtered := make([]Section, 0, len(sections))
+	for _, section := range sections {
+		tags := append(append([]Tag{}, inherited...), section.Tags...)
+		section.SubSections = f.applySections(section.SubSections, tags)
+		section.Items = f.applyItems(section.Items, tags)
+		filtered = append(filtered, section)
+	}
+	return filtered
+}
+
+func (f MenuFilter) applyItems(items []Item, inherited []Tag) []Item {
+	filtered := make([]Item, 0, len(items))
+	for _, item := range items {
+		if !f.allows(append(append([]Tag{}, inherited...), item.Tags...)) {
+			continue
+		}
+		item.AddOns.Items = f.applyItems(item.AddOns.Items, item.AddOns.Tags)
+		item.Condiments.Items = f.applyItems(item.Condiments.Items, item.Condiments.Tags)
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+func (f MenuFilter) allows(tags []Tag) bool {
+	names := make(map[string]TagKind, len(tags))
+	for _, tag := range tags {
+		names[tag.Name] = tag.Kind
+	}
+	for _, allergen := range f.ExcludeAllergens {
+		if kind, ok := names[allergen]; ok && kind == Allergen {
+			return false
+		}
+	}
+	for _, diet := range f.Diets {
+		if kind, ok := names[diet]; !ok || kind != Dietary {
+			return false
+		}
+	}
+	return true
+}
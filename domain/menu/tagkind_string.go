@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=TagKind"; DO NOT EDIT.
+
+package menu
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[UndefinedTag-0]
+	_ = x[Allergen-1]
+	_ = x[Dietary-2]
+	_ = x[Custom-3]
+}
+
+const _TagKind_name = "UndefinedTagAllergenDietaryCustom"
+
+var _TagKind_index = [...]uint8{0, 12, 20, 27, 33}
+
+func (i TagKind) String() string {
+	if i < 0 || i >= TagKind(len(_TagKind_index)-1) {
+		return "TagKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _TagKind_name[_TagKind_index[i]:_TagKind_index[i+1]]
+}
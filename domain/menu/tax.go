@@ -0,0 +1,65 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownTaxCategory = errors.New("unknown tax category")
+
+// TaxCategories lists the tax categories sections and items may be given; the tax calculator provides it.
+type TaxCategories interface {
+	TaxCategories() []string
+}
+
+// TaxCategoryOr returns the section's tax category, or the one inherited from above it when it has none.
+func (s *Section) TaxCategoryOr(inherited string) string {
+	if s.TaxCategory != nil {
+		return *s.TaxCategory
+	}
+	return inherited
+}
+
+// TaxCategoryOr returns the item's tax category, or the one inherited from its section when it has none.
+func (i *Item) TaxCategoryOr(inherited string) string {
+	if i.TaxCategory != nil {
+		return *i.TaxCategory
+	}
+	return inherited
+}
+
+// checkTaxCategory makes sure a tax category is one of those configured. A nil category is always fine, since it
+// inherits one instead.
+func (m *service) checkTaxCategory(category *string) error {
+	if category == nil {
+		return nil
+	}
+	for _, known := range m.taxCategories.TaxCategories() {
+		if known == *category {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownTaxCategory, *category)
+}
+
+// checkTaxCategories makes sure every tax category in a menu tree is one of those configured, e.g. before it is
+// imported.
+func (m *service) checkTaxCategories(sections []Section) error {
+	for _, section := range sections {
+		if err := m.checkTaxCategory(section.TaxCategory); err != nil {
+			return fmt.Errorf("section %q: %w", section.Title, err)
+		}
+		if err := m.checkTaxCategories(section.SubSections); err != nil {
+			return err
+		}
+		for _, item := range section.Items {
+			if err := m.checkTaxCategory(item.TaxCategory); err != nil {
+				return fmt.Errorf("item %q: %w", item.Title, err)
+			}
+			if err := m.checkTaxCategories([]Section{item.AddOns, item.Condiments}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package menu
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+// DefaultVariantName is the name given to the implicit variant of an item that has no variants of its own.
+const DefaultVariantName = "Regular"
+
+// Variant is a size or portion of an item that is sold at its own price, e.g. a "Large" coffee or a "Half" salad.
+type Variant struct {
+	domain.Base
+	ItemID    *uuid.UUID `json:"item_id"`
+	Name      string     `json:"name" gorm:"not null"`
+	Price     uint64     `json:"price" gorm:"default:000"`
+	ListOrder uint       `json:"list_order" gorm:"default:0"`
+	Active    bool       `json:"active"`
+	SKU       *string    `json:"sku,omitempty"`
+}
+
+func (v *Variant) Validate() error {
+	if v.Name == "" {
+		return errors.New("variant name is empty")
+	}
+	if v.ItemID == nil || *v.ItemID == uuid.Nil {
+		return errors.New("variant must belong to an item")
+	}
+	if v.SKU != nil && *v.SKU == "" {
+		return errors.New("variant sku is empty")
+	}
+	return nil
+}
+
+// DefaultVariant describes a single-price item as a variant. It shares the item's ID so that clients can refer to
+// it the same way they refer to explicitly stored variants.
+func (i *Item) DefaultVariant() Variant {
+	variant := Variant{
+		ItemID: &i.ID,
+		Name:   DefaultVariantName,
+		Price:  i.Price,
+		Active: i.Active,
+	}
+	variant.ID = i.ID
+	return variant
+}
+
+// PriceVariants returns the item's variants, falling back to its default variant when none are stored.
+func (i *Item) PriceVariants() []Variant {
+	if len(i.Variants) > 0 {
+		return i.Variants
+	}
+	return []Variant{i.DefaultVariant()}
+}
+
+// VariantByID looks up one of the item's variants, including its default variant.
+func (i *Item) VariantByID(id uuid.UUID) (Variant, bool) {
+	for _, variant := range i.PriceVariants() {
+		if variant.ID == id {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+// withDefaultVariants fills in the default variant of every single-price item in the section tree so that clients
+// can treat every item as having at least one variant.
+func (s *Section) withDefaultVariants() {
+	for i := range s.Items {
+		s.Items[i].withDefaultVariants()
+	}
+	for i := range s.SubSections {
+		s.SubSections[i].withDefaultVariants()
+	}
+}
+
+func (i *Item) withDefaultVariants() {
+	i.Variants = i.PriceVariants()
+	i.AddOns.withDefaultVariants()
+	i.Condiments.withDefaultVariants()
+}
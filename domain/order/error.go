@@ -0,0 +1,12 @@
+package order
+
+import "errors"
+
+var (
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrLineNotFound   = errors.New("line item not found")
+	ErrNotACart       = errors.New("order has already been placed")
+	ErrEmptyCart      = errors.New("cart has no line items")
+	ErrNotCancellable = errors.New("order can no longer be cancelled")
+	ErrOrderChanged   = errors.New("order was changed by another request; try again")
+)
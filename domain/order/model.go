@@ -0,0 +1,165 @@
+package order
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+// Status is where an order stands.
+type Status string
+
+const (
+	// Cart orders are still being put together and can be changed freely; nothing has been sent to the kitchen.
+	Cart Status = "cart"
+	// Placed orders were submitted, priced against the menu of the moment they were placed.
+	Placed Status = "placed"
+	// Cancelled orders were called off, by the guest or by staff.
+	Cancelled Status = "cancelled"
+)
+
+// Customer is who an order belongs to: an account, or a device such as a kiosk calling with an API key.
+type Customer struct {
+	AccountID *uuid.UUID `json:"account_id,omitempty" gorm:"index"`
+	APIKeyID  *uuid.UUID `json:"api_key_id,omitempty" gorm:"index"`
+}
+
+// Owns reports whether the order belongs to the customer.
+func (c Customer) Owns(o *Order) bool {
+	switch {
+	case c.AccountID != nil:
+		return o.AccountID != nil && *o.AccountID == *c.AccountID
+	case c.APIKeyID != nil:
+		return o.APIKeyID != nil && *o.APIKeyID == *c.APIKeyID
+	default:
+		return false
+	}
+}
+
+// Order is a cart being filled, or an order that was placed from one. Its line items carry the titles and prices of
+// the menu at the time they were added, and again at the time it was placed, so that later menu edits do not rewrite
+// what a guest was charged.
+type Order struct {
+	domain.Base
+	Status Status `json:"status" gorm:"not null;index"`
+	Customer
+	Notes        *string    `json:"notes,omitempty"`
+	Lines        []LineItem `json:"lines" gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Subtotal     uint64     `json:"subtotal" gorm:"not null;default:0"`
+	Discount     uint64     `json:"discount" gorm:"not null;default:0"`
+	Tax          uint64     `json:"tax" gorm:"not null;default:0"`
+	Total        uint64     `json:"total" gorm:"not null;default:0"`
+	PlacedAt     *time.Time `json:"placed_at,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CancelReason *string    `json:"cancel_reason,omitempty"`
+}
+
+// total adds up the line items of a cart. Discounts and taxes are only worked out when it is placed, so until then its
+// total is its subtotal.
+func (o *Order) total() {
+	o.Subtotal, o.Discount, o.Tax = 0, 0, 0
+	for _, line := range o.Lines {
+		o.Subtotal += line.Total
+	}
+	o.Total = o.Subtotal
+}
+
+// price takes the line items and amounts of the order from a quote. Line items keep their ids, in the order of the
+// quote's lines, so that a cart priced again is not stored as new line items.
+func (o *Order) price(q *quote.Quote) {
+	lines := make([]LineItem, len(q.Lines))
+	for i, line := range q.Lines {
+		lines[i] = lineItem(line)
+		if i < len(o.Lines) {
+			lines[i].ID = o.Lines[i].ID
+		}
+	}
+	o.Lines = lines
+	o.Subtotal, o.Discount, o.Tax, o.Total = q.Subtotal, q.Discount, q.Tax, q.Total
+}
+
+// LineItem is an item ordered in a given quantity, with the variant, add-ons, condiments and modifier options picked
+// for it. Title, VariantName and UnitPrice are copied from the menu; UnitPrice covers the variant and everything
+// picked with it, and Total is UnitPrice times Quantity.
+type LineItem struct {
+	domain.Base
+	OrderID     uuid.UUID   `json:"order_id" gorm:"not null;index"`
+	ItemID      uuid.UUID   `json:"item_id" gorm:"not null"`
+	VariantID   uuid.UUID   `json:"variant_id" gorm:"not null"`
+	Title       string      `json:"title" gorm:"not null"`
+	VariantName string      `json:"variant_name" gorm:"not null"`
+	Quantity    uint        `json:"quantity" gorm:"not null"`
+	Notes       *string     `json:"notes,omitempty"`
+	Selections  []Selection `json:"selections" gorm:"foreignKey:LineItemID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UnitPrice   uint64      `json:"unit_price" gorm:"not null"`
+	Total       uint64      `json:"total" gorm:"not null"`
+}
+
+// Selection is an add-on, a condiment or a modifier option picked for a line item, with its title and price as they
+// were on the menu. Price is signed since modifier options may take money off.
+type Selection struct {
+	domain.Base
+	LineItemID uuid.UUID           `json:"line_item_id" gorm:"not null;index"`
+	Kind       quote.SelectionKind `json:"kind" gorm:"not null"`
+	// RefID is the add-on or condiment item, or the modifier option, that was picked.
+	RefID uuid.UUID `json:"ref_id" gorm:"not null"`
+	// GroupID is the modifier group of a modifier option.
+	GroupID *uuid.UUID `json:"group_id,omitempty"`
+	Group   string     `json:"group"`
+	Title   string     `json:"title" gorm:"not null"`
+	Price   int64      `json:"price" gorm:"not null"`
+}
+
+// lineItem copies a priced line into a line item to be stored.
+func lineItem(line quote.Line) LineItem {
+	item := LineItem{ItemID: line.ItemID, VariantID: line.VariantID, Title: line.Title,
+		VariantName: line.VariantName, Quantity: line.Quantity, Notes: line.Notes,
+		Selections: make([]Selection, 0, len(line.Selections)), UnitPrice: line.UnitPrice, Total: line.Total}
+	for _, selection := range line.Selections {
+		item.Selections = append(item.Selections, Selection{Kind: selection.Kind, RefID: selection.RefID,
+			GroupID: selection.GroupID, Group: selection.Group, Title: selection.Title, Price: selection.Price})
+	}
+	return item
+}
+
+// request rebuilds the request a line item was made from, to price it again.
+func (l *LineItem) request() quote.LineRequest {
+	variantID := l.VariantID
+	req := quote.LineRequest{ItemID: l.ItemID, VariantID: &variantID, Quantity: l.Quantity, Notes: l.Notes,
+		Modifiers: map[uuid.UUID][]uuid.UUID{}}
+	for _, selection := range l.Selections {
+		switch selection.Kind {
+		case quote.AddOnSelection:
+			req.AddOns = append(req.AddOns, selection.RefID)
+		case quote.CondimentSelection:
+			req.Condiments = append(req.Condiments, selection.RefID)
+		case quote.ModifierSelection:
+			if selection.GroupID != nil {
+				req.Modifiers[*selection.GroupID] = append(req.Modifiers[*selection.GroupID], selection.RefID)
+			}
+		}
+	}
+	return req
+}
+
+// OrderRequest starts a cart, or places an order right away, with the line items given.
+type OrderRequest struct {
+	Lines []quote.LineRequest `json:"lines"`
+	Notes *string             `json:"notes,omitempty"`
+}
+
+// CancelRequest calls an order off, optionally saying why.
+type CancelRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+// Filter narrows a list of orders down. Customer, when set, limits it to the orders of that customer, and PlacedSince
+// to the orders placed from then on.
+type Filter struct {
+	Status      Status `form:"status"`
+	Customer    *Customer
+	PlacedSince *time.Time `form:"-"`
+}
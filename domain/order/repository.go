@@ -0,0 +1,18 @@
+package order
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository describes the expected behavior for the data persistence of carts and orders.
+type Repository interface {
+	CreateOrder(ctx context.Context, order *Order) error
+	FindOrder(ctx context.Context, id uuid.UUID) (*Order, error)
+	// ListOrders returns the latest orders matching the filter, newest first.
+	ListOrders(ctx context.Context, filter Filter, limit int) ([]Order, error)
+	// SaveOrder saves the order and replaces its line items, unless its status is no longer the one given, in which
+	// case it reports false.
+	SaveOrder(ctx context.Context, order *Order, from Status) (bool, error)
+}
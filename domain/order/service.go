@@ -0,0 +1,232 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+// Events passed on to the notifier when orders change.
+const (
+	OrderPlaced    = "order.placed"
+	OrderCancelled = "order.cancelled"
+)
+
+// listLimit is how many orders Orders returns.
+const listLimit = 200
+
+// Notifier is told about orders being placed and cancelled, e.g. to pass them on to webhook subscribers or to the
+// kitchen.
+type Notifier interface {
+	Notify(ctx context.Context, eventType string, data interface{}) error
+}
+
+// Service describes the expected behavior for putting carts together and placing orders. Methods taking an owner only
+// act on that customer's orders, and answer ErrOrderNotFound for anyone else's; a nil owner is staff acting on any
+// order.
+type Service interface {
+	// NewCart starts a cart, with the line items given if any.
+	NewCart(ctx context.Context, customer Customer, request OrderRequest) (*Order, error)
+	AddLine(ctx context.Context, rawID string, owner *Customer, request quote.LineRequest) (*Order, error)
+	RemoveLine(ctx context.Context, rawID, rawLineID string, owner *Customer) (*Order, error)
+	// Checkout places a cart, pricing its line items again against the menu of the moment and working out its
+	// discounts and taxes.
+	Checkout(ctx context.Context, rawID string, owner *Customer) (*Order, error)
+	// Place places an order right away, without going through a cart.
+	Place(ctx context.Context, customer Customer, request OrderRequest) (*Order, error)
+	Orders(ctx context.Context, filter Filter) ([]Order, error)
+	OrderByID(ctx context.Context, rawID string, owner *Customer) (*Order, error)
+	// Cancel calls off a cart or a placed order.
+	Cancel(ctx context.Context, rawID string, owner *Customer, request CancelRequest) (*Order, error)
+}
+
+type service struct {
+	repo      Repository
+	quotes    quote.Service
+	notifiers []Notifier
+	log       domain.Logger
+}
+
+// NewService returns a Service that keeps orders in the repository and has them priced by the quote service. The
+// notifiers are told about orders in the order they are given.
+func NewService(repo Repository, quotes quote.Service, log domain.Logger, notifiers ...Notifier) *service {
+	return &service{repo, quotes, notifiers, log}
+}
+
+func (s *service) NewCart(ctx context.Context, customer Customer, req OrderRequest) (*Order, error) {
+	order := Order{Status: Cart, Customer: customer, Notes: req.Notes}
+	if err := s.addLines(ctx, &order, req.Lines); err != nil {
+		return &Order{}, err
+	}
+	if err := s.repo.CreateOrder(ctx, &order); err != nil {
+		return &Order{}, err
+	}
+	return &order, nil
+}
+
+func (s *service) AddLine(ctx context.Context, rawID string, owner *Customer, req quote.LineRequest) (*Order, error) {
+	order, err := s.cart(ctx, rawID, owner)
+	if err != nil {
+		return &Order{}, err
+	}
+	if err := s.addLines(ctx, order, []quote.LineRequest{req}); err != nil {
+		return &Order{}, err
+	}
+	return order, s.save(ctx, order, Cart)
+}
+
+func (s *service) RemoveLine(ctx context.Context, rawID, rawLineID string, owner *Customer) (*Order, error) {
+	lineID, err := uuid.Parse(rawLineID)
+	if err != nil {
+		return &Order{}, err
+	}
+	order, err := s.cart(ctx, rawID, owner)
+	if err != nil {
+		return &Order{}, err
+	}
+	for i, line := range order.Lines {
+		if line.ID == lineID {
+			order.Lines = append(order.Lines[:i], order.Lines[i+1:]...)
+			order.total()
+			return order, s.save(ctx, order, Cart)
+		}
+	}
+	return &Order{}, ErrLineNotFound
+}
+
+func (s *service) Checkout(ctx context.Context, rawID string, owner *Customer) (*Order, error) {
+	order, err := s.cart(ctx, rawID, owner)
+	if err != nil {
+		return &Order{}, err
+	}
+	if len(order.Lines) == 0 {
+		return &Order{}, ErrEmptyCart
+	}
+	requests := make([]quote.LineRequest, len(order.Lines))
+	for i := range order.Lines {
+		requests[i] = order.Lines[i].request()
+	}
+	q, err := s.quotes.Requote(ctx, quote.Request{Lines: requests})
+	if err != nil {
+		return &Order{}, err
+	}
+	order.price(q)
+	now := time.Now().UTC()
+	order.Status, order.PlacedAt = Placed, &now
+	if err := s.save(ctx, order, Cart); err != nil {
+		return &Order{}, err
+	}
+	s.notify(ctx, OrderPlaced, order)
+	return order, nil
+}
+
+func (s *service) Place(ctx context.Context, customer Customer, req OrderRequest) (*Order, error) {
+	if len(req.Lines) == 0 {
+		return &Order{}, ErrEmptyCart
+	}
+	q, err := s.quotes.Quote(ctx, quote.Request{Lines: req.Lines})
+	if err != nil {
+		return &Order{}, err
+	}
+	now := time.Now().UTC()
+	order := Order{Status: Placed, Customer: customer, Notes: req.Notes, PlacedAt: &now}
+	order.price(q)
+	if err := s.repo.CreateOrder(ctx, &order); err != nil {
+		return &Order{}, err
+	}
+	s.notify(ctx, OrderPlaced, &order)
+	return &order, nil
+}
+
+func (s *service) Orders(ctx context.Context, filter Filter) ([]Order, error) {
+	return s.repo.ListOrders(ctx, filter, listLimit)
+}
+
+func (s *service) OrderByID(ctx context.Context, rawID string, owner *Customer) (*Order, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &Order{}, err
+	}
+	order, err := s.repo.FindOrder(ctx, id)
+	if err != nil {
+		return &Order{}, err
+	}
+	if owner != nil && !owner.Owns(order) {
+		return &Order{}, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (s *service) Cancel(ctx context.Context, rawID string, owner *Customer, req CancelRequest) (*Order, error) {
+	order, err := s.OrderByID(ctx, rawID, owner)
+	if err != nil {
+		return &Order{}, err
+	}
+	from := order.Status
+	if from != Cart && from != Placed {
+		return &Order{}, ErrNotCancellable
+	}
+	now := time.Now().UTC()
+	order.Status, order.CancelledAt, order.CancelReason = Cancelled, &now, req.Reason
+	if err := s.save(ctx, order, from); err != nil {
+		return &Order{}, err
+	}
+	if from == Placed {
+		s.notify(ctx, OrderCancelled, order)
+	}
+	return order, nil
+}
+
+// cart finds an order that is still a cart.
+func (s *service) cart(ctx context.Context, rawID string, owner *Customer) (*Order, error) {
+	order, err := s.OrderByID(ctx, rawID, owner)
+	if err != nil {
+		return &Order{}, err
+	}
+	if order.Status != Cart {
+		return &Order{}, ErrNotACart
+	}
+	return order, nil
+}
+
+// save saves the order unless another request moved it on from the status it was read with.
+func (s *service) save(ctx context.Context, order *Order, from Status) error {
+	saved, err := s.repo.SaveOrder(ctx, order, from)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrOrderChanged
+	}
+	return nil
+}
+
+// addLines prices the line items requested and adds them to a cart.
+func (s *service) addLines(ctx context.Context, order *Order, requests []quote.LineRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	q, err := s.quotes.Quote(ctx, quote.Request{Lines: requests})
+	if err != nil {
+		return err
+	}
+	for _, line := range q.Lines {
+		order.Lines = append(order.Lines, lineItem(line))
+	}
+	order.total()
+	return nil
+}
+
+// notify passes an order change on to the notifiers. The change has already been saved, so a notifier that fails
+// only gets logged.
+func (s *service) notify(ctx context.Context, eventType string, order *Order) {
+	for _, notifier := range s.notifiers {
+		if err := notifier.Notify(ctx, eventType, order); err != nil {
+			s.log.Errorf("could not notify %s for order %s: %v", eventType, order.ID, err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+// publishedMenu serves a fixed published menu to the quote service.
+type publishedMenu struct {
+	sections []menu.Section
+}
+
+func (m *publishedMenu) PublishedMenus(context.Context, menu.MenuFilter) (*[]menu.Section, error) {
+	return &m.sections, nil
+}
+
+// memoryRepository keeps orders in a map.
+type memoryRepository struct {
+	orders map[uuid.UUID]Order
+}
+
+func (r *memoryRepository) CreateOrder(_ context.Context, order *Order) error {
+	order.ID = uuid.New()
+	r.orders[order.ID] = *order
+	return nil
+}
+
+func (r *memoryRepository) FindOrder(_ context.Context, id uuid.UUID) (*Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (r *memoryRepository) ListOrders(context.Context, Filter, int) ([]Order, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) SaveOrder(_ context.Context, order *Order, from Status) (bool, error) {
+	if r.orders[order.ID].Status != from {
+		return false, nil
+	}
+	r.orders[order.ID] = *order
+	return true, nil
+}
+
+// notifications keeps the events notified.
+type notifications []string
+
+func (n *notifications) Notify(_ context.Context, eventType string, _ interface{}) error {
+	*n = append(*n, eventType)
+	return nil
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Infof(string, ...interface{})  {}
+func (discardLogger) Errorf(string, ...interface{}) {}
+
+// bagelMenu is a published menu with a bagel that comes plain or toasted, a sold out muffin and a coffee that is only
+// sold in one size now.
+type bagelMenu struct {
+	bagel, muffin, coffee         uuid.UUID
+	large, small                  uuid.UUID
+	spreads, plain, scallion, lox uuid.UUID
+}
+
+func newBagelMenu() (bagelMenu, *publishedMenu) {
+	ids := bagelMenu{bagel: uuid.New(), muffin: uuid.New(), coffee: uuid.New(), large: uuid.New(), small: uuid.New(),
+		spreads: uuid.New(), plain: uuid.New(), scallion: uuid.New(), lox: uuid.New()}
+	spreads := menu.ModifierGroup{Title: "Spread", ItemID: &ids.bagel, MaxSelections: 1, Options: []menu.ModifierOption{
+		{Title: "Plain", Active: true}, {Title: "Scallion", PriceDelta: 50, Active: true},
+		{Title: "Lox", PriceDelta: 300, Active: false}}}
+	spreads.ID = ids.spreads
+	spreads.Options[0].ID, spreads.Options[1].ID, spreads.Options[2].ID = ids.plain, ids.scallion, ids.lox
+
+	bagel := menu.Item{Title: "Bagel", Price: 395, Active: true, ModifierGroups: []menu.ModifierGroup{spreads}}
+	bagel.ID = ids.bagel
+	muffin := menu.Item{Title: "Muffin", Price: 325, Active: false}
+	muffin.ID = ids.muffin
+	large := menu.Variant{ItemID: &ids.coffee, Name: "Large", Price: 400, Active: true}
+	large.ID = ids.large
+	small := menu.Variant{ItemID: &ids.coffee, Name: "Small", Price: 300, Active: false}
+	small.ID = ids.small
+	coffee := menu.Item{Title: "Coffee", Active: true, Variants: []menu.Variant{large, small}}
+	coffee.ID = ids.coffee
+
+	breakfast := menu.Section{Title: "Breakfast", Active: true, Visible: true,
+		Items: []menu.Item{bagel, muffin, coffee}}
+	breakfast.ID = uuid.New()
+	return ids, &publishedMenu{sections: []menu.Section{breakfast}}
+}
+
+func TestPlaceChecksTheOrderAgainstThePublishedMenu(t *testing.T) {
+	ids, published := newBagelMenu()
+	tests := []struct {
+		name      string
+		line      quote.LineRequest
+		wantErr   error
+		wantTotal uint64
+	}{
+		{"bagel with a spread", quote.LineRequest{ItemID: ids.bagel, Quantity: 2,
+			Modifiers: map[uuid.UUID][]uuid.UUID{ids.spreads: {ids.scallion}}}, nil, 890},
+		{"coffee in the size still sold", quote.LineRequest{ItemID: ids.coffee, VariantID: &ids.large}, nil, 400},
+		{"item not on the menu", quote.LineRequest{ItemID: uuid.New()}, quote.ErrItemUnavailable, 0},
+		{"sold out item", quote.LineRequest{ItemID: ids.muffin}, quote.ErrItemUnavailable, 0},
+		{"size no longer sold", quote.LineRequest{ItemID: ids.coffee, VariantID: &ids.small},
+			quote.ErrItemUnavailable, 0},
+		{"variant of another item", quote.LineRequest{ItemID: ids.bagel, VariantID: &ids.large},
+			quote.ErrInvalidSelection, 0},
+		{"spread that ran out", quote.LineRequest{ItemID: ids.bagel,
+			Modifiers: map[uuid.UUID][]uuid.UUID{ids.spreads: {ids.lox}}}, menu.ErrInactiveOption, 0},
+		{"two spreads where one is allowed", quote.LineRequest{ItemID: ids.bagel,
+			Modifiers: map[uuid.UUID][]uuid.UUID{ids.spreads: {ids.plain, ids.scallion}}},
+			menu.ErrTooManySelections, 0},
+		{"option of an unknown group", quote.LineRequest{ItemID: ids.bagel,
+			Modifiers: map[uuid.UUID][]uuid.UUID{uuid.New(): {ids.plain}}}, quote.ErrInvalidSelection, 0},
+		{"add-on the item does not have", quote.LineRequest{ItemID: ids.bagel, AddOns: []uuid.UUID{ids.coffee}},
+			quote.ErrInvalidSelection, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, notified := &memoryRepository{orders: make(map[uuid.UUID]Order)}, &notifications{}
+			s := NewService(repo, quote.NewService(published), discardLogger{}, notified)
+			order, err := s.Place(context.Background(), Customer{},
+				OrderRequest{Lines: []quote.LineRequest{tt.line}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Place() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.orders) != 0 || len(*notified) != 0 {
+					t.Errorf("rejected order was stored or notified")
+				}
+				return
+			}
+			if order.Status != Placed || order.Total != tt.wantTotal {
+				t.Errorf("order is %s for %d, want placed for %d", order.Status, order.Total, tt.wantTotal)
+			}
+			if len(*notified) != 1 || (*notified)[0] != OrderPlaced {
+				t.Errorf("notified %v, want %s", *notified, OrderPlaced)
+			}
+		})
+	}
+}
+
+func TestCheckoutRejectsWhatWentOffTheMenu(t *testing.T) {
+	ids, published := newBagelMenu()
+	repo, notified := &memoryRepository{orders: make(map[uuid.UUID]Order)}, &notifications{}
+	s := NewService(repo, quote.NewService(published), discardLogger{}, notified)
+	ctx := context.Background()
+	cart, err := s.NewCart(ctx, Customer{}, OrderRequest{Lines: []quote.LineRequest{{ItemID: ids.bagel}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	published.sections[0].Items[0].Active = false
+	if _, err := s.Checkout(ctx, cart.ID.String(), nil); !errors.Is(err, quote.ErrItemUnavailable) {
+		t.Fatalf("Checkout() error = %v, want %v", err, quote.ErrItemUnavailable)
+	}
+	if status := repo.orders[cart.ID].Status; status != Cart || len(*notified) != 0 {
+		t.Errorf("cart was moved on to %s and %d notifications were sent", status, len(*notified))
+	}
+}
+
+func TestOrdersOfOthersAreNotFound(t *testing.T) {
+	ids, published := newBagelMenu()
+	repo := &memoryRepository{orders: make(map[uuid.UUID]Order)}
+	s := NewService(repo, quote.NewService(published), discardLogger{})
+	ctx := context.Background()
+	owner, stranger := uuid.New(), uuid.New()
+	order, err := s.Place(ctx, Customer{AccountID: &owner},
+		OrderRequest{Lines: []quote.LineRequest{{ItemID: ids.bagel}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		owner   *Customer
+		wantErr error
+	}{
+		{"the customer", &Customer{AccountID: &owner}, nil},
+		{"staff", nil, nil},
+		{"another customer", &Customer{AccountID: &stranger}, ErrOrderNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.OrderByID(ctx, order.ID.String(), tt.owner); err != tt.wantErr {
+				t.Errorf("OrderByID() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ids, published := newBagelMenu()
+	tests := []struct {
+		name       string
+		status     Status
+		wantErr    error
+		wantNotify []string
+	}{
+		{"cart", Cart, nil, nil},
+		{"placed order", Placed, nil, []string{OrderCancelled}},
+		{"cancelled order", Cancelled, ErrNotCancellable, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, notified := &memoryRepository{orders: make(map[uuid.UUID]Order)}, &notifications{}
+			s := NewService(repo, quote.NewService(published), discardLogger{}, notified)
+			ctx := context.Background()
+			order, err := s.NewCart(ctx, Customer{}, OrderRequest{Lines: []quote.LineRequest{{ItemID: ids.bagel}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			order.Status = tt.status
+			repo.orders[order.ID] = *order
+
+			if _, err := s.Cancel(ctx, order.ID.String(), nil, CancelRequest{}); err != tt.wantErr {
+				t.Fatalf("Cancel() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(*notified) != len(tt.wantNotify) {
+				t.Errorf("notified %v, want %v", *notified, tt.wantNotify)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+package promotion
+
+import "errors"
+
+var ErrPromotionNotFound = errors.New("promotion not found")
@@ -0,0 +1,135 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+// Kind tells how a promotion takes money off.
+type Kind string
+
+const (
+	// PercentOff takes Percent off every unit of the items it targets.
+	PercentOff Kind = "percent_off"
+	// AmountOff takes Amount off every unit of the items it targets.
+	AmountOff Kind = "amount_off"
+	// BuyXGetY takes Percent off Get units for every Buy units bought along with them, the cheapest ones going at
+	// the discount. Percent is 100 for "get one free".
+	BuyXGetY Kind = "buy_x_get_y"
+	// Combo sells its targets, as many of each as they say, together for Amount.
+	Combo Kind = "combo"
+)
+
+// Promotion is a rule for taking money off quotes, and so orders, without touching menu prices. It is only applied
+// while it is active and within its schedule, which works as an availability window does: on the days given, between
+// the start and end times, and between the start and end dates.
+//
+// Promotions are applied by descending priority. Every unit of a line is discounted by at most one promotion that is
+// not stackable; stackable ones may be applied on top of each other, but never on top of one that is not.
+type Promotion struct {
+	domain.Base
+	Name        string  `json:"name" gorm:"not null"`
+	Description *string `json:"description,omitempty"`
+	Kind        Kind    `json:"kind" gorm:"not null"`
+	Active      bool    `json:"active"`
+	Priority    int     `json:"priority" gorm:"default:0"`
+	Stackable   bool    `json:"stackable" gorm:"default:false"`
+	// Percent is how much percent_off and buy_x_get_y promotions take off, from 1 to 100.
+	Percent uint `json:"percent,omitempty"`
+	// Amount is what an amount_off promotion takes off each unit, or what a combo costs, in cents.
+	Amount uint64 `json:"amount,omitempty"`
+	Buy    uint   `json:"buy,omitempty"`
+	Get    uint   `json:"get,omitempty"`
+	// Targets are the sections and items the promotion applies to, or the parts of a combo. A promotion other than a
+	// combo without any targets applies to everything.
+	Targets   []Target      `json:"targets"`
+	Days      menu.Weekdays `json:"days" gorm:"default:0"`
+	StartTime string        `json:"start_time"`
+	EndTime   string        `json:"end_time"`
+	TimeZone  string        `json:"time_zone" gorm:"default:UTC"`
+	StartDate *string       `json:"start_date,omitempty"`
+	EndDate   *string       `json:"end_date,omitempty"`
+}
+
+// Target picks a section, along with everything in its subsections, or a single item. Quantity is how many a combo
+// takes of it.
+type Target struct {
+	domain.Base
+	PromotionID uuid.UUID  `json:"-" gorm:"not null;index"`
+	SectionID   *uuid.UUID `json:"section_id,omitempty"`
+	ItemID      *uuid.UUID `json:"item_id,omitempty"`
+	Quantity    uint       `json:"quantity,omitempty"`
+}
+
+func (p *Promotion) Validate() error {
+	if p.Name == "" {
+		return errors.New("promotion needs a name")
+	}
+	switch p.Kind {
+	case PercentOff:
+		if p.Percent < 1 || p.Percent > 100 {
+			return fmt.Errorf("percent must be from 1 to 100, not %d", p.Percent)
+		}
+	case AmountOff:
+		if p.Amount == 0 {
+			return errors.New("amount_off promotion needs an amount")
+		}
+	case BuyXGetY:
+		if p.Buy == 0 || p.Get == 0 {
+			return errors.New("buy_x_get_y promotion needs both buy and get")
+		}
+		if p.Percent < 1 || p.Percent > 100 {
+			return fmt.Errorf("percent must be from 1 to 100, not %d", p.Percent)
+		}
+	case Combo:
+		if len(p.Targets) == 0 {
+			return errors.New("combo needs at least one target")
+		}
+		if p.Amount == 0 {
+			return errors.New("combo promotion needs an amount")
+		}
+	default:
+		return fmt.Errorf("unknown kind of promotion %q", p.Kind)
+	}
+	for _, target := range p.Targets {
+		if (target.SectionID == nil) == (target.ItemID == nil) {
+			return errors.New("target must pick either a section or an item")
+		}
+		if p.Kind == Combo && target.Quantity == 0 {
+			return errors.New("combo target needs a quantity")
+		}
+	}
+	schedule := p.schedule()
+	return schedule.ValidateSchedule()
+}
+
+// schedule is when the promotion runs, as an availability window.
+func (p *Promotion) schedule() menu.AvailabilityWindow {
+	return menu.AvailabilityWindow{Days: p.Days, StartTime: p.StartTime, EndTime: p.EndTime, TimeZone: p.TimeZone,
+		StartDate: p.StartDate, EndDate: p.EndDate}
+}
+
+// RunningAt reports whether the promotion is active and within its schedule at the given instant.
+func (p *Promotion) RunningAt(at time.Time) bool {
+	schedule := p.schedule()
+	return p.Active && schedule.OpenAt(at)
+}
+
+// matches reports whether the target picks the item, given the sections it is in.
+func (t *Target) matches(itemID uuid.UUID, sections []uuid.UUID) bool {
+	if t.ItemID != nil {
+		return *t.ItemID == itemID
+	}
+	for _, section := range sections {
+		if *t.SectionID == section {
+			return true
+		}
+	}
+	return false
+}
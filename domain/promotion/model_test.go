@@ -0,0 +1,40 @@
+package promotion
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidate(t *testing.T) {
+	item := uuid.New()
+	tests := []struct {
+		name      string
+		promotion Promotion
+		wantErr   bool
+	}{
+		{"percent off", Promotion{Name: "Happy hour", Kind: PercentOff, Percent: 50,
+			Targets: []Target{{ItemID: &item}}}, false},
+		{"percent over 100", Promotion{Name: "Happy hour", Kind: PercentOff, Percent: 101}, true},
+		{"amount off without an amount", Promotion{Name: "Lunch", Kind: AmountOff}, true},
+		{"buy x get y without get", Promotion{Name: "Two for one", Kind: BuyXGetY, Buy: 1, Percent: 100}, true},
+		{"combo", Promotion{Name: "Meal deal", Kind: Combo, Amount: 1200,
+			Targets: []Target{{ItemID: &item, Quantity: 1}}}, false},
+		{"combo without an amount", Promotion{Name: "Meal deal", Kind: Combo,
+			Targets: []Target{{ItemID: &item, Quantity: 1}}}, true},
+		{"combo without targets", Promotion{Name: "Meal deal", Kind: Combo, Amount: 1200}, true},
+		{"combo target without a quantity", Promotion{Name: "Meal deal", Kind: Combo, Amount: 1200,
+			Targets: []Target{{ItemID: &item}}}, true},
+		{"target picking nothing", Promotion{Name: "Lunch", Kind: AmountOff, Amount: 100,
+			Targets: []Target{{}}}, true},
+		{"unknown kind", Promotion{Name: "Lunch", Kind: "free"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.promotion.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package promotion
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+// unit is one of the quantity of a quote line, and what is left to pay for it.
+type unit struct {
+	line  int
+	price uint64
+	// discounted units have had money taken off them; closed ones were taken by a promotion that does not stack and
+	// are left alone by every promotion after it.
+	discounted, closed bool
+}
+
+// evaluation is a quote being worked through promotion by promotion.
+type evaluation struct {
+	quote *quote.Quote
+	units []*unit
+}
+
+// Apply works out the promotions running at the time of the quote and adds a discount to it for each that takes any
+// money off, saying what it was taken off. Promotions go by descending priority, and those of equal priority in the
+// order they are given.
+func Apply(promotions []Promotion, q *quote.Quote) {
+	running := make([]Promotion, 0, len(promotions))
+	for _, promotion := range promotions {
+		if promotion.RunningAt(q.QuotedAt) {
+			running = append(running, promotion)
+		}
+	}
+	sort.SliceStable(running, func(i, j int) bool { return running[i].Priority > running[j].Priority })
+
+	e := evaluation{quote: q}
+	for i, line := range q.Lines {
+		for n := uint(0); n < line.Quantity; n++ {
+			e.units = append(e.units, &unit{line: i, price: line.UnitPrice})
+		}
+	}
+	for i := range running {
+		if discount, ok := e.apply(&running[i]); ok {
+			q.Discounts = append(q.Discounts, discount)
+		}
+	}
+}
+
+// apply takes what the promotion takes off the units still open to it, most expensive first.
+func (e *evaluation) apply(p *Promotion) (quote.Discount, bool) {
+	var open []*unit
+	for _, u := range e.units {
+		if !u.closed && (p.Stackable || !u.discounted) {
+			open = append(open, u)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool { return open[i].price > open[j].price })
+
+	var (
+		amount uint64
+		taken  []*unit
+		reason string
+	)
+	switch p.Kind {
+	case PercentOff, AmountOff:
+		for _, u := range e.eligible(p.Targets, open) {
+			cut := u.price
+			if p.Kind == PercentOff {
+				cut = percentOf(u.price, p.Percent)
+			} else if p.Amount < cut {
+				cut = p.Amount
+			}
+			amount += e.take(p, u, cut)
+			taken = append(taken, u)
+		}
+		if p.Kind == PercentOff {
+			reason = fmt.Sprintf("%d%% off", p.Percent)
+		} else {
+			reason = fmt.Sprintf("%s off each", cents(p.Amount))
+		}
+	case BuyXGetY:
+		eligible := e.eligible(p.Targets, open)
+		size := int(p.Buy + p.Get)
+		for start := 0; start+size <= len(eligible); start += size {
+			for i, u := range eligible[start : start+size] {
+				var cut uint64
+				if i >= int(p.Buy) {
+					cut = percentOf(u.price, p.Percent)
+				}
+				amount += e.take(p, u, cut)
+				taken = append(taken, u)
+			}
+		}
+		if p.Percent == 100 {
+			reason = fmt.Sprintf("buy %d get %d free", p.Buy, p.Get)
+		} else {
+			reason = fmt.Sprintf("buy %d get %d at %d%% off", p.Buy, p.Get, p.Percent)
+		}
+	case Combo:
+		for {
+			parts, ok := e.combo(p.Targets, open)
+			if !ok {
+				break
+			}
+			var sum uint64
+			for _, u := range parts {
+				sum += u.price
+			}
+			if sum <= p.Amount {
+				break
+			}
+			// What the combo saves is spread over its parts by price, the cents rounded away going to the first.
+			saving := sum - p.Amount
+			cuts := make([]uint64, len(parts))
+			left := saving
+			for i, u := range parts {
+				cuts[i] = saving * u.price / sum
+				left -= cuts[i]
+			}
+			for i, u := range parts {
+				extra := u.price - cuts[i]
+				if left < extra {
+					extra = left
+				}
+				cuts[i] += extra
+				left -= extra
+				amount += e.take(p, u, cuts[i])
+			}
+			taken = append(taken, parts...)
+			open = remaining(open, parts)
+		}
+		reason = fmt.Sprintf("combo for %s", cents(p.Amount))
+	}
+	if amount == 0 {
+		return quote.Discount{}, false
+	}
+	return quote.Discount{Name: p.Name, Reason: reason + ": " + e.describe(taken), Amount: amount}, true
+}
+
+// take cuts the price of the unit and marks it as taken by the promotion, returning how much was taken off. The cut
+// is added to the discount of the unit's line, so that the line is taxed on what is left to pay for it.
+func (e *evaluation) take(p *Promotion, u *unit, cut uint64) uint64 {
+	if cut > u.price {
+		cut = u.price
+	}
+	u.price -= cut
+	e.quote.Lines[u.line].Discount += cut
+	u.discounted = true
+	if !p.Stackable {
+		u.closed = true
+	}
+	return cut
+}
+
+// eligible returns the units picked by one of the targets, in the order given. Without any targets every unit is.
+func (e *evaluation) eligible(targets []Target, units []*unit) []*unit {
+	if len(targets) == 0 {
+		return units
+	}
+	var picked []*unit
+	for _, u := range units {
+		line := e.quote.Lines[u.line]
+		for i := range targets {
+			if targets[i].matches(line.ItemID, line.Sections) {
+				picked = append(picked, u)
+				break
+			}
+		}
+	}
+	return picked
+}
+
+// combo picks the most expensive units for every part of a combo, or reports that it cannot be made up.
+func (e *evaluation) combo(targets []Target, units []*unit) ([]*unit, bool) {
+	var parts []*unit
+	for i := range targets {
+		candidates := e.eligible(targets[i:i+1], remaining(units, parts))
+		if uint(len(candidates)) < targets[i].Quantity {
+			return nil, false
+		}
+		parts = append(parts, candidates[:targets[i].Quantity]...)
+	}
+	return parts, true
+}
+
+// remaining returns the units less those given, in the same order.
+func remaining(units []*unit, less []*unit) []*unit {
+	left := make([]*unit, 0, len(units))
+	for _, u := range units {
+		taken := false
+		for _, l := range less {
+			if u == l {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			left = append(left, u)
+		}
+	}
+	return left
+}
+
+// describe lists the titles of the lines the units belong to, in the order of the quote, e.g. "Fries ×2, Soda".
+func (e *evaluation) describe(units []*unit) string {
+	counts := make(map[int]int)
+	for _, u := range units {
+		counts[u.line]++
+	}
+	titles := make([]string, 0, len(counts))
+	for i, line := range e.quote.Lines {
+		switch count := counts[i]; {
+		case count == 1:
+			titles = append(titles, line.Title)
+		case count > 1:
+			titles = append(titles, fmt.Sprintf("%s ×%d", line.Title, count))
+		}
+	}
+	return strings.Join(titles, ", ")
+}
+
+// percentOf returns the percentage of an amount, rounding half a cent up.
+func percentOf(amount uint64, percent uint) uint64 {
+	return (amount*uint64(percent) + 50) / 100
+}
+
+// cents writes an amount in cents as a decimal, e.g. 1250 as 12.50.
+func cents(amount uint64) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
@@ -0,0 +1,44 @@
+package promotion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+func TestApplyTakesDiscountsOffTheLinesTargeted(t *testing.T) {
+	beer, burger := uuid.New(), uuid.New()
+	q := quote.Quote{
+		Lines: []quote.Line{
+			{ItemID: beer, Title: "Beer", Quantity: 2, UnitPrice: 1000, Total: 2000, TaxCategory: "alcohol"},
+			{ItemID: burger, Title: "Burger", Quantity: 1, UnitPrice: 1500, Total: 1500, TaxCategory: "food"},
+		},
+		QuotedAt: time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	promotions := []Promotion{
+		{Name: "Happy hour", Kind: PercentOff, Active: true, Percent: 50, Targets: []Target{{ItemID: &beer}},
+			TimeZone: "UTC"},
+		{Name: "Lunch deal", Kind: AmountOff, Active: true, Amount: 200, Stackable: true,
+			Targets: []Target{{ItemID: &burger}}, TimeZone: "UTC"},
+	}
+	Apply(promotions, &q)
+
+	if len(q.Discounts) != 2 {
+		t.Fatalf("got %d discounts, want 2", len(q.Discounts))
+	}
+	if q.Lines[0].Discount != 1000 || q.Lines[1].Discount != 200 {
+		t.Errorf("took %d off the beer and %d off the burger, want 1000 and 200", q.Lines[0].Discount,
+			q.Lines[1].Discount)
+	}
+	var sum uint64
+	for _, discount := range q.Discounts {
+		sum += discount.Amount
+	}
+	if sum != q.Lines[0].Discount+q.Lines[1].Discount {
+		t.Errorf("discounts come to %d but the lines were discounted by %d", sum,
+			q.Lines[0].Discount+q.Lines[1].Discount)
+	}
+}
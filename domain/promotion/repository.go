@@ -0,0 +1,12 @@
+package promotion
+
+import "context"
+
+// Repository describes the expected behavior for the data persistence of promotions and their targets.
+type Repository interface {
+	ListPromotions(context.Context) (*[]Promotion, error)
+	FindPromotion(context.Context, *Promotion) error
+	CreatePromotion(context.Context, *Promotion) error
+	UpdatePromotion(context.Context, *Promotion) error
+	DeletePromotion(context.Context, *Promotion) error
+}
@@ -0,0 +1,173 @@
+package promotion
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+// Service describes the expected behavior for managing promotions and applying them to quotes.
+type Service interface {
+	Promotions(context.Context) (*[]Promotion, error)
+	PromotionByID(context.Context, string) (*Promotion, error)
+	CreatePromotion(context.Context, *Promotion) error
+	UpdatePromotion(context.Context, string, UpdatePromotionRequest) (*Promotion, error)
+	DeletePromotion(context.Context, string) error
+	// Adjust adds the discounts of the promotions running at the time of the quote to it.
+	Adjust(context.Context, *quote.Quote) error
+}
+
+// UpdatePromotionRequest holds the fields of a promotion being changed; nil fields are left as they are. Targets,
+// when given, replace the promotion's targets altogether.
+type UpdatePromotionRequest struct {
+	Name        *string        `json:"name,omitempty"`
+	Description *string        `json:"description,omitempty"`
+	Active      *bool          `json:"active,omitempty"`
+	Priority    *int           `json:"priority,omitempty"`
+	Stackable   *bool          `json:"stackable,omitempty"`
+	Percent     *uint          `json:"percent,omitempty"`
+	Amount      *uint64        `json:"amount,omitempty"`
+	Buy         *uint          `json:"buy,omitempty"`
+	Get         *uint          `json:"get,omitempty"`
+	Targets     *[]Target      `json:"targets,omitempty"`
+	Days        *menu.Weekdays `json:"days,omitempty"`
+	StartTime   *string        `json:"start_time,omitempty"`
+	EndTime     *string        `json:"end_time,omitempty"`
+	TimeZone    *string        `json:"time_zone,omitempty"`
+	StartDate   *string        `json:"start_date,omitempty"`
+	EndDate     *string        `json:"end_date,omitempty"`
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService returns a new instance of service.
+func NewService(repo Repository) *service {
+	return &service{repo}
+}
+
+func (s *service) Promotions(ctx context.Context) (*[]Promotion, error) {
+	return s.repo.ListPromotions(ctx)
+}
+
+func (s *service) PromotionByID(ctx context.Context, rawID string) (*Promotion, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &Promotion{}, err
+	}
+	promotion := Promotion{}
+	promotion.ID = id
+	if err := s.repo.FindPromotion(ctx, &promotion); err != nil {
+		return &Promotion{}, err
+	}
+	return &promotion, nil
+}
+
+// CreatePromotion adds a promotion, active unless it says otherwise. A buy_x_get_y promotion without a percent gives
+// its units away, and combo targets without a quantity are taken once.
+func (s *service) CreatePromotion(ctx context.Context, promotion *Promotion) error {
+	if promotion.Kind == BuyXGetY && promotion.Percent == 0 {
+		promotion.Percent = 100
+	}
+	promotion.Targets = newTargets(promotion.Kind, promotion.Targets)
+	if err := promotion.Validate(); err != nil {
+		return err
+	}
+	return s.repo.CreatePromotion(ctx, promotion)
+}
+
+func (s *service) UpdatePromotion(ctx context.Context, rawID string, req UpdatePromotionRequest) (*Promotion, error) {
+	promotion, err := s.PromotionByID(ctx, rawID)
+	if err != nil {
+		return promotion, err
+	}
+	if req.Name != nil {
+		promotion.Name = *req.Name
+	}
+	if req.Description != nil {
+		promotion.Description = req.Description
+	}
+	if req.Active != nil {
+		promotion.Active = *req.Active
+	}
+	if req.Priority != nil {
+		promotion.Priority = *req.Priority
+	}
+	if req.Stackable != nil {
+		promotion.Stackable = *req.Stackable
+	}
+	if req.Percent != nil {
+		promotion.Percent = *req.Percent
+	}
+	if req.Amount != nil {
+		promotion.Amount = *req.Amount
+	}
+	if req.Buy != nil {
+		promotion.Buy = *req.Buy
+	}
+	if req.Get != nil {
+		promotion.Get = *req.Get
+	}
+	if req.Targets != nil {
+		promotion.Targets = newTargets(promotion.Kind, *req.Targets)
+	}
+	if req.Days != nil {
+		promotion.Days = *req.Days
+	}
+	if req.StartTime != nil {
+		promotion.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		promotion.EndTime = *req.EndTime
+	}
+	if req.TimeZone != nil {
+		promotion.TimeZone = *req.TimeZone
+	}
+	if req.StartDate != nil {
+		promotion.StartDate = req.StartDate
+	}
+	if req.EndDate != nil {
+		promotion.EndDate = req.EndDate
+	}
+	if err := promotion.Validate(); err != nil {
+		return &Promotion{}, err
+	}
+	if err := s.repo.UpdatePromotion(ctx, promotion); err != nil {
+		return &Promotion{}, err
+	}
+	return promotion, nil
+}
+
+func (s *service) DeletePromotion(ctx context.Context, rawID string) error {
+	promotion, err := s.PromotionByID(ctx, rawID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeletePromotion(ctx, promotion)
+}
+
+func (s *service) Adjust(ctx context.Context, q *quote.Quote) error {
+	promotions, err := s.repo.ListPromotions(ctx)
+	if err != nil {
+		return err
+	}
+	Apply(*promotions, q)
+	return nil
+}
+
+// newTargets copies targets as given for a promotion, leaving out IDs of their own so that they are stored afresh.
+func newTargets(kind Kind, targets []Target) []Target {
+	copied := make([]Target, 0, len(targets))
+	for _, target := range targets {
+		target := Target{SectionID: target.SectionID, ItemID: target.ItemID, Quantity: target.Quantity}
+		if kind == Combo && target.Quantity == 0 {
+			target.Quantity = 1
+		}
+		copied = append(copied, target)
+	}
+	return copied
+}
@@ -0,0 +1,9 @@
+package quote
+
+import "errors"
+
+var (
+	ErrItemUnavailable  = errors.New("item is not on the menu right now")
+	ErrInvalidSelection = errors.New("selection does not belong to the item")
+	ErrNoLines          = errors.New("nothing to quote")
+)
@@ -0,0 +1,139 @@
+package quote
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SelectionKind tells what a guest picked to go with an item.
+type SelectionKind string
+
+const (
+	// AddOnSelection is an item from the item's add-ons section, charged at its own price.
+	AddOnSelection SelectionKind = "add_on"
+	// CondimentSelection is an item from the item's condiments section, charged at its own price.
+	CondimentSelection SelectionKind = "condiment"
+	// ModifierSelection is an option of one of the item's modifier groups, charged its price delta.
+	ModifierSelection SelectionKind = "modifier"
+)
+
+// MaxQuantity is the most of one line that can be ordered at once.
+const MaxQuantity = 99
+
+// LineRequest picks an item to price. VariantID defaults to the item's first variant and Quantity to one. Modifiers
+// maps a modifier group to the options picked from it; groups left out get their default options.
+type LineRequest struct {
+	ItemID     uuid.UUID                 `json:"item_id" binding:"required"`
+	VariantID  *uuid.UUID                `json:"variant_id,omitempty"`
+	Quantity   uint                      `json:"quantity"`
+	AddOns     []uuid.UUID               `json:"add_ons,omitempty"`
+	Condiments []uuid.UUID               `json:"condiments,omitempty"`
+	Modifiers  map[uuid.UUID][]uuid.UUID `json:"modifiers,omitempty"`
+	Notes      *string                   `json:"notes,omitempty"`
+}
+
+func (r *LineRequest) Validate() error {
+	if r.ItemID == uuid.Nil {
+		return fmt.Errorf("item_id is required")
+	}
+	if r.Quantity > MaxQuantity {
+		return fmt.Errorf("quantity %d exceeds the maximum of %d", r.Quantity, MaxQuantity)
+	}
+	return nil
+}
+
+// Request asks for the price of the lines given. Location picks whose taxes apply, and defaults to the restaurant's
+// own.
+type Request struct {
+	Lines    []LineRequest `json:"lines" binding:"required"`
+	Location string        `json:"location,omitempty"`
+}
+
+// Selection is an add-on, a condiment or a modifier option picked for a line, with its title and price as they are
+// on the menu. Price is signed since modifier options may take money off.
+type Selection struct {
+	Kind SelectionKind `json:"kind"`
+	// RefID is the add-on or condiment item, or the modifier option, that was picked.
+	RefID uuid.UUID `json:"ref_id"`
+	// GroupID is the modifier group of a modifier option.
+	GroupID *uuid.UUID `json:"group_id,omitempty"`
+	Group   string     `json:"group"`
+	Title   string     `json:"title"`
+	Price   int64      `json:"price"`
+}
+
+// Line is an item priced in a given quantity. UnitPrice covers the variant and everything picked with it, and Total
+// is UnitPrice times Quantity. Prices are in cents. TaxCategory is the item's own or that of the nearest section above
+// it; what is picked with the item is taxed along with it.
+type Line struct {
+	ItemID      uuid.UUID   `json:"item_id"`
+	VariantID   uuid.UUID   `json:"variant_id"`
+	Title       string      `json:"title"`
+	VariantName string      `json:"variant_name"`
+	Quantity    uint        `json:"quantity"`
+	Notes       *string     `json:"notes,omitempty"`
+	Selections  []Selection `json:"selections"`
+	UnitPrice   uint64      `json:"unit_price"`
+	Total       uint64      `json:"total"`
+	TaxCategory string      `json:"tax_category,omitempty"`
+	// Discount is what discounts aimed at this line, such as promotions on the item, take off its Total. It is part
+	// of the quote's Discount, which may also hold discounts on the quote as a whole.
+	Discount uint64 `json:"discount,omitempty"`
+	// Sections lists the sections the item is in, the nearest first, for adjusters that deal in whole sections.
+	Sections []uuid.UUID `json:"-"`
+}
+
+// Discount is money taken off a quote, e.g. by a promotion, saying why. Adjusters that take it off particular lines
+// also add their share to the Discount of each of those lines.
+type Discount struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"`
+	Amount uint64 `json:"amount"`
+}
+
+// Tax is tax charged on a quote once its discounts are taken off. Included tax is part of the prices already and is
+// only broken out.
+type Tax struct {
+	Name     string  `json:"name"`
+	Category string  `json:"category"`
+	Percent  float64 `json:"percent"`
+	Amount   uint64  `json:"amount"`
+	Included bool    `json:"included"`
+}
+
+// Quote is the itemised price of a set of lines at a given moment and location. Amounts are in cents; Tax is all the
+// tax charged, and Total is Subtotal less Discount plus the tax not already included in prices.
+type Quote struct {
+	Location  string     `json:"location,omitempty"`
+	Lines     []Line     `json:"lines"`
+	Subtotal  uint64     `json:"subtotal"`
+	Discounts []Discount `json:"discounts"`
+	Discount  uint64     `json:"discount"`
+	Taxes     []Tax      `json:"taxes"`
+	Tax       uint64     `json:"tax"`
+	Total     uint64     `json:"total"`
+	QuotedAt  time.Time  `json:"quoted_at"`
+}
+
+// total adds up the lines, discounts and taxes. Discounts never take the quote below nothing.
+func (q *Quote) total() {
+	q.Subtotal, q.Discount, q.Tax = 0, 0, 0
+	for _, line := range q.Lines {
+		q.Subtotal += line.Total
+	}
+	for _, discount := range q.Discounts {
+		q.Discount += discount.Amount
+	}
+	if q.Discount > q.Subtotal {
+		q.Discount = q.Subtotal
+	}
+	q.Total = q.Subtotal - q.Discount
+	for _, tax := range q.Taxes {
+		q.Tax += tax.Amount
+		if !tax.Included {
+			q.Total += tax.Amount
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package quote
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+// Menu is where quotes look up what can be ordered and at what price; the menu service provides it.
+type Menu interface {
+	PublishedMenus(ctx context.Context, filter menu.MenuFilter) (*[]menu.Section, error)
+}
+
+// Adjuster takes money off or puts it on a quote once its lines are priced, e.g. promotions and taxes. Adjusters run
+// in the order they are given to NewService, each seeing the discounts and taxes of those before it.
+type Adjuster interface {
+	Adjust(ctx context.Context, quote *Quote) error
+}
+
+// Service describes the expected behavior for pricing selections from the menu. It is the one place prices are worked
+// out, for clients showing a total and for orders alike.
+type Service interface {
+	// Quote prices the lines against the published menu as it is right now.
+	Quote(ctx context.Context, request Request) (*Quote, error)
+	// Requote prices lines that were priced before again, exactly as they were picked: modifier groups a line leaves
+	// out get no options rather than their default ones.
+	Requote(ctx context.Context, request Request) (*Quote, error)
+}
+
+type service struct {
+	menu      Menu
+	adjusters []Adjuster
+}
+
+// NewService returns a Service that prices lines against the published menu and then hands them to the adjusters.
+func NewService(menu Menu, adjusters ...Adjuster) *service {
+	return &service{menu, adjusters}
+}
+
+func (s *service) Quote(ctx context.Context, req Request) (*Quote, error) {
+	return s.quote(ctx, req, true)
+}
+
+func (s *service) Requote(ctx context.Context, req Request) (*Quote, error) {
+	return s.quote(ctx, req, false)
+}
+
+func (s *service) quote(ctx context.Context, req Request, withDefaults bool) (*Quote, error) {
+	if len(req.Lines) == 0 {
+		return &Quote{}, ErrNoLines
+	}
+	quote := Quote{Location: req.Location, Lines: make([]Line, 0, len(req.Lines)), Discounts: []Discount{},
+		Taxes: []Tax{}, QuotedAt: time.Now().UTC()}
+	catalog, err := s.catalog(ctx, quote.QuotedAt)
+	if err != nil {
+		return &Quote{}, err
+	}
+	for _, lineReq := range req.Lines {
+		line, err := catalog.price(lineReq, withDefaults)
+		if err != nil {
+			return &Quote{}, err
+		}
+		quote.Lines = append(quote.Lines, line)
+	}
+	quote.total()
+	for _, adjuster := range s.adjusters {
+		if err := adjuster.Adjust(ctx, &quote); err != nil {
+			return &Quote{}, err
+		}
+		quote.total()
+	}
+	return &quote, nil
+}
+
+// catalog is the published menu as it can be ordered from at a given instant: active items within their
+// availability windows, in sections that are too, along with the tax category and the sections each falls under.
+type catalog struct {
+	items         map[uuid.UUID]menu.Item
+	taxCategories map[uuid.UUID]string
+	sections      map[uuid.UUID][]uuid.UUID
+	at            time.Time
+}
+
+// catalog reads the whole published menu rather than the part available at the instant, so that add-ons and
+// condiments which cannot be had right now are told apart from ones that do not belong to the item.
+func (s *service) catalog(ctx context.Context, at time.Time) (catalog, error) {
+	menus, err := s.menu.PublishedMenus(ctx, menu.MenuFilter{})
+	if err != nil {
+		return catalog{}, err
+	}
+	c := catalog{items: make(map[uuid.UUID]menu.Item), taxCategories: make(map[uuid.UUID]string),
+		sections: make(map[uuid.UUID][]uuid.UUID), at: at}
+	var walk func(sections []menu.Section, taxCategory string, above []uuid.UUID)
+	walk = func(sections []menu.Section, taxCategory string, above []uuid.UUID) {
+		for _, section := range sections {
+			if !section.AvailableAt(at) {
+				continue
+			}
+			sectionCategory := section.TaxCategoryOr(taxCategory)
+			within := append([]uuid.UUID{section.ID}, above...)
+			walk(section.SubSections, sectionCategory, within)
+			for _, item := range section.Items {
+				if item.AvailableAt(at) {
+					c.items[item.ID] = item
+					c.taxCategories[item.ID] = item.TaxCategoryOr(sectionCategory)
+					c.sections[item.ID] = within
+				}
+			}
+		}
+	}
+	walk(*menus, "", nil)
+	return c, nil
+}
+
+// price builds a line from a request, copying titles and prices from the menu. Modifier groups the request leaves
+// out get their default options when withDefaults is set, and no options otherwise.
+func (c catalog) price(req LineRequest, withDefaults bool) (Line, error) {
+	if err := req.Validate(); err != nil {
+		return Line{}, err
+	}
+	item, ok := c.items[req.ItemID]
+	if !ok {
+		return Line{}, fmt.Errorf("%w: %v", ErrItemUnavailable, req.ItemID)
+	}
+
+	variants := item.PriceVariants()
+	variant := variants[0]
+	if req.VariantID != nil {
+		if variant, ok = item.VariantByID(*req.VariantID); !ok {
+			return Line{}, fmt.Errorf("%w: %q has no variant %v", ErrInvalidSelection, item.Title, *req.VariantID)
+		}
+	}
+	if !variant.Active {
+		return Line{}, fmt.Errorf("%w: %s %s", ErrItemUnavailable, item.Title, variant.Name)
+	}
+
+	line := Line{ItemID: item.ID, VariantID: variant.ID, Title: item.Title, VariantName: variant.Name,
+		Quantity: req.Quantity, Notes: req.Notes, Selections: []Selection{},
+		TaxCategory: c.taxCategories[item.ID], Sections: c.sections[item.ID]}
+	if line.Quantity == 0 {
+		line.Quantity = 1
+	}
+	unitPrice := int64(variant.Price)
+
+	for _, picked := range []struct {
+		kind    SelectionKind
+		noun    string
+		section menu.Section
+		ids     []uuid.UUID
+	}{
+		{AddOnSelection, "add-ons", item.AddOns, req.AddOns},
+		{CondimentSelection, "condiments", item.Condiments, req.Condiments},
+	} {
+		seen := make(map[uuid.UUID]bool, len(picked.ids))
+		for _, id := range picked.ids {
+			extra, ok := findItem(picked.section.Items, id)
+			if !ok {
+				return Line{}, fmt.Errorf("%w: %v is not one of the %s of %q", ErrInvalidSelection, id,
+					picked.noun, item.Title)
+			}
+			if !picked.section.Active || !extra.AvailableAt(c.at) {
+				return Line{}, fmt.Errorf("%w: %s for %q", ErrItemUnavailable, extra.Title, item.Title)
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			line.Selections = append(line.Selections, Selection{Kind: picked.kind, RefID: extra.ID,
+				Group: picked.section.Title, Title: extra.Title, Price: int64(extra.Price)})
+			unitPrice += int64(extra.Price)
+		}
+	}
+
+	known := make(map[uuid.UUID]bool, len(item.ModifierGroups))
+	for _, group := range item.ModifierGroups {
+		known[group.ID] = true
+		optionIDs, picked := req.Modifiers[group.ID]
+		if !picked && withDefaults {
+			for _, option := range group.Defaults() {
+				optionIDs = append(optionIDs, option.ID)
+			}
+		}
+		options, delta, err := group.ValidateSelection(optionIDs)
+		if err != nil {
+			return Line{}, err
+		}
+		groupID := group.ID
+		for _, option := range options {
+			line.Selections = append(line.Selections, Selection{Kind: ModifierSelection, RefID: option.ID,
+				GroupID: &groupID, Group: group.Title, Title: option.Title, Price: option.PriceDelta})
+		}
+		unitPrice += delta
+	}
+	for groupID := range req.Modifiers {
+		if !known[groupID] {
+			return Line{}, fmt.Errorf("%w: %q has no modifier group %v", ErrInvalidSelection, item.Title, groupID)
+		}
+	}
+
+	if unitPrice < 0 {
+		return Line{}, fmt.Errorf("%q would cost less than nothing", item.Title)
+	}
+	line.UnitPrice = uint64(unitPrice)
+	line.Total = line.UnitPrice * uint64(line.Quantity)
+	return line, nil
+}
+
+func findItem(items []menu.Item, id uuid.UUID) (menu.Item, bool) {
+	for _, item := range items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return menu.Item{}, false
+}
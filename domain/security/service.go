@@ -3,8 +3,13 @@ package security
 type Service interface {
 	ConfirmationChecker(password string, confirmPassword string) error
 	VerifyPasswordMatches(hashedPW string, password string) error
-	Hash(password string) string
+	Hash(password string) (string, error)
 	IsValid(password string) error
+	// IsValidPIN validates a PIN against the PIN policy.
+	IsValidPIN(pin string) error
+	// NeedsRehash reports whether a hash was made by another algorithm, or with other parameters, than the
+	// configured ones, so that it can be replaced the next time the password is given.
+	NeedsRehash(hashedPW string) bool
 }
 
 type security struct {
@@ -15,4 +20,3 @@ type security struct {
 func NewService(secSvc Service) *security {
 	return &security{secSvc}
 }
-
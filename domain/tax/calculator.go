@@ -0,0 +1,130 @@
+package tax
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calculator describes the expected behavior for working out tax. It knows nothing of menus or orders, so that any
+// quote or order path can use it.
+type Calculator interface {
+	// Calculate works out the tax due on the amounts at a location at the given instant. An empty location is the
+	// default one; without any locations there is no tax.
+	Calculate(location string, at time.Time, amounts []Amount) ([]Charge, error)
+	// TaxCategories lists the categories sections and items may be given.
+	TaxCategories() []string
+}
+
+type calculator struct {
+	policy Policy
+}
+
+// NewCalculator returns a Calculator for the policy, once it is found to be sound.
+func NewCalculator(policy Policy) (*calculator, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &calculator{policy}, nil
+}
+
+func (c *calculator) TaxCategories() []string {
+	return append([]string{}, c.policy.Categories...)
+}
+
+func (c *calculator) Calculate(name string, at time.Time, amounts []Amount) ([]Charge, error) {
+	name, err := c.locationName(name)
+	if err != nil || name == "" {
+		return []Charge{}, err
+	}
+	location := c.policy.Locations[name]
+
+	var rates []Rate
+	// Inclusive tax is taken out of prices that hold every rate of their category at once.
+	divisors := make(map[string]uint64)
+	for _, rate := range location.Rates {
+		if !rate.EffectiveAt(at) {
+			continue
+		}
+		rates = append(rates, rate)
+		if divisors[rate.Category] == 0 {
+			divisors[rate.Category] = partsPerMillion
+		}
+		if location.Mode == Inclusive {
+			divisors[rate.Category] += rate.PPM
+		}
+	}
+
+	bases := make(map[string]uint64)
+	perLine := make([]Amount, 0, len(amounts))
+	for _, amount := range amounts {
+		category, err := c.category(amount.Category)
+		if err != nil {
+			return []Charge{}, err
+		}
+		bases[category] += amount.Amount
+		perLine = append(perLine, Amount{category, amount.Amount})
+	}
+
+	charges := make([]Charge, 0, len(rates))
+	for _, rate := range rates {
+		base, ok := bases[rate.Category]
+		if !ok {
+			continue
+		}
+		charge := Charge{Name: rate.Name, Category: rate.Category, Percent: rate.Percent(),
+			Included: location.Mode == Inclusive}
+		if c.policy.RoundPerLine {
+			for _, amount := range perLine {
+				if amount.Category == rate.Category {
+					charge.Amount += c.divide(amount.Amount*rate.PPM, divisors[rate.Category])
+				}
+			}
+		} else {
+			charge.Amount = c.divide(base*rate.PPM, divisors[rate.Category])
+		}
+		charges = append(charges, charge)
+	}
+	return charges, nil
+}
+
+// locationName resolves the location a calculation is done for, which is empty when there are no locations at all.
+func (c *calculator) locationName(name string) (string, error) {
+	if name == "" {
+		name = c.policy.DefaultLocation
+	}
+	if name == "" {
+		for only := range c.policy.Locations {
+			return only, nil
+		}
+		return "", nil
+	}
+	if _, ok := c.policy.Locations[name]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownLocation, name)
+	}
+	return name, nil
+}
+
+// category resolves the category an amount is taxed under.
+func (c *calculator) category(category string) (string, error) {
+	if category == "" {
+		return c.policy.DefaultCategory, nil
+	}
+	for _, known := range c.policy.Categories {
+		if known == category {
+			return category, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownCategory, category)
+}
+
+// divide divides and rounds to the nearest whole number, breaking ties as the policy says.
+func (c *calculator) divide(dividend, divisor uint64) uint64 {
+	quotient, remainder := dividend/divisor, dividend%divisor
+	switch {
+	case 2*remainder > divisor:
+		quotient++
+	case 2*remainder == divisor && (c.policy.Rounding == HalfUp || quotient%2 == 1):
+		quotient++
+	}
+	return quotient
+}
@@ -0,0 +1,164 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDivideBreaksTiesAsThePolicySays(t *testing.T) {
+	tests := []struct {
+		dividend, divisor uint64
+		halfEven, halfUp  uint64
+	}{
+		{0, 10, 0, 0},
+		{24, 10, 2, 2},
+		{25, 10, 2, 3},
+		{26, 10, 3, 3},
+		{35, 10, 4, 4},
+		{45, 10, 4, 5},
+		{1500000, 1000000, 2, 2},
+		{2500000, 1000000, 2, 3},
+		{2500001, 1000000, 3, 3},
+	}
+	even := calculator{Policy{Rounding: HalfEven}}
+	up := calculator{Policy{Rounding: HalfUp}}
+	for _, tt := range tests {
+		if got := even.divide(tt.dividend, tt.divisor); got != tt.halfEven {
+			t.Errorf("half even %d/%d = %d, want %d", tt.dividend, tt.divisor, got, tt.halfEven)
+		}
+		if got := up.divide(tt.dividend, tt.divisor); got != tt.halfUp {
+			t.Errorf("half up %d/%d = %d, want %d", tt.dividend, tt.divisor, got, tt.halfUp)
+		}
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	newYear := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	summer := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	rates := []Rate{
+		{Name: "State tax", Category: "food", PPM: 40000},
+		{Name: "City tax", Category: "food", PPM: 60000},
+		{Name: "Old liquor tax", Category: "alcohol", PPM: 100000, Until: &newYear},
+		{Name: "Liquor tax", Category: "alcohol", PPM: 125000, From: &newYear},
+	}
+
+	tests := []struct {
+		name         string
+		mode         Mode
+		rounding     Rounding
+		roundPerLine bool
+		at           time.Time
+		amounts      []Amount
+		want         map[string]uint64
+	}{
+		{name: "exclusive", mode: Exclusive, rounding: HalfEven, at: summer,
+			amounts: []Amount{{"food", 1000}, {"alcohol", 800}},
+			want:    map[string]uint64{"State tax": 40, "City tax": 60, "Liquor tax": 100}},
+		{name: "uncategorised amounts fall under the default category", mode: Exclusive, rounding: HalfEven,
+			at: summer, amounts: []Amount{{"", 1000}}, want: map[string]uint64{"State tax": 40, "City tax": 60}},
+		{name: "inclusive takes every rate of the category out at once", mode: Inclusive, rounding: HalfEven,
+			at: summer, amounts: []Amount{{"food", 1100}}, want: map[string]uint64{"State tax": 40, "City tax": 60}},
+		{name: "inclusive leaves rates not in effect out of the divisor", mode: Inclusive, rounding: HalfEven,
+			at: summer, amounts: []Amount{{"alcohol", 1125}}, want: map[string]uint64{"Liquor tax": 125}},
+		{name: "half even ties go to the even cent", mode: Exclusive, rounding: HalfEven, at: summer,
+			amounts: []Amount{{"food", 1025}}, want: map[string]uint64{"State tax": 41, "City tax": 62}},
+		{name: "half up ties go up", mode: Exclusive, rounding: HalfUp, at: summer,
+			amounts: []Amount{{"food", 1025}}, want: map[string]uint64{"State tax": 41, "City tax": 62}},
+		{name: "half even rounds a half cent down to even", mode: Exclusive, rounding: HalfEven, at: summer,
+			amounts: []Amount{{"food", 1075}}, want: map[string]uint64{"State tax": 43, "City tax": 64}},
+		{name: "half up rounds a half cent up", mode: Exclusive, rounding: HalfUp, at: summer,
+			amounts: []Amount{{"food", 1075}}, want: map[string]uint64{"State tax": 43, "City tax": 65}},
+		{name: "rounded once per rate", mode: Exclusive, rounding: HalfUp, at: summer,
+			amounts: []Amount{{"food", 1075}, {"food", 1075}}, want: map[string]uint64{"State tax": 86,
+				"City tax": 129}},
+		{name: "rounded once per line", mode: Exclusive, rounding: HalfUp, roundPerLine: true, at: summer,
+			amounts: []Amount{{"food", 1075}, {"food", 1075}}, want: map[string]uint64{"State tax": 86,
+				"City tax": 130}},
+		{name: "a rate ending applies until just before its end", mode: Exclusive, rounding: HalfEven,
+			at: newYear.Add(-time.Nanosecond), amounts: []Amount{{"alcohol", 800}},
+			want: map[string]uint64{"Old liquor tax": 80}},
+		{name: "a rate taking effect applies from its start", mode: Exclusive, rounding: HalfEven, at: newYear,
+			amounts: []Amount{{"alcohol", 800}}, want: map[string]uint64{"Liquor tax": 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calculator, err := NewCalculator(Policy{
+				Categories:      []string{"food", "alcohol"},
+				DefaultCategory: "food",
+				Rounding:        tt.rounding,
+				RoundPerLine:    tt.roundPerLine,
+				Locations:       map[string]Location{"downtown": {Mode: tt.mode, Rates: rates}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			charges, err := calculator.Calculate("", tt.at, tt.amounts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]uint64)
+			for _, charge := range charges {
+				if charge.Included != (tt.mode == Inclusive) {
+					t.Errorf("%s included is %t in %s mode", charge.Name, charge.Included, tt.mode)
+				}
+				got[charge.Name] = charge.Amount
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateRejectsUnknownLocationsAndCategories(t *testing.T) {
+	calculator, err := NewCalculator(Policy{
+		Categories: []string{"food"},
+		Rounding:   HalfEven,
+		Locations:  map[string]Location{"downtown": {Mode: Exclusive}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := calculator.Calculate("airport", time.Now(), nil); err == nil {
+		t.Error("unknown location was accepted")
+	}
+	if _, err := calculator.Calculate("", time.Now(), []Amount{{"alcohol", 100}}); err == nil {
+		t.Error("unknown category was accepted")
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  uint64
+		amounts []uint64
+		want    []uint64
+	}{
+		{"nothing to spread", 0, []uint64{500, 500}, []uint64{0, 0}},
+		{"nothing to spread over", 100, []uint64{0, 0}, []uint64{0, 0}},
+		{"even split", 100, []uint64{300, 300, 400}, []uint64{30, 30, 40}},
+		{"leftover cent goes to the first of equal remainders", 100, []uint64{1, 1, 1}, []uint64{34, 33, 33}},
+		{"leftover cents go to the largest remainders", 7, []uint64{2, 2, 2}, []uint64{3, 2, 2}},
+		{"leftover cent goes to the amount that lost most", 5, []uint64{1000, 1}, []uint64{5, 0}},
+		{"leftover cents go to the largest remainders wherever they are", 3, []uint64{1, 2, 4}, []uint64{0, 1, 2}},
+		{"whole amounts", 1000, []uint64{250, 750}, []uint64{250, 750}},
+		{"large amounts do not overflow", 1 << 40, []uint64{1 << 40, 1 << 40}, []uint64{1 << 39, 1 << 39}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocate(tt.amount, tt.amounts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allocate(%d, %v) = %v, want %v", tt.amount, tt.amounts, got, tt.want)
+			}
+			var total, sum uint64
+			for i, share := range got {
+				total += tt.amounts[i]
+				sum += share
+			}
+			if total > 0 && sum != tt.amount {
+				t.Errorf("shares add up to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package tax
+
+import "errors"
+
+var (
+	ErrUnknownCategory = errors.New("unknown tax category")
+	ErrUnknownLocation = errors.New("unknown tax location")
+)
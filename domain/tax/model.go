@@ -0,0 +1,130 @@
+package tax
+
+import (
+	"fmt"
+	"time"
+)
+
+// Mode tells whether prices include tax.
+type Mode string
+
+const (
+	// Exclusive tax is charged on top of prices.
+	Exclusive Mode = "exclusive"
+	// Inclusive tax is part of prices already, and only broken out.
+	Inclusive Mode = "inclusive"
+)
+
+// Rounding tells how tax is rounded to the cent.
+type Rounding string
+
+const (
+	// HalfEven rounds half a cent to the nearest even cent, banker's rounding, so that rounding evens out over many
+	// sales.
+	HalfEven Rounding = "half_even"
+	// HalfUp rounds half a cent up.
+	HalfUp Rounding = "half_up"
+)
+
+// partsPerMillion is the scale rates are kept in.
+const partsPerMillion = 1000000
+
+// Rate is a tax charged on one category of goods at a location, from From until just before Until when they are
+// set.
+type Rate struct {
+	Name     string
+	Category string
+	// PPM is the rate in parts per million, e.g. 88750 for 8.875%.
+	PPM   uint64
+	From  *time.Time
+	Until *time.Time
+}
+
+// EffectiveAt reports whether the rate applies at the given instant.
+func (r Rate) EffectiveAt(at time.Time) bool {
+	if r.From != nil && at.Before(*r.From) {
+		return false
+	}
+	return r.Until == nil || at.Before(*r.Until)
+}
+
+// Percent returns the rate as a percentage.
+func (r Rate) Percent() float64 {
+	return float64(r.PPM) / (partsPerMillion / 100)
+}
+
+// Location is a jurisdiction with its own rates, and its own say on whether prices include tax.
+type Location struct {
+	Mode  Mode
+	Rates []Rate
+}
+
+// Policy is how tax is worked out. Amounts without a category fall under DefaultCategory, and calculations without a
+// location are done for DefaultLocation. Tax is rounded once per rate, or once per line and rate with RoundPerLine.
+type Policy struct {
+	Categories      []string
+	DefaultCategory string
+	DefaultLocation string
+	Rounding        Rounding
+	RoundPerLine    bool
+	Locations       map[string]Location
+}
+
+func (p *Policy) Validate() error {
+	known := make(map[string]bool, len(p.Categories))
+	for _, category := range p.Categories {
+		if category == "" {
+			return fmt.Errorf("tax categories cannot be empty")
+		}
+		known[category] = true
+	}
+	if p.DefaultCategory != "" && !known[p.DefaultCategory] {
+		return fmt.Errorf("%w: %q", ErrUnknownCategory, p.DefaultCategory)
+	}
+	if p.DefaultLocation != "" {
+		if _, ok := p.Locations[p.DefaultLocation]; !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownLocation, p.DefaultLocation)
+		}
+	} else if len(p.Locations) > 1 {
+		return fmt.Errorf("a default location is needed when there is more than one")
+	}
+	switch p.Rounding {
+	case HalfEven, HalfUp:
+	default:
+		return fmt.Errorf("unknown tax rounding %q", p.Rounding)
+	}
+	for name, location := range p.Locations {
+		switch location.Mode {
+		case Exclusive, Inclusive:
+		default:
+			return fmt.Errorf("location %q: unknown tax mode %q", name, location.Mode)
+		}
+		for _, rate := range location.Rates {
+			if rate.Name == "" {
+				return fmt.Errorf("location %q: every rate needs a name", name)
+			}
+			if !known[rate.Category] {
+				return fmt.Errorf("location %q: rate %q: %w: %q", name, rate.Name, ErrUnknownCategory, rate.Category)
+			}
+			if rate.From != nil && rate.Until != nil && !rate.From.Before(*rate.Until) {
+				return fmt.Errorf("location %q: rate %q ends before it takes effect", name, rate.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Amount is money falling under a tax category, e.g. a line of an order once its discounts are taken off.
+type Amount struct {
+	Category string
+	Amount   uint64
+}
+
+// Charge is what one rate comes to. Included charges are already part of the amounts taxed.
+type Charge struct {
+	Name     string
+	Category string
+	Percent  float64
+	Amount   uint64
+	Included bool
+}
@@ -0,0 +1,79 @@
+package tax
+
+import (
+	"context"
+	"math/bits"
+	"sort"
+
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+// Adjust adds the tax on a quote to it, at the quote's location or the default one. Each line is taxed on what is
+// actually paid for it: its total less its own discounts, and less its share of the discounts on the whole quote,
+// which are spread over the lines in proportion to what is left of them.
+func (c *calculator) Adjust(_ context.Context, q *quote.Quote) error {
+	location, err := c.locationName(q.Location)
+	if err != nil {
+		return err
+	}
+	q.Location = location
+
+	totals := make([]uint64, len(q.Lines))
+	var lineDiscounts uint64
+	for i, line := range q.Lines {
+		discount := line.Discount
+		if discount > line.Total {
+			discount = line.Total
+		}
+		totals[i] = line.Total - discount
+		lineDiscounts += discount
+	}
+	var quoteDiscount uint64
+	if q.Discount > lineDiscounts {
+		quoteDiscount = q.Discount - lineDiscounts
+	}
+	discounts := allocate(quoteDiscount, totals)
+	amounts := make([]Amount, len(q.Lines))
+	for i, line := range q.Lines {
+		amounts[i] = Amount{Category: line.TaxCategory, Amount: totals[i] - discounts[i]}
+	}
+
+	charges, err := c.Calculate(location, q.QuotedAt, amounts)
+	if err != nil {
+		return err
+	}
+	for _, charge := range charges {
+		q.Taxes = append(q.Taxes, quote.Tax{Name: charge.Name, Category: charge.Category, Percent: charge.Percent,
+			Amount: charge.Amount, Included: charge.Included})
+	}
+	return nil
+}
+
+// allocate spreads an amount, no greater than their sum, over amounts in proportion to them. The cents left over by
+// rounding down go to the amounts that lost the most to it.
+func allocate(amount uint64, amounts []uint64) []uint64 {
+	shares := make([]uint64, len(amounts))
+	var sum uint64
+	for _, a := range amounts {
+		sum += a
+	}
+	if amount == 0 || sum == 0 {
+		return shares
+	}
+	remainders := make([]uint64, len(amounts))
+	left := amount
+	for i, a := range amounts {
+		hi, lo := bits.Mul64(amount, a)
+		shares[i], remainders[i] = bits.Div64(hi, lo, sum)
+		left -= shares[i]
+	}
+	order := make([]int, len(amounts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return remainders[order[i]] > remainders[order[j]] })
+	for _, i := range order[:left] {
+		shares[i]++
+	}
+	return shares
+}
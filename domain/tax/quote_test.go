@@ -0,0 +1,66 @@
+package tax
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+func TestAdjustTaxesLinesOnWhatIsPaid(t *testing.T) {
+	calculator, err := NewCalculator(Policy{
+		Categories:      []string{"food", "alcohol"},
+		DefaultCategory: "food",
+		Rounding:        HalfEven,
+		Locations: map[string]Location{"downtown": {Mode: Exclusive, Rates: []Rate{
+			{Name: "Food tax", Category: "food", PPM: 50000},
+			{Name: "Liquor tax", Category: "alcohol", PPM: 100000},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// lineDiscounts are the discounts aimed at the beer and the burger; quoteDiscount is on top of them, on the
+		// quote as a whole.
+		lineDiscounts [2]uint64
+		quoteDiscount uint64
+		food, alcohol uint64
+	}{
+		{name: "no discounts", food: 50, alcohol: 100},
+		{name: "half off the beer", lineDiscounts: [2]uint64{500, 0}, food: 50, alcohol: 50},
+		{name: "half off the burger", lineDiscounts: [2]uint64{0, 500}, food: 25, alcohol: 100},
+		{name: "discount on the whole quote", quoteDiscount: 1000, food: 25, alcohol: 50},
+		{name: "whole quote discount spread over what is left", lineDiscounts: [2]uint64{500, 0},
+			quoteDiscount: 750, food: 25, alcohol: 25},
+		{name: "everything free", lineDiscounts: [2]uint64{1000, 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := quote.Quote{
+				Lines: []quote.Line{
+					{Title: "Beer", Quantity: 1, UnitPrice: 1000, Total: 1000, TaxCategory: "alcohol",
+						Discount: tt.lineDiscounts[0]},
+					{Title: "Burger", Quantity: 1, UnitPrice: 1000, Total: 1000, TaxCategory: "food",
+						Discount: tt.lineDiscounts[1]},
+				},
+				Discount: tt.lineDiscounts[0] + tt.lineDiscounts[1] + tt.quoteDiscount,
+				QuotedAt: time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+			}
+			if err := calculator.Adjust(context.Background(), &q); err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]uint64)
+			for _, tax := range q.Taxes {
+				got[tax.Category] += tax.Amount
+			}
+			if got["food"] != tt.food || got["alcohol"] != tt.alcohol {
+				t.Errorf("taxed food %d and alcohol %d, want %d and %d", got["food"], got["alcohol"], tt.food,
+					tt.alcohol)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package twofactor
+
+import "errors"
+
+var (
+	ErrNotEnrolled      = errors.New("two-factor authentication is not enabled for this account")
+	ErrAlreadyEnrolled  = errors.New("two-factor authentication is already enabled for this account")
+	ErrInvalidCode      = errors.New("invalid two-factor code")
+	ErrInvalidChallenge = errors.New("login challenge is invalid, expired or already used")
+	ErrRequired         = errors.New("two-factor authentication is required for this role")
+)
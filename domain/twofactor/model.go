@@ -0,0 +1,72 @@
+package twofactor
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+// Enrollment holds an account's TOTP secret. It only protects logins once it has been confirmed with a code, which
+// shows that the secret made it into the account holder's authenticator app.
+type Enrollment struct {
+	domain.Base
+	AccountID   uuid.UUID  `json:"account_id" gorm:"not null;uniqueIndex"`
+	Secret      string     `json:"-" gorm:"not null"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	// LastUsedStep is the time step of the last code accepted, so that a code cannot be used twice.
+	LastUsedStep int64 `json:"-" gorm:"not null;default:0"`
+}
+
+// Confirmed reports whether the enrollment protects logins.
+func (e *Enrollment) Confirmed() bool {
+	return e.ConfirmedAt != nil
+}
+
+// RecoveryCode stands in for a TOTP code once, for when the authenticator app is lost. Only its hash is stored.
+type RecoveryCode struct {
+	domain.Base
+	AccountID uuid.UUID  `json:"account_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// Challenge is the first step of a two-step login: the password checked out, and the challenge token is handed out
+// to be exchanged for access tokens along with a code. Only a hash of the token is stored.
+type Challenge struct {
+	domain.Base
+	AccountID      uuid.UUID  `json:"account_id" gorm:"not null;index"`
+	Username       string     `json:"username" gorm:"not null"`
+	TokenHash      string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt         *time.Time `json:"used_at,omitempty"`
+	FailedAttempts int        `json:"failed_attempts" gorm:"not null;default:0"`
+}
+
+// Usable reports whether the challenge may still be answered.
+func (c *Challenge) Usable(now time.Time) bool {
+	return c.UsedAt == nil && now.Before(c.ExpiresAt)
+}
+
+// Secret is handed to an account holder enrolling, to be added to an authenticator app by scanning the URI as a QR
+// code or typing in the secret.
+type Secret struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Status tells whether an account uses two-factor authentication.
+type Status struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// ChallengeResponse answers a login that needs a second step. When EnrollmentRequired is set the account has no
+// authenticator yet and must enroll with the challenge token before answering it.
+type ChallengeResponse struct {
+	ChallengeToken     string `json:"challenge_token"`
+	Expiry             int64  `json:"challenge_expiry"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+}
@@ -0,0 +1,35 @@
+package twofactor
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository describes the expected behavior for the data persistence of TOTP enrollments, recovery codes and login
+// challenges.
+type Repository interface {
+	// FindEnrollment returns ErrNotEnrolled when the account has no enrollment.
+	FindEnrollment(ctx context.Context, accountID uuid.UUID) (Enrollment, error)
+	// ReplaceEnrollment stores the enrollment in place of any the account already has, dropping its recovery codes.
+	ReplaceEnrollment(ctx context.Context, enrollment *Enrollment) error
+	ConfirmEnrollment(ctx context.Context, accountID uuid.UUID, at time.Time) error
+	// DeleteEnrollment removes the enrollment along with the recovery codes.
+	DeleteEnrollment(ctx context.Context, accountID uuid.UUID) error
+	// UseStep records that a code of the time step was accepted unless one of the same or a later step already was,
+	// and reports whether this call was the one to do so.
+	UseStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error)
+
+	ReplaceRecoveryCodes(ctx context.Context, accountID uuid.UUID, codes []RecoveryCode) error
+	// UseRecoveryCode marks the unused recovery code with the hash used, and reports whether there was one.
+	UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string, at time.Time) (bool, error)
+	CountRecoveryCodes(ctx context.Context, accountID uuid.UUID) (int64, error)
+
+	CreateChallenge(ctx context.Context, challenge *Challenge) error
+	FindChallenge(ctx context.Context, tokenHash string) (Challenge, error)
+	// UseChallenge marks the challenge used unless it already was, and reports whether this call was the one to do so.
+	UseChallenge(ctx context.Context, challengeID uuid.UUID, at time.Time) (bool, error)
+	// FailChallenge counts a wrong code against the challenge, using it up after the maximum number of attempts.
+	FailChallenge(ctx context.Context, challengeID uuid.UUID, maxAttempts int, at time.Time) error
+}
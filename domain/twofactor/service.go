@@ -0,0 +1,343 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultChallengePeriod is how long a login challenge may be answered when no period is configured.
+	DefaultChallengePeriod = 5 * time.Minute
+	// MaxChallengeAttempts is how many wrong codes a login challenge takes before it is used up.
+	MaxChallengeAttempts = 5
+	// RecoveryCodeCount is how many recovery codes an account is given at a time.
+	RecoveryCodeCount = 10
+	// DefaultIssuer names this service in authenticator apps when no issuer is configured.
+	DefaultIssuer = "ServerCarte"
+)
+
+// Framework represents the minimum methods that the one-time password algorithm must implement
+type Framework interface {
+	NewSecret() (string, error)
+	// URI is the otpauth:// URI authenticator apps read the secret from.
+	URI(secret, issuer, accountName string) string
+	// Verify checks a code against the secret at the time given, returning the time step it is valid for.
+	Verify(secret, code string, at time.Time) (int64, bool)
+}
+
+// Options configure the service. Accounts of the RequiredRoles cannot log in without a second factor.
+type Options struct {
+	Issuer          string
+	RequiredRoles   []string
+	ChallengePeriod time.Duration
+}
+
+// Service enrolls accounts in TOTP two-factor authentication and runs the second step of their logins.
+type Service interface {
+	// Required reports whether accounts of the role must use two-factor authentication.
+	Required(role string) bool
+	Enabled(ctx context.Context, accountID uuid.UUID) (bool, error)
+	Status(ctx context.Context, accountID uuid.UUID, role string) (Status, error)
+	// Enroll generates a new secret for the account, replacing one not confirmed yet.
+	Enroll(ctx context.Context, accountID uuid.UUID, accountName string) (Secret, error)
+	// Confirm turns two-factor authentication on with a code from the new secret, and returns the recovery codes.
+	Confirm(ctx context.Context, accountID uuid.UUID, code string) ([]string, error)
+	// Disable turns two-factor authentication off, given a code or recovery code, unless the role requires it.
+	Disable(ctx context.Context, accountID uuid.UUID, role, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes, given a code or recovery code.
+	RegenerateRecoveryCodes(ctx context.Context, accountID uuid.UUID, code string) ([]string, error)
+
+	// StartChallenge hands out a challenge token once the account's password checked out.
+	StartChallenge(ctx context.Context, accountID uuid.UUID, username string) (ChallengeResponse, error)
+	// EnrollChallenge enrolls the account of a challenge that has yet to set up an authenticator.
+	EnrollChallenge(ctx context.Context, challengeToken string) (Secret, error)
+	// CompleteChallenge answers a challenge with a code or recovery code and returns the account it logs in. When the
+	// answer confirms a new enrollment, the account's recovery codes are returned as well.
+	CompleteChallenge(ctx context.Context, challengeToken, code string) (uuid.UUID, []string, error)
+}
+
+type twoFactor struct {
+	framework     Framework
+	repo          Repository
+	issuer        string
+	requiredRoles map[string]bool
+	period        time.Duration
+}
+
+// NewService returns a twoFactor - compliant service instance. Must satisfy the Service interface.
+func NewService(framework Framework, repo Repository, options Options) *twoFactor {
+	t := twoFactor{framework: framework, repo: repo, issuer: options.Issuer, requiredRoles: make(map[string]bool),
+		period: options.ChallengePeriod}
+	if t.issuer == "" {
+		t.issuer = DefaultIssuer
+	}
+	if t.period <= 0 {
+		t.period = DefaultChallengePeriod
+	}
+	for _, role := range options.RequiredRoles {
+		t.requiredRoles[strings.ToLower(role)] = true
+	}
+	return &t
+}
+
+func (t *twoFactor) Required(role string) bool {
+	return t.requiredRoles[strings.ToLower(role)]
+}
+
+func (t *twoFactor) Enabled(ctx context.Context, accountID uuid.UUID) (bool, error) {
+	enrollment, err := t.repo.FindEnrollment(ctx, accountID)
+	if errors.Is(err, ErrNotEnrolled) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return enrollment.Confirmed(), nil
+}
+
+func (t *twoFactor) Status(ctx context.Context, accountID uuid.UUID, role string) (Status, error) {
+	enabled, err := t.Enabled(ctx, accountID)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Enabled: enabled, Required: t.Required(role)}
+	if enabled {
+		if status.RecoveryCodesLeft, err = t.repo.CountRecoveryCodes(ctx, accountID); err != nil {
+			return Status{}, err
+		}
+	}
+	return status, nil
+}
+
+func (t *twoFactor) Enroll(ctx context.Context, accountID uuid.UUID, accountName string) (Secret, error) {
+	if enabled, err := t.Enabled(ctx, accountID); err != nil {
+		return Secret{}, err
+	} else if enabled {
+		return Secret{}, ErrAlreadyEnrolled
+	}
+	secret, err := t.framework.NewSecret()
+	if err != nil {
+		return Secret{}, err
+	}
+	if err := t.repo.ReplaceEnrollment(ctx, &Enrollment{AccountID: accountID, Secret: secret}); err != nil {
+		return Secret{}, err
+	}
+	return Secret{Secret: secret, URI: t.framework.URI(secret, t.issuer, accountName)}, nil
+}
+
+func (t *twoFactor) Confirm(ctx context.Context, accountID uuid.UUID, code string) ([]string, error) {
+	enrollment, err := t.repo.FindEnrollment(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Confirmed() {
+		return nil, ErrAlreadyEnrolled
+	}
+	return t.confirm(ctx, &enrollment, code)
+}
+
+// confirm checks a code from a new secret, which may not be a recovery code as there are none yet, and turns the
+// enrollment on.
+func (t *twoFactor) confirm(ctx context.Context, enrollment *Enrollment, code string) ([]string, error) {
+	if err := t.checkTOTP(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+	if err := t.repo.ConfirmEnrollment(ctx, enrollment.AccountID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return t.newRecoveryCodes(ctx, enrollment.AccountID)
+}
+
+func (t *twoFactor) Disable(ctx context.Context, accountID uuid.UUID, role, code string) error {
+	if t.Required(role) {
+		return ErrRequired
+	}
+	enrollment, err := t.enrollment(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if err := t.check(ctx, &enrollment, code); err != nil {
+		return err
+	}
+	return t.repo.DeleteEnrollment(ctx, accountID)
+}
+
+func (t *twoFactor) RegenerateRecoveryCodes(ctx context.Context, accountID uuid.UUID, code string) ([]string, error) {
+	enrollment, err := t.enrollment(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(ctx, &enrollment, code); err != nil {
+		return nil, err
+	}
+	return t.newRecoveryCodes(ctx, accountID)
+}
+
+func (t *twoFactor) StartChallenge(ctx context.Context, accountID uuid.UUID, username string) (ChallengeResponse,
+	error) {
+	enabled, err := t.Enabled(ctx, accountID)
+	if err != nil {
+		return ChallengeResponse{}, err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return ChallengeResponse{}, err
+	}
+	challenge := Challenge{
+		AccountID: accountID,
+		Username:  username,
+		TokenHash: hash(token),
+		ExpiresAt: time.Now().UTC().Add(t.period),
+	}
+	if err := t.repo.CreateChallenge(ctx, &challenge); err != nil {
+		return ChallengeResponse{}, err
+	}
+	return ChallengeResponse{ChallengeToken: token, Expiry: challenge.ExpiresAt.Unix(), EnrollmentRequired: !enabled},
+		nil
+}
+
+func (t *twoFactor) EnrollChallenge(ctx context.Context, challengeToken string) (Secret, error) {
+	challenge, err := t.challenge(ctx, challengeToken)
+	if err != nil {
+		return Secret{}, err
+	}
+	return t.Enroll(ctx, challenge.AccountID, challenge.Username)
+}
+
+func (t *twoFactor) CompleteChallenge(ctx context.Context, challengeToken, code string) (uuid.UUID, []string, error) {
+	challenge, err := t.challenge(ctx, challengeToken)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	enrollment, err := t.repo.FindEnrollment(ctx, challenge.AccountID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	var recoveryCodes []string
+	if enrollment.Confirmed() {
+		err = t.check(ctx, &enrollment, code)
+	} else {
+		recoveryCodes, err = t.confirm(ctx, &enrollment, code)
+	}
+	now := time.Now().UTC()
+	if err == ErrInvalidCode {
+		// Six digits do not take long to guess, so a challenge only takes a few wrong codes.
+		if err := t.repo.FailChallenge(ctx, challenge.ID, MaxChallengeAttempts, now); err != nil {
+			return uuid.Nil, nil, err
+		}
+		return uuid.Nil, nil, ErrInvalidCode
+	}
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	fresh, err := t.repo.UseChallenge(ctx, challenge.ID, now)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	if !fresh {
+		return uuid.Nil, nil, ErrInvalidChallenge
+	}
+	return challenge.AccountID, recoveryCodes, nil
+}
+
+// challenge looks up a challenge that may still be answered.
+func (t *twoFactor) challenge(ctx context.Context, token string) (Challenge, error) {
+	challenge, err := t.repo.FindChallenge(ctx, hash(token))
+	if err != nil || !challenge.Usable(time.Now().UTC()) {
+		return Challenge{}, ErrInvalidChallenge
+	}
+	return challenge, nil
+}
+
+// enrollment looks up a confirmed enrollment.
+func (t *twoFactor) enrollment(ctx context.Context, accountID uuid.UUID) (Enrollment, error) {
+	enrollment, err := t.repo.FindEnrollment(ctx, accountID)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if !enrollment.Confirmed() {
+		return Enrollment{}, ErrNotEnrolled
+	}
+	return enrollment, nil
+}
+
+// check accepts a TOTP code or, failing that, an unused recovery code.
+func (t *twoFactor) check(ctx context.Context, enrollment *Enrollment, code string) error {
+	if err := t.checkTOTP(ctx, enrollment, code); err != ErrInvalidCode {
+		return err
+	}
+	used, err := t.repo.UseRecoveryCode(ctx, enrollment.AccountID, hash(normalizeRecoveryCode(code)),
+		time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// checkTOTP accepts a TOTP code that is valid now and newer than the last one accepted, so that a code seen over
+// someone's shoulder cannot be replayed.
+func (t *twoFactor) checkTOTP(ctx context.Context, enrollment *Enrollment, code string) error {
+	step, ok := t.framework.Verify(enrollment.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidCode
+	}
+	fresh, err := t.repo.UseStep(ctx, enrollment.AccountID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// newRecoveryCodes replaces the account's recovery codes, returning the new ones; only their hashes are kept.
+func (t *twoFactor) newRecoveryCodes(ctx context.Context, accountID uuid.UUID) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	stored := make([]RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+		codes[i] = code[:4] + "-" + code[4:]
+		stored[i] = RecoveryCode{AccountID: accountID, CodeHash: hash(code)}
+	}
+	if err := t.repo.ReplaceRecoveryCodes(ctx, accountID, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode forgives the case and dashes of a recovery code typed in.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func randomToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hash returns the hash a challenge token or recovery code is stored and looked up under.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,57 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// enrollmentRepository answers FindEnrollment with a fixed result; the rest of Repository is left unimplemented.
+type enrollmentRepository struct {
+	Repository
+	enrollment Enrollment
+	err        error
+}
+
+func (r enrollmentRepository) FindEnrollment(context.Context, uuid.UUID) (Enrollment, error) {
+	return r.enrollment, r.err
+}
+
+func TestEnabled(t *testing.T) {
+	confirmedAt := time.Now()
+	timeout := errors.New("database timeout")
+	tests := []struct {
+		name        string
+		repo        enrollmentRepository
+		wantEnabled bool
+		wantErr     error
+	}{
+		{"not enrolled", enrollmentRepository{err: ErrNotEnrolled}, false, nil},
+		{"enrolled but not confirmed", enrollmentRepository{}, false, nil},
+		{"confirmed", enrollmentRepository{enrollment: Enrollment{ConfirmedAt: &confirmedAt}}, true, nil},
+		{"lookup fails", enrollmentRepository{err: timeout}, false, timeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(nil, tt.repo, Options{})
+			enabled, err := service.Enabled(context.Background(), uuid.New())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Enabled() error = %v, want %v", err, tt.wantErr)
+			}
+			if enabled != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", enabled, tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestStatusPassesLookupErrorsUp(t *testing.T) {
+	timeout := errors.New("database timeout")
+	service := NewService(nil, enrollmentRepository{err: timeout}, Options{})
+	if _, err := service.Status(context.Background(), uuid.New(), "admin"); !errors.Is(err, timeout) {
+		t.Errorf("Status() error = %v, want %v", err, timeout)
+	}
+}
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+)
+
+// Subscription registers a URL to be called whenever one of the events it is filtered on happens. Payloads are signed
+// with the subscription's secret so that the receiver can tell they came from this server.
+type Subscription struct {
+	domain.Base
+	URL         string      `json:"url" gorm:"not null"`
+	Secret      string      `json:"-" gorm:"not null"`
+	Events      EventFilter `json:"events" gorm:"not null"`
+	Active      bool        `json:"active" gorm:"default:true"`
+	Description *string     `json:"description,omitempty"`
+}
+
+func (s *Subscription) Validate() error {
+	target, err := url.Parse(s.URL)
+	if err != nil || !target.IsAbs() || target.Host == "" {
+		return fmt.Errorf("url %q must be an absolute http or https url", s.URL)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("url %q must be an absolute http or https url", s.URL)
+	}
+	if s.Secret == "" {
+		return errors.New("secret is empty")
+	}
+	return s.Events.Validate()
+}
+
+// EventFilter lists the events a subscription receives. An entry is either an event type such as "item.updated", a
+// family of events such as "item.*", or "*" for every event.
+type EventFilter []string
+
+func (f EventFilter) Validate() error {
+	if len(f) == 0 {
+		return errors.New("subscription must list at least one event")
+	}
+	for _, pattern := range f {
+		if pattern == "" || strings.ContainsAny(pattern, ", \t\n") {
+			return fmt.Errorf("invalid event filter %q", pattern)
+		}
+		if i := strings.Index(pattern, "*"); i >= 0 && pattern != "*" && !(i == len(pattern)-1 &&
+			strings.HasSuffix(pattern, ".*")) {
+			return fmt.Errorf("invalid event filter %q; wildcards may only end a filter, e.g. item.*", pattern)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the filter lets the event type through.
+func (f EventFilter) Matches(eventType string) bool {
+	for _, pattern := range f {
+		switch {
+		case pattern == "*", pattern == eventType:
+			return true
+		case strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// GormDataType stores the filter as a comma-separated string.
+func (f EventFilter) GormDataType() string {
+	return "string"
+}
+
+func (f EventFilter) Value() (driver.Value, error) {
+	return strings.Join(f, ","), nil
+}
+
+func (f *EventFilter) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into an event filter", value)
+	}
+	*f = nil
+	if text != "" {
+		*f = strings.Split(text, ",")
+	}
+	return nil
+}
+
+// DeliveryStatus is where a delivery stands.
+type DeliveryStatus string
+
+const (
+	// Pending deliveries are waiting for their next attempt.
+	Pending DeliveryStatus = "pending"
+	// Succeeded deliveries were answered with a 2xx status.
+	Succeeded DeliveryStatus = "succeeded"
+	// Failed deliveries ran out of attempts, or belong to a subscription that was deactivated, and are only retried
+	// by hand.
+	Failed DeliveryStatus = "failed"
+)
+
+// Delivery is one event on its way to one subscription, and the log of how the attempts to deliver it went. The
+// payload is kept as it was first sent so that every retry is byte-for-byte the same, signature aside.
+type Delivery struct {
+	domain.Base
+	SubscriptionID uuid.UUID      `json:"subscription_id" gorm:"not null;index"`
+	EventID        uuid.UUID      `json:"event_id" gorm:"not null"`
+	EventType      string         `json:"event_type" gorm:"not null"`
+	Payload        string         `json:"payload" gorm:"type:text;not null"`
+	Status         DeliveryStatus `json:"status" gorm:"not null;index"`
+	Attempts       uint           `json:"attempts" gorm:"default:0"`
+	ResponseStatus *int           `json:"response_status,omitempty"`
+	LastError      *string        `json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time     `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at,omitempty" gorm:"index"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+}
+
+// Payload is the body posted to a subscription.
+type Payload struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository describes the expected behavior for the data persistence of webhook subscriptions and their deliveries.
+type Repository interface {
+	ListSubscriptions(context.Context) (*[]Subscription, error)
+	FindSubscription(context.Context, *Subscription) error
+	CreateSubscription(context.Context, *Subscription) error
+	UpdateSubscription(context.Context, *Subscription) error
+	DeleteSubscription(context.Context, *Subscription) error
+	ListDeliveries(context.Context, uuid.UUID) (*[]Delivery, error)
+	FindDelivery(context.Context, *Delivery) error
+	CreateDelivery(context.Context, *Delivery) error
+	UpdateDelivery(context.Context, *Delivery) error
+	DueDeliveries(context.Context, time.Time, int) (*[]Delivery, error)
+}
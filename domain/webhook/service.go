@@ -0,0 +1,446 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain"
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+const (
+	// dispatchInterval is how often the dispatcher looks for deliveries that are due, when it is not woken up sooner
+	// by a new event.
+	dispatchInterval = 5 * time.Second
+	// dispatchBatch is the most deliveries attempted at once.
+	dispatchBatch = 50
+	// resubscribeDelay is how long FollowMenu waits before subscribing again after the menu event stream failed.
+	resubscribeDelay = time.Second
+)
+
+// ErrDeliveryInProgress is returned when a delivery is redelivered while it is being attempted.
+var ErrDeliveryInProgress = errors.New("webhook delivery is already being attempted")
+
+// Sender posts a signed payload to a subscriber and returns the status code it answered with. An error means no
+// answer was received at all.
+type Sender interface {
+	Send(ctx context.Context, url string, header http.Header, body []byte) (int, error)
+}
+
+// RetryPolicy decides how often, and how long apart, a delivery is attempted. The wait after the nth failed attempt
+// is InitialBackoff doubled n-1 times, but never more than MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    uint
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy spreads 8 attempts over roughly two hours.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 8, InitialBackoff: 30 * time.Second, MaxBackoff: time.Hour}
+
+// backoff returns how long to wait after the given number of failed attempts.
+func (p RetryPolicy) backoff(attempts uint) time.Duration {
+	wait := p.InitialBackoff
+	for i := uint(1); i < attempts && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// Service describes the expected behavior for managing webhook subscriptions and delivering events to them.
+type Service interface {
+	Subscriptions(context.Context) (*[]Subscription, error)
+	SubscriptionByID(context.Context, string) (*Subscription, error)
+	Subscribe(context.Context, *Subscription) error
+	UpdateSubscription(context.Context, string, UpdateSubscriptionRequest) (*Subscription, error)
+	Unsubscribe(context.Context, string) error
+	Deliveries(context.Context, string) (*[]Delivery, error)
+	Redeliver(context.Context, string, string) (*Delivery, error)
+	Notify(context.Context, string, interface{}) error
+	Run(context.Context)
+	FollowMenu(context.Context, menu.Service)
+}
+
+// UpdateSubscriptionRequest holds the fields of a subscription being changed; nil fields are left as they are.
+type UpdateSubscriptionRequest struct {
+	URL         *string      `json:"url,omitempty"`
+	Secret      *string      `json:"secret,omitempty"`
+	Events      *EventFilter `json:"events,omitempty"`
+	Active      *bool        `json:"active,omitempty"`
+	Description *string      `json:"description,omitempty"`
+}
+
+type service struct {
+	repo   Repository
+	sender Sender
+	policy RetryPolicy
+	log    domain.Logger
+	// wake nudges the dispatcher when new deliveries are waiting.
+	wake chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[uuid.UUID]struct{}
+}
+
+// NewService returns a new instance of service. Zero fields of the policy are taken from DefaultRetryPolicy.
+func NewService(repo Repository, sender Sender, policy RetryPolicy, log domain.Logger) *service {
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+	return &service{
+		repo:     repo,
+		sender:   sender,
+		policy:   policy,
+		log:      log,
+		wake:     make(chan struct{}, 1),
+		inFlight: make(map[uuid.UUID]struct{}),
+	}
+}
+
+// ---   Subscriptions  --- //
+
+func (s *service) Subscriptions(ctx context.Context) (*[]Subscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *service) SubscriptionByID(ctx context.Context, rawID string) (*Subscription, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return &Subscription{}, err
+	}
+	subscription := Subscription{}
+	subscription.ID = id
+	if err := s.repo.FindSubscription(ctx, &subscription); err != nil {
+		return &Subscription{}, err
+	}
+	return &subscription, nil
+}
+
+// Subscribe registers a new subscription. A secret is generated when none is given; it is left on the subscription
+// for the caller to hand back, since it is not shown again.
+func (s *service) Subscribe(ctx context.Context, subscription *Subscription) error {
+	if subscription.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return err
+		}
+		subscription.Secret = secret
+	}
+	subscription.Active = true
+	if err := subscription.Validate(); err != nil {
+		return err
+	}
+	return s.repo.CreateSubscription(ctx, subscription)
+}
+
+func (s *service) UpdateSubscription(ctx context.Context, rawID string, req UpdateSubscriptionRequest) (*Subscription,
+	error) {
+	subscription, err := s.SubscriptionByID(ctx, rawID)
+	if err != nil {
+		return subscription, err
+	}
+	if req.URL != nil {
+		subscription.URL = *req.URL
+	}
+	if req.Secret != nil {
+		subscription.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		subscription.Events = *req.Events
+	}
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	if req.Description != nil {
+		subscription.Description = req.Description
+	}
+	if err := subscription.Validate(); err != nil {
+		return &Subscription{}, err
+	}
+	if err := s.repo.UpdateSubscription(ctx, subscription); err != nil {
+		return &Subscription{}, err
+	}
+	return subscription, nil
+}
+
+// Unsubscribe deletes a subscription along with its delivery log.
+func (s *service) Unsubscribe(ctx context.Context, rawID string) error {
+	subscription, err := s.SubscriptionByID(ctx, rawID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(ctx, subscription)
+}
+
+// newSecret returns 32 random bytes, hex encoded.
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// ---   Deliveries  --- //
+
+// Deliveries returns the delivery log of a subscription, newest first.
+func (s *service) Deliveries(ctx context.Context, rawSubscriptionID string) (*[]Delivery, error) {
+	subscription, err := s.SubscriptionByID(ctx, rawSubscriptionID)
+	if err != nil {
+		return &[]Delivery{}, err
+	}
+	return s.repo.ListDeliveries(ctx, subscription.ID)
+}
+
+// Redeliver attempts a delivery again right away, whatever its status, and returns how the attempt went. A failed
+// attempt is retried on the usual schedule while the delivery has attempts left; one that had already run out of
+// attempts stays failed.
+func (s *service) Redeliver(ctx context.Context, rawSubscriptionID, rawDeliveryID string) (*Delivery, error) {
+	subscription, err := s.SubscriptionByID(ctx, rawSubscriptionID)
+	if err != nil {
+		return &Delivery{}, err
+	}
+	deliveryID, err := uuid.Parse(rawDeliveryID)
+	if err != nil {
+		return &Delivery{}, err
+	}
+	delivery := Delivery{}
+	delivery.ID = deliveryID
+	if err := s.repo.FindDelivery(ctx, &delivery); err != nil {
+		return &Delivery{}, err
+	}
+	if delivery.SubscriptionID != subscription.ID {
+		return &Delivery{}, ErrDeliveryNotFound
+	}
+	if !s.claim(delivery.ID) {
+		return &Delivery{}, ErrDeliveryInProgress
+	}
+	defer s.release(delivery.ID)
+
+	if err := s.attempt(ctx, subscription, &delivery); err != nil {
+		return &Delivery{}, err
+	}
+	return &delivery, nil
+}
+
+// Notify queues a delivery of the event to every active subscription whose filter it matches.
+func (s *service) Notify(ctx context.Context, eventType string, data interface{}) error {
+	subscriptions, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	var eventID uuid.UUID
+	now := time.Now().UTC()
+	for _, subscription := range *subscriptions {
+		if !subscription.Active || !subscription.Events.Matches(eventType) {
+			continue
+		}
+		if payload == nil {
+			if eventID, err = uuid.NewRandom(); err != nil {
+				return err
+			}
+			if payload, err = json.Marshal(Payload{ID: eventID, Type: eventType, CreatedAt: now, Data: data}); err != nil {
+				return err
+			}
+		}
+		delivery := Delivery{
+			SubscriptionID: subscription.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         Pending,
+			NextAttemptAt:  &now,
+		}
+		if err := s.repo.CreateDelivery(ctx, &delivery); err != nil {
+			return err
+		}
+	}
+	if payload != nil {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run attempts the deliveries as they fall due until ctx is done. Deliveries still pending when the server stopped
+// are picked up again on the next start.
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+	for {
+		s.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// dispatch attempts the deliveries that are due, side by side so that one slow subscriber does not hold up the rest.
+func (s *service) dispatch(ctx context.Context) {
+	for {
+		due, err := s.repo.DueDeliveries(ctx, time.Now().UTC(), dispatchBatch)
+		if err != nil {
+			s.log.Errorf("webhook: could not look up due deliveries: %v", err)
+			return
+		}
+		subscriptions := make(map[uuid.UUID]*Subscription)
+		var wg sync.WaitGroup
+		attempted := 0
+		for i := range *due {
+			delivery := &(*due)[i]
+			if !s.claim(delivery.ID) {
+				continue
+			}
+			subscription, ok := subscriptions[delivery.SubscriptionID]
+			if !ok {
+				subscription = &Subscription{}
+				subscription.ID = delivery.SubscriptionID
+				if err := s.repo.FindSubscription(ctx, subscription); err != nil {
+					subscription = nil
+				}
+				subscriptions[delivery.SubscriptionID] = subscription
+			}
+			if subscription == nil || !subscription.Active {
+				s.abandon(ctx, delivery, "subscription is inactive")
+				s.release(delivery.ID)
+				continue
+			}
+			attempted++
+			wg.Add(1)
+			go func(subscription *Subscription, delivery *Delivery) {
+				defer wg.Done()
+				defer s.release(delivery.ID)
+				if err := s.attempt(ctx, subscription, delivery); err != nil {
+					s.log.Errorf("webhook: could not record delivery %s: %v", delivery.ID, err)
+				}
+			}(subscription, delivery)
+		}
+		wg.Wait()
+		if len(*due) < dispatchBatch || attempted == 0 || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// attempt sends the delivery once and records the outcome: succeeded on a 2xx answer, otherwise pending until the
+// next attempt, or failed once the attempts run out.
+func (s *service) attempt(ctx context.Context, subscription *Subscription, delivery *Delivery) error {
+	body := []byte(delivery.Payload)
+	now := time.Now().UTC()
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(EventHeader, delivery.EventType)
+	header.Set(DeliveryHeader, delivery.ID.String())
+	header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	header.Set(SignatureHeader, Sign(subscription.Secret, now.Unix(), body))
+
+	status, err := s.sender.Send(ctx, subscription.URL, header, body)
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = nil
+	delivery.LastError = nil
+	if err == nil {
+		delivery.ResponseStatus = &status
+		if status < 200 || status > 299 {
+			err = fmt.Errorf("receiver answered %d %s", status, http.StatusText(status))
+		}
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = Succeeded
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= s.policy.MaxAttempts:
+		message := err.Error()
+		delivery.Status = Failed
+		delivery.LastError = &message
+		delivery.NextAttemptAt = nil
+	default:
+		message := err.Error()
+		next := now.Add(s.policy.backoff(delivery.Attempts))
+		delivery.Status = Pending
+		delivery.LastError = &message
+		delivery.NextAttemptAt = &next
+	}
+	return s.repo.UpdateDelivery(ctx, delivery)
+}
+
+// abandon fails a delivery without attempting it.
+func (s *service) abandon(ctx context.Context, delivery *Delivery, reason string) {
+	delivery.Status = Failed
+	delivery.LastError = &reason
+	delivery.NextAttemptAt = nil
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		s.log.Errorf("webhook: could not record delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// claim marks a delivery as being attempted, unless it already is.
+func (s *service) claim(id uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.inFlight[id]; ok {
+		return false
+	}
+	s.inFlight[id] = struct{}{}
+	return true
+}
+
+func (s *service) release(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inFlight, id)
+}
+
+// FollowMenu turns menu changes into webhook events until ctx is done, under the same names as the menu event
+// stream, e.g. item.updated when a price changes. When the menu stream drops it, it subscribes again from the last
+// event it saw.
+func (s *service) FollowMenu(ctx context.Context, menuSvc menu.Service) {
+	var lastEventID uint64
+	for ctx.Err() == nil {
+		events, err := menuSvc.Events(ctx, lastEventID)
+		if err != nil {
+			s.log.Errorf("webhook: could not follow menu events: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(resubscribeDelay):
+			}
+			continue
+		}
+		for event := range events {
+			lastEventID = event.ID
+			if err := s.Notify(ctx, string(event.Type), event); err != nil {
+				s.log.Errorf("webhook: could not queue %s event: %v", event.Type, err)
+			}
+		}
+	}
+}
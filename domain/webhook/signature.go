@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent along with every delivery.
+const (
+	EventHeader     = "X-Servercarte-Event"
+	DeliveryHeader  = "X-Servercarte-Delivery"
+	TimestampHeader = "X-Servercarte-Timestamp"
+	SignatureHeader = "X-Servercarte-Signature"
+)
+
+// signaturePrefix names the algorithm used to sign a payload.
+const signaturePrefix = "sha256="
+
+var (
+	ErrMissingSignature = errors.New("webhook signature or timestamp is missing")
+	ErrInvalidSignature = errors.New("webhook signature does not match the payload")
+	ErrStaleSignature   = errors.New("webhook timestamp is outside the allowed tolerance")
+)
+
+// Sign returns the signature of a payload sent at the given unix time: the hex encoded HMAC-SHA256, keyed with the
+// subscription's secret, of the timestamp, a period and the body. Signing the timestamp lets receivers turn away
+// replayed deliveries.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a delivery against its body, as a receiver would. A tolerance of zero
+// accepts any timestamp.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	signature, rawTimestamp := header.Get(SignatureHeader), header.Get(TimestampHeader)
+	if !strings.HasPrefix(signature, signaturePrefix) || rawTimestamp == "" {
+		return ErrMissingSignature
+	}
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrStaleSignature
+		}
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -4,7 +4,10 @@ go 1.16
 
 require (
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
-	github.com/gin-gonic/gin v1.6.3
+	// gin v1.7.7 for Engine.SetTrustedProxies, which login throttling relies on to tell the client address apart
+	// from a forged X-Forwarded-For header; v1.6.3 trusts that header from anyone (CVE-2020-28483).
+	github.com/gin-gonic/gin v1.7.7
+	github.com/go-pdf/fpdf v0.6.0
 	github.com/google/uuid v1.2.0
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/sys v0.0.0-20210324051608-47abb6519492 // indirect
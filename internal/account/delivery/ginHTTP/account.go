@@ -1,48 +1,74 @@
 package ginHTTP
 
 import (
-	"github.com/coquizen/servercarte/domain/user"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"github.com/coquizen/servercarte/domain/user"
 
 	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/authorization"
+	"github.com/coquizen/servercarte/domain/twofactor"
 
 	"github.com/google/uuid"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/apikey"
 )
 
 type accountHandler struct {
-	authSvc    authentication.Service
-	accountSvc account.Service
+	authSvc      authentication.Service
+	accountSvc   account.Service
+	twoFactorSvc twofactor.Service
+	keySvc       apikey.Service
 }
 
 type accountRequest struct {
 	Username string `json:"username"`
 }
 
-// RegisterRoutes sets up account API endpoint using Gin.
-func RegisterRoutes(accountSvc account.Service, authSvc authentication.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorizationMiddleware gin.HandlerFunc) {
-	handler := accountHandler{authSvc, accountSvc}
+// RegisterRoutes sets up account API endpoint using Gin. Managing accounts requires the account:manage permission;
+// the routes acting on the account logged in take sessionMiddleWare, which does not accept API keys. PIN logins
+// are only accepted from devices holding a device API key.
+func RegisterRoutes(accountSvc account.Service, authSvc authentication.Service, twoFactorSvc twofactor.Service, keySvc apikey.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, sessionMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc) {
+	handler := accountHandler{authSvc, accountSvc, twoFactorSvc, keySvc}
 	publicRoutes(handler, r)
-	privateRoutes(handler, r, authMiddleWare, authorizationMiddleware)
+	privateRoutes(handler, r, authMiddleWare, sessionMiddleWare, authorize(authorization.ManageAccounts))
 }
 
 func publicRoutes(handler accountHandler, router *gin.Engine) {
 	router.POST("/login", handler.login)
+	router.POST("/login/2fa", handler.completeLogin)
+	router.POST("/login/2fa/enroll", handler.enrollAtLogin)
+	router.POST("/login/pin", handler.pinLogin)
+	router.POST("/token/refresh", handler.refresh)
+	router.POST("/password/forgot", handler.forgotPassword)
+	router.POST("/password/reset", handler.resetPassword)
+	router.GET("/email/verify", handler.verifyEmail)
+	router.POST("/email/verify", handler.verifyEmail)
 }
 
-func privateRoutes(handler accountHandler, router *gin.Engine, authMiddleWare gin.HandlerFunc, authorizationMiddleware gin.HandlerFunc) {
+func privateRoutes(handler accountHandler, router *gin.Engine, authMiddleWare gin.HandlerFunc, sessionMiddleWare gin.HandlerFunc, authorizationMiddleware gin.HandlerFunc) {
+	router.POST("/logout", sessionMiddleWare, handler.logout)
+	router.POST("/password/change", sessionMiddleWare, handler.changePassword)
+	router.POST("/email/verify/resend", sessionMiddleWare, handler.resendVerification)
+	router.PUT("/pin", sessionMiddleWare, handler.setPIN)
+	router.DELETE("/pin", sessionMiddleWare, handler.clearPIN)
 
 	routerGroup := router.Group("/accounts", authMiddleWare, authorizationMiddleware)
 	routerGroup.GET("", handler.list)
+	routerGroup.GET("/audit", handler.auditLog)
 
 	anotherRouterGroup := router.Group("/account", authMiddleWare, authorizationMiddleware)
 	anotherRouterGroup.GET("", handler.view)
 	anotherRouterGroup.POST("", handler.create)
 	anotherRouterGroup.PATCH("", handler.update)
 	anotherRouterGroup.DELETE("", handler.delete)
+	anotherRouterGroup.POST("/unlock", handler.unlock)
 
 }
 
@@ -70,6 +96,8 @@ type credentials struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// login checks a password. Accounts using two-factor authentication, or whose role requires it, are handed a
+// challenge token to answer at /login/2fa; everyone else gets their tokens right away.
 func (h *accountHandler) login(ctx *gin.Context) {
 	var cred credentials
 	if err := ctx.ShouldBindJSON(&cred); err != nil {
@@ -77,24 +105,341 @@ func (h *accountHandler) login(ctx *gin.Context) {
 		return
 	}
 
-	acct, err := h.accountSvc.Authenticate(ctx, cred.Username, cred.Password)
+	acct, err := h.accountSvc.Authenticate(ctx, cred.Username, cred.Password, ctx.ClientIP())
 	if err != nil {
+		var throttled *account.ThrottledError
+		if errors.As(err, &throttled) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	tokenString, err := h.authSvc.GenerateToken(ctx, acct.ID, acct.Username, int(acct.Role))
+
+	enabled, err := h.twoFactorSvc.Enabled(ctx, acct.ID)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if enabled || h.twoFactorSvc.Required(acct.Role.String()) {
+		challenge, err := h.twoFactorSvc.StartChallenge(ctx, acct.ID, acct.Username)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		ctx.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	tokens, err := h.authSvc.StartSession(ctx, acct.ID, acct.Username, int(acct.Role),
+		h.accountSvc.PasswordExpired(&acct))
 	if err != nil {
 		ctx.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 
-	claims, err := h.authSvc.ParseTokenClaims(tokenString)
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+type challengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+}
+
+// secondFactorTokens are handed out by a two-step login. Recovery codes are only included when the login completed
+// the account's enrollment, and are never shown again.
+type secondFactorTokens struct {
+	authentication.Tokens
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// enrollAtLogin sets up an authenticator for an account whose role requires one, before its first two-step login.
+func (h *accountHandler) enrollAtLogin(ctx *gin.Context) {
+	var req challengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	secret, err := h.twoFactorSvc.EnrollChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		switch err {
+		case twofactor.ErrInvalidChallenge:
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case twofactor.ErrAlreadyEnrolled:
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": secret})
+}
+
+// completeLogin exchanges a challenge token and a code from the authenticator app, or a recovery code, for tokens.
+func (h *accountHandler) completeLogin(ctx *gin.Context) {
+	var req challengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	accountID, recoveryCodes, err := h.twoFactorSvc.CompleteChallenge(ctx, req.ChallengeToken, req.Code)
+	if err != nil {
+		switch err {
+		case twofactor.ErrInvalidChallenge, twofactor.ErrInvalidCode:
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case twofactor.ErrNotEnrolled:
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	acct, err := h.accountSvc.FindByID(ctx, accountID)
 	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
+	tokens, err := h.authSvc.StartSession(ctx, acct.ID, acct.Username, int(acct.Role),
+		h.accountSvc.PasswordExpired(&acct))
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, secondFactorTokens{tokens, recoveryCodes})
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// refresh swaps a refresh token for a new access token and refresh token. Each refresh token works once; presenting
+// one a second time logs its session out everywhere.
+func (h *accountHandler) refresh(ctx *gin.Context) {
+	var req refreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"token": tokenString, "expiry": claims.Expiry})
+	tokens, err := h.accountSvc.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		switch err {
+		case authentication.ErrInvalidRefreshToken, authentication.ErrExpiredToken, authentication.ErrRevokedToken,
+			authentication.ErrRefreshTokenReused, account.ErrAccountNotFound:
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// logout revokes the session of the access token used, or with ?all=true every session of the account.
+func (h *accountHandler) logout(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+
+	var err error
+	if ctx.Query("all") == "true" {
+		err = h.authSvc.EndAccountSessions(ctx, claims.AccountID)
+	} else {
+		err = h.authSvc.EndSession(ctx, claims.SessionID)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// forgotPassword mails a password reset link. It answers the same whether or not the email address has an account.
+func (h *accountHandler) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.accountSvc.ForgotPassword(ctx, req.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusAccepted, gin.H{"data": "if an account uses this email address, a reset link is on its way"})
+}
+
+// resetPassword sets a new password with the token from a reset link. It logs the account out everywhere.
+func (h *accountHandler) resetPassword(ctx *gin.Context) {
+	var req account.PasswordResetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.accountSvc.ResetPassword(ctx, req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "password successfully reset"})
+}
+
+// changePassword changes the password of the account logged in, given its current password.
+func (h *accountHandler) changePassword(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+
+	var req account.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.accountSvc.ChangePassword(ctx, claims.Username, req.OldPassword, req.Password,
+		req.PasswordConfirm); err != nil {
+		if err == account.ErrNotAuthorized {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "password successfully changed"})
+}
+
+// setPIN sets the PIN of the account logged in, given its password.
+func (h *accountHandler) setPIN(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+
+	var req account.PINRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.accountSvc.SetPIN(ctx, claims.AccountID, req); err != nil {
+		if err == account.ErrNotAuthorized {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "pin successfully set"})
+}
+
+func (h *accountHandler) clearPIN(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	if err := h.accountSvc.ClearPIN(ctx, claims.AccountID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "pin successfully removed"})
+}
+
+// pinLogin logs staff in on a shared device with their PIN. The request must carry the device's API key, and is
+// answered with a short-lived access token that only grants the PIN scopes and cannot be refreshed.
+func (h *accountHandler) pinLogin(ctx *gin.Context) {
+	key, ok := apikey.FromRequest(ctx.Request)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "a device api key is required"})
+		return
+	}
+	device, err := h.keySvc.Authenticate(ctx, key, ctx.ClientIP(), ctx.Request.Method+" "+ctx.Request.URL.Path)
+	if err != nil || !device.Device {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "a device api key is required"})
+		return
+	}
+
+	var req account.PINLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	tokens, err := h.accountSvc.PINLogin(ctx, req, ctx.ClientIP())
+	if err != nil {
+		var throttled *account.ThrottledError
+		if errors.As(err, &throttled) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		if err == account.ErrInvalidPIN {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// verifyEmail verifies an email address with the token from a verification link, given as ?token= so that the
+// link itself can point here, or in a JSON body.
+func (h *accountHandler) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.accountSvc.VerifyEmail(ctx, req.Token); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "email address successfully verified"})
+}
+
+// resendVerification mails another verification link to the account logged in, unless it is verified already.
+func (h *accountHandler) resendVerification(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+
+	if err := h.accountSvc.SendVerification(ctx, claims.AccountID); err != nil {
+		if err == account.ErrAccountNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+// unlock lifts the lockout of a username or an IP address, e.g. {"username": "remy"}, before it runs out.
+func (h *accountHandler) unlock(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+
+	var req account.UnlockRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.accountSvc.Unlock(ctx, claims.Username, req); err != nil {
+		if err == account.ErrNothingToUnlock {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "lockout successfully lifted"})
+}
+
+// auditLog lists the latest security events, newest first, narrowed down by ?username=, ?actor= and ?event=.
+func (h *accountHandler) auditLog(ctx *gin.Context) {
+	var filter account.AuditFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entries, err := h.accountSvc.AuditLog(ctx, filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": entries})
 }
 
 func (h *accountHandler) list(ctx *gin.Context) {
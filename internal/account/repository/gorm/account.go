@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -62,3 +63,164 @@ func (a *AccountRepository) Update(ctx context.Context, account *account.Account
 func (a *AccountRepository) Delete(ctx context.Context, accountID uuid.UUID) error {
 	return a.db.Delete(&account.Account{}, accountID).Error
 }
+
+func (a *AccountRepository) FindByID(ctx context.Context, accountID uuid.UUID) (account.Account, error) {
+	var found account.Account
+	if err := a.db.Preload("User").First(&found, accountID).Error; err != nil {
+		return nullAccount, err
+	}
+	return found, nil
+}
+
+func (a *AccountRepository) FindByEmail(ctx context.Context, email string) (account.Account, error) {
+	var found account.Account
+	if err := a.db.Preload("User").Joins("JOIN users ON users.id = accounts.user_id").Where(
+		"LOWER(users.email) = LOWER(?)", email).First(&found).Error; err != nil {
+		return nullAccount, err
+	}
+	return found, nil
+}
+
+// SetPassword only touches the password, unlike Update, which saves every field of the account.
+func (a *AccountRepository) SetPassword(ctx context.Context, accountID uuid.UUID, passwordHash string, at time.Time,
+	keep int) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		var current account.Account
+		if err := tx.Select("id", "password").First(&current, accountID).Error; err != nil {
+			return err
+		}
+		if keep > 0 && current.Password != "" {
+			if err := tx.Create(&account.PasswordHistory{AccountID: accountID, Hash: current.Password}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&account.Account{}).Where("id = ?", accountID).Updates(map[string]interface{}{
+			"password": passwordHash, "password_changed_at": at}).Error; err != nil {
+			return err
+		}
+
+		stale := tx.Where("account_id = ?", accountID)
+		if keep > 0 {
+			var kept []uuid.UUID
+			if err := tx.Model(&account.PasswordHistory{}).Where("account_id = ?", accountID).Order(
+				"created_at desc").Limit(keep).Pluck("id", &kept).Error; err != nil {
+				return err
+			}
+			stale = stale.Where("id NOT IN ?", kept)
+		}
+		return stale.Delete(&account.PasswordHistory{}).Error
+	})
+}
+
+func (a *AccountRepository) SetPIN(ctx context.Context, accountID uuid.UUID, pinHash string) error {
+	return a.db.Model(&account.Account{}).Where("id = ?", accountID).Update("pin", pinHash).Error
+}
+
+func (a *AccountRepository) RehashPassword(ctx context.Context, accountID uuid.UUID, oldHash, newHash string) error {
+	return a.db.Model(&account.Account{}).Where("id = ? AND password = ?", accountID, oldHash).Update("password",
+		newHash).Error
+}
+
+func (a *AccountRepository) PasswordHistory(ctx context.Context, accountID uuid.UUID, limit int) ([]string, error) {
+	var hashes []string
+	err := a.db.Model(&account.PasswordHistory{}).Where("account_id = ?", accountID).Order("created_at desc").Limit(
+		limit).Pluck("hash", &hashes).Error
+	return hashes, err
+}
+
+func (a *AccountRepository) SetEmailVerified(ctx context.Context, accountID uuid.UUID, at time.Time) error {
+	return a.db.Model(&account.Account{}).Where("id = ? AND email_verified_at IS NULL", accountID).Update(
+		"email_verified_at", at).Error
+}
+
+func (a *AccountRepository) CreateToken(ctx context.Context, token *account.OneTimeToken) error {
+	return a.db.Create(token).Error
+}
+
+func (a *AccountRepository) FindToken(ctx context.Context, tokenHash string,
+	purpose account.TokenPurpose) (account.OneTimeToken, error) {
+	var token account.OneTimeToken
+	err := a.db.Where("token_hash = ? AND purpose = ?", tokenHash, purpose).First(&token).Error
+	return token, err
+}
+
+// UseToken marks the token used in a single conditional update, so that of two requests racing to spend the same
+// token only one succeeds.
+func (a *AccountRepository) UseToken(ctx context.Context, tokenID uuid.UUID, at time.Time) (bool, error) {
+	result := a.db.Model(&account.OneTimeToken{}).Where("id = ? AND used_at IS NULL", tokenID).Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (a *AccountRepository) ExpireTokens(ctx context.Context, accountID uuid.UUID, purpose account.TokenPurpose,
+	at time.Time) error {
+	return a.db.Model(&account.OneTimeToken{}).Where("account_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?",
+		accountID, purpose, at).Update("expires_at", at).Error
+}
+
+func (a *AccountRepository) FindThrottle(ctx context.Context, key string) (account.LoginThrottle, error) {
+	var throttle account.LoginThrottle
+	err := a.db.Where("throttle_key = ?", key).First(&throttle).Error
+	return throttle, err
+}
+
+// CountFailure creates the key's throttle unless it is there already, then counts the failure in one update. The
+// lockout is cleared before last_failure_at is set, since MySQL assigns from left to right, using the values it has
+// just set.
+func (a *AccountRepository) CountFailure(ctx context.Context, key string, now time.Time,
+	window time.Duration) (account.LoginThrottle, error) {
+	throttle := account.LoginThrottle{Key: key, LastFailureAt: now}
+	if err := a.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&throttle).Error; err != nil {
+		return throttle, err
+	}
+	windowStart := now.Add(-window)
+	if err := a.db.Exec(`UPDATE login_throttles SET
+		failures = CASE WHEN last_failure_at < ? OR locked_until <= ? THEN 1 ELSE failures + 1 END,
+		locked_until = CASE WHEN last_failure_at < ? OR locked_until <= ? THEN NULL ELSE locked_until END,
+		last_failure_at = ?, updated_at = ?
+		WHERE throttle_key = ?`, windowStart, now, windowStart, now, now, now, key).Error; err != nil {
+		return throttle, err
+	}
+	return a.FindThrottle(ctx, key)
+}
+
+func (a *AccountRepository) LockThrottle(ctx context.Context, key string, now, until time.Time) (bool, error) {
+	result := a.db.Model(&account.LoginThrottle{}).
+		Where("throttle_key = ? AND (locked_until IS NULL OR locked_until <= ?)", key, now).
+		Update("locked_until", until)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (a *AccountRepository) RefundFailure(ctx context.Context, key string) error {
+	return a.db.Model(&account.LoginThrottle{}).Where("throttle_key = ? AND failures > 0", key).
+		Update("failures", gorm.Expr("failures - 1")).Error
+}
+
+func (a *AccountRepository) DeleteThrottle(ctx context.Context, key string) error {
+	return a.db.Where("throttle_key = ?", key).Delete(&account.LoginThrottle{}).Error
+}
+
+func (a *AccountRepository) CreateAuditEntry(ctx context.Context, entry *account.AuditEntry) error {
+	return a.db.Create(entry).Error
+}
+
+func (a *AccountRepository) ListAuditEntries(ctx context.Context, filter account.AuditFilter,
+	limit int) ([]account.AuditEntry, error) {
+	var entries []account.AuditEntry
+	query := a.db.Order("created_at desc").Limit(limit)
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return []account.AuditEntry{}, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package ginHTTP
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/apikey"
+	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/authorization"
+)
+
+type apiKeyHandler struct {
+	keySvc apikey.Service
+}
+
+// RegisterRoutes sets up the API key endpoints using Gin. Managing API keys requires the apikey:manage permission,
+// and an access token: keys cannot mint other keys.
+func RegisterRoutes(svc apikey.Service, r *gin.Engine, sessionMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc) {
+	h := apiKeyHandler{svc}
+	keyGroup := r.Group("/api/v1/api-keys", sessionMiddleWare, authorize(authorization.ManageAPIKeys))
+	keyGroup.GET("", h.listKeys)
+	keyGroup.POST("", h.createKey)
+	keyGroup.DELETE("/:id", h.revokeKey)
+}
+
+func (h *apiKeyHandler) listKeys(ctx *gin.Context) {
+	keys, err := h.keySvc.Keys(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// createKey issues a key. The key itself is only ever shown in this response.
+func (h *apiKeyHandler) createKey(ctx *gin.Context) {
+	var req apikey.CreateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	created, err := h.keySvc.Create(ctx, req, claims.Username, account.AccessLevel(claims.Role))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": created.APIKey, "key": created.Key})
+}
+
+func (h *apiKeyHandler) revokeKey(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	if err := h.keySvc.Revoke(ctx, ctx.Param("id"), claims.Username); err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "api key successfully revoked"})
+}
+
+// statusFor maps an error from the API key service to the status code it is answered with.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, apikey.ErrKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apikey.ErrAlreadyRevoked):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
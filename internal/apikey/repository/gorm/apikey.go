@@ -0,0 +1,66 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/coquizen/servercarte/domain/apikey"
+)
+
+// apiKeyRepository represents the client to its persistent repository
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository instantiates an instance for data persistence
+func NewAPIKeyRepository(db *gorm.DB) *apiKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) List(_ context.Context) ([]apikey.APIKey, error) {
+	var keys []apikey.APIKey
+	if err := r.db.Order("created_at").Find(&keys).Error; err != nil {
+		return keys, err
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Find(_ context.Context, id uuid.UUID) (*apikey.APIKey, error) {
+	var key apikey.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &key, apikey.ErrKeyNotFound
+		}
+		return &key, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByPrefix(_ context.Context, prefix string) (*apikey.APIKey, error) {
+	var key apikey.APIKey
+	if err := r.db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &key, apikey.ErrKeyNotFound
+		}
+		return &key, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Create(_ context.Context, key *apikey.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) Revoke(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&apikey.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *apiKeyRepository) Touch(_ context.Context, id uuid.UUID, at time.Time, ip string) error {
+	return r.db.Model(&apikey.APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": at, "last_used_ip": ip}).Error
+}
@@ -0,0 +1,18 @@
+package ginHTTP
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/authentication"
+)
+
+// RegisterRoutes publishes the keys access tokens are verified with, so that other services can verify them without
+// sharing a secret.
+func RegisterRoutes(authSvc authentication.Service, r *gin.Engine) {
+	r.GET("/.well-known/jwks.json", func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, authSvc.KeySet())
+	})
+}
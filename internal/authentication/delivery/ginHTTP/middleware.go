@@ -5,44 +5,74 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/apikey"
 	"github.com/coquizen/servercarte/domain/authentication"
 )
 
 type authenticationMiddleware struct {
 	authSvc authentication.Service
+	keySvc  apikey.Service
+	// fullLogin turns down scoped access tokens, e.g. those of a PIN login.
+	fullLogin bool
 }
 
-// NewMiddleWare is a constructor function to be used as an authentication middleware
+// NewMiddleWare is a constructor function to be used as an authentication middleware. It only accepts access tokens
+// of a full login, for routes that act on the account logged in.
 func NewMiddleWare(authSvc authentication.Service) gin.HandlerFunc {
 	return (&authenticationMiddleware{
-		authSvc,
+		authSvc:   authSvc,
+		fullLogin: true,
 	}).handle
 }
 
-//
+// NewMiddleWareWithAPIKeys returns an authentication middleware that accepts API keys as well as access tokens.
+func NewMiddleWareWithAPIKeys(authSvc authentication.Service, keySvc apikey.Service) gin.HandlerFunc {
+	return (&authenticationMiddleware{
+		authSvc: authSvc,
+		keySvc:  keySvc,
+	}).handle
+}
+
+// handle rejects requests without a valid access token, or whose session has been revoked, e.g. by logging out
 func (m *authenticationMiddleware) handle(ctx *gin.Context) {
+	if key, ok := m.apiKey(ctx.Request); ok {
+		m.handleAPIKey(ctx, key)
+		return
+	}
 	tokenString, err := m.authSvc.ExtractToken(ctx.Request)
 	if err != nil {
-		if err == authentication.ErrInvalidAccessToken {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		ctx.AbortWithStatus(http.StatusInternalServerError)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	claims, err := m.authSvc.Authorize(ctx, tokenString)
+	if err != nil || (m.fullLogin && claims.Restricted()) {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	claims, err := m.authSvc.ParseTokenClaims(tokenString)
-	if err != nil {
-		if err == authentication.ErrInvalidAccessToken {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		} else if err == authentication.ErrExpiredToken {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
- 	}
 	ctx.Set(authentication.CtxAuthenticationKey, claims)
 }
 
+// apiKey returns the API key the request was made with, if API keys are accepted and it was made with one.
+func (m *authenticationMiddleware) apiKey(req *http.Request) (string, bool) {
+	if m.keySvc == nil {
+		return "", false
+	}
+	return apikey.FromRequest(req)
+}
 
-
-
+// handleAPIKey rejects requests made with an unknown, expired or revoked key. Keys have the lowest role, so that
+// nothing mistakes them for an account; the authorizer only lets them use the permissions they were granted.
+func (m *authenticationMiddleware) handleAPIKey(ctx *gin.Context, key string) {
+	found, err := m.keySvc.Authenticate(ctx, key, ctx.ClientIP(), ctx.Request.Method+" "+ctx.Request.URL.Path)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ctx.Set(authentication.CtxAuthenticationKey, authentication.CustomClaims{
+		APIKeyID: found.ID,
+		Username: "apikey:" + found.Prefix,
+		Role:     int(account.Guest),
+		Scopes:   found.Scopes,
+	})
+}
@@ -17,21 +17,30 @@ import (
 )
 
 var (
-	ErrMalformedToken = errors.New("token could not be parsed")
+	ErrMalformedToken   = errors.New("token could not be parsed")
 	ErrNonExistentToken = errors.New("no token found in Authorization header")
 )
 
 var NullCustomClaims = authentication.CustomClaims{}
 
-
 // adapter is an authentication adapter
 type adapter struct {
-	secretKey        []byte
+	// signingKey is the secret of HMAC algorithms, or the private key of asymmetric ones.
+	signingKey       interface{}
+	keyID            string
 	algorithm        jwt.SigningMethod
+	verificationKeys map[string]verificationKey
+	keySet           authentication.KeySet
 	expirationPeriod time.Duration
 	minSecretLength  int
 }
 
+// verificationKey is a key tokens are accepted from, along with the algorithm it is used with.
+type verificationKey struct {
+	algorithm jwt.SigningMethod
+	key       interface{}
+}
+
 // claims is a local alias for authentication.CustomClaims. To facilitate satisfaction
 // of a contract as specified in jwt-go
 type claims authentication.CustomClaims
@@ -59,23 +68,17 @@ func isTokenExpired(tokenExpiry int64) bool {
 	currTime := time.Now().UTC()
 	return currTime.After(exp)
 }
-//
-// func isValidRole(role account.AccessLevel) bool {
-// 	switch role {
-// 	case account.Admin, account.Employee, account.Guest:
-// 		return true
-// 	default:
-// 		return false
-// 	}
-// }
 
 // minSecretLen is the fallback secret key-length in case configuration did not declare such a length
-var minSecretLen = 32
+const minSecretLen = 32
 
-// New returns a configured instance.
+// New returns a configured instance. HMAC algorithms sign with the secret key; RSA, ECDSA and EdDSA algorithms sign
+// with the private key file and publish its public key, along with any verification keys still accepted while keys
+// are being rotated.
 func New(cfg config.Authentication) (*adapter, error) {
+	minSecretLength := minSecretLen
 	if cfg.MinKeyLength > 0 {
-		minSecretLen = cfg.MinKeyLength
+		minSecretLength = cfg.MinKeyLength
 	}
 
 	signingMethod := jwt.GetSigningMethod(cfg.Algorithm)
@@ -83,40 +86,105 @@ func New(cfg config.Authentication) (*adapter, error) {
 		return &adapter{}, fmt.Errorf("invalid algorithm; given %v without SigningMethod interface implemented",
 			cfg.Algorithm)
 	}
-	secKey := []byte(cfg.SecretKey)
 	period := time.Duration(cfg.ExpirationPeriod) * time.Minute
-	return &adapter{algorithm: signingMethod, expirationPeriod: period, minSecretLength: minSecretLen, secretKey: secKey}, nil
-}
+	a := adapter{algorithm: signingMethod, expirationPeriod: period, minSecretLength: minSecretLength,
+		keyID: cfg.KeyID, verificationKeys: make(map[string]verificationKey), keySet: authentication.KeySet{
+			Keys: []authentication.JSONWebKey{}}}
+
+	if _, ok := signingMethod.(*jwt.SigningMethodHMAC); ok {
+		if len(cfg.SecretKey) < a.minSecretLength {
+			return &adapter{}, fmt.Errorf("secret_key is %d bytes long; %s needs at least %d", len(cfg.SecretKey),
+				cfg.Algorithm, a.minSecretLength)
+		}
+		a.signingKey = []byte(cfg.SecretKey)
+		a.verificationKeys[a.keyID] = verificationKey{signingMethod, a.signingKey}
+	} else {
+		if cfg.PrivateKeyFile == "" {
+			return &adapter{}, fmt.Errorf("%s requires a private_key_file", cfg.Algorithm)
+		}
+		privateKey, err := loadPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return &adapter{}, err
+		}
+		a.signingKey = privateKey
+		if a.keyID, err = a.addVerificationKey(cfg.KeyID, signingMethod, privateKey.Public()); err != nil {
+			return &adapter{}, err
+		}
+		if err := a.probe(); err != nil {
+			return &adapter{}, fmt.Errorf("%s cannot sign with %s: %v", cfg.PrivateKeyFile, cfg.Algorithm, err)
+		}
+	}
 
-// GenerateToken generates a token with claims encoded
-func (s *adapter) GenerateToken(_ context.Context, accountID uuid.UUID, username string, accessLevel int) (string, error) {
+	for _, key := range cfg.VerificationKeys {
+		publicKey, err := loadPublicKey(key.PublicKeyFile)
+		if err != nil {
+			return &adapter{}, err
+		}
+		algorithm := key.Algorithm
+		if algorithm == "" {
+			if algorithm, err = algorithmFor(publicKey); err != nil {
+				return &adapter{}, fmt.Errorf("%s: %v", key.PublicKeyFile, err)
+			}
+		}
+		method := jwt.GetSigningMethod(algorithm)
+		if method == nil {
+			return &adapter{}, fmt.Errorf("%s: unsupported algorithm %v", key.PublicKeyFile, algorithm)
+		}
+		if _, err := a.addVerificationKey(key.KeyID, method, publicKey); err != nil {
+			return &adapter{}, fmt.Errorf("%s: %v", key.PublicKeyFile, err)
+		}
+	}
+	return &a, nil
+}
 
-	exp := time.Now().Add(s.expirationPeriod).Unix()
-	fmt.Printf("accountID: %v", accountID)
-	cstClaims := &claims{
-		AccountID: accountID,
-		Username: username,
-		Role: accessLevel,
-		Expiry:    exp,
+// addVerificationKey accepts tokens signed by the key and publishes it, under its thumbprint unless a key id is
+// given. It returns the key id used.
+func (s *adapter) addVerificationKey(keyID string, method jwt.SigningMethod, key interface{}) (string, error) {
+	jwk, err := jsonWebKey(key, keyID, method.Alg())
+	if err != nil {
+		return "", err
+	}
+	if jwk.KeyID == "" {
+		if jwk.KeyID, err = thumbprint(jwk); err != nil {
+			return "", err
+		}
 	}
+	if _, ok := s.verificationKeys[jwk.KeyID]; ok {
+		return "", fmt.Errorf("key id %q is used twice", jwk.KeyID)
+	}
+	s.verificationKeys[jwk.KeyID] = verificationKey{method, key}
+	s.keySet.Keys = append(s.keySet.Keys, jwk)
+	return jwk.KeyID, nil
+}
 
-	token := jwt.NewWithClaims(s.algorithm, cstClaims)
-	signedToken, err := token.SignedString(s.secretKey)
+// probe signs and verifies a sample with the signing key, to find a key that does not suit the algorithm, e.g. an
+// ECDSA key on the wrong curve, at start up rather than at the first login.
+func (s *adapter) probe() error {
+	signature, err := s.algorithm.Sign("probe", s.signingKey)
 	if err != nil {
-		return signedToken, err
+		return err
 	}
-	return signedToken, err
+	return s.algorithm.Verify("probe", signature, s.verificationKeys[s.keyID].key)
 }
 
-// // parseToken reads the header string and parses the token as encoded by GenerateToken
-// func (s *adapter) parseToken(tokenString string) (*jwt.Token, error) {
-// 	return jwt.ParseWithClaims(tokenString, &claims{}, func(token *jwt.Token) (interface{}, error) {
-// 		if s.algorithm != token.Method {
-// 			return nil, errors.CreateEmployeeAccount("could not decode token; please re-authenticate")
-// 		}
-// 		return s.secretKey, nil
-// 	})
-// }
+// GenerateToken generates a token with claims encoded, setting their expiry unless they already have one
+func (s *adapter) GenerateToken(_ context.Context, customClaims *authentication.CustomClaims) (string, error) {
+	if customClaims.Expiry == 0 {
+		customClaims.Expiry = time.Now().Add(s.expirationPeriod).Unix()
+	}
+	cstClaims := claims(*customClaims)
+
+	token := jwt.NewWithClaims(s.algorithm, &cstClaims)
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
+	}
+	return token.SignedString(s.signingKey)
+}
+
+// KeySet lists the public keys tokens are verified with.
+func (s *adapter) KeySet() authentication.KeySet {
+	return s.keySet
+}
 
 // ExtractToken extracts the token string from the request header
 func (s *adapter) ExtractToken(req *http.Request) (string, error) {
@@ -133,10 +201,19 @@ func (s *adapter) ExtractToken(req *http.Request) (string, error) {
 // ParseTokenClaims extracts the claims as encoded in the token
 func (s *adapter) ParseTokenClaims(tokenString string) (authentication.CustomClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &claims{}, func(token *jwt.Token) (interface{}, error) {
-		if token.Method != s.algorithm {
+		// Tokens without a key id were signed before key ids were introduced, with the signing key.
+		keyID, _ := token.Header["kid"].(string)
+		if keyID == "" {
+			keyID = s.keyID
+		}
+		key, ok := s.verificationKeys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %v", keyID)
+		}
+		if token.Method.Alg() != key.algorithm.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return s.secretKey, nil
+		return key.key, nil
 	})
 	if err != nil {
 		return NullCustomClaims, ErrMalformedToken
@@ -146,8 +223,11 @@ func (s *adapter) ParseTokenClaims(tokenString string) (authentication.CustomCla
 		return NullCustomClaims, err
 	}
 	return authentication.CustomClaims{
-		AccountID: c.AccountID,
-		Username: c.Username,
-		Role: c.Role,
-		Expiry: c.Expiry}, nil
-}
\ No newline at end of file
+		AccountID:       c.AccountID,
+		SessionID:       c.SessionID,
+		Username:        c.Username,
+		Role:            c.Role,
+		Expiry:          c.Expiry,
+		PasswordExpired: c.PasswordExpired,
+		Scopes:          c.Scopes}, nil
+}
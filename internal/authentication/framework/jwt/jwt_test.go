@@ -0,0 +1,32 @@
+package jwt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coquizen/servercarte/internal/config"
+)
+
+func TestNewChecksTheSecretLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		minLength int
+		secret    string
+		wantErr   bool
+	}{
+		{"default minimum met", 0, strings.Repeat("s", 32), false},
+		{"shorter than the default minimum", 0, strings.Repeat("s", 31), true},
+		{"empty secret", 0, "", true},
+		{"configured minimum met", 64, strings.Repeat("s", 64), false},
+		{"shorter than the configured minimum", 64, strings.Repeat("s", 48), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(config.Authentication{Algorithm: "HS256", ExpirationPeriod: 15, MinKeyLength: tt.minLength,
+				SecretKey: tt.secret})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
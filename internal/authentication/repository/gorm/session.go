@@ -0,0 +1,68 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/coquizen/servercarte/domain/authentication"
+)
+
+// SessionRepository represents the client to its persistent repository
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository instantiates an instance for data persistence
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db}
+}
+
+func (r *SessionRepository) CreateSession(_ context.Context, session *authentication.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *SessionRepository) FindSession(_ context.Context, session *authentication.Session) error {
+	return r.db.First(session, session.ID).Error
+}
+
+func (r *SessionRepository) RevokeSession(_ context.Context, sessionID uuid.UUID, at time.Time) error {
+	return r.db.Model(&authentication.Session{}).Where("id = ? AND revoked_at IS NULL", sessionID).Update(
+		"revoked_at", at).Error
+}
+
+func (r *SessionRepository) RevokeAccountSessions(_ context.Context, accountID uuid.UUID, at time.Time) error {
+	return r.db.Model(&authentication.Session{}).Where("account_id = ? AND revoked_at IS NULL", accountID).Update(
+		"revoked_at", at).Error
+}
+
+func (r *SessionRepository) CreateRefreshToken(_ context.Context, token *authentication.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *SessionRepository) FindRefreshToken(_ context.Context, tokenHash string) (*authentication.RefreshToken,
+	error) {
+	var token authentication.RefreshToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return &token, err
+	}
+	return &token, nil
+}
+
+// UseRefreshToken marks the token used in a single conditional update, so that of two requests racing to spend the
+// same token only one succeeds.
+func (r *SessionRepository) UseRefreshToken(_ context.Context, token *authentication.RefreshToken,
+	at time.Time) (bool, error) {
+	result := r.db.Model(&authentication.RefreshToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update(
+		"used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	token.UsedAt = &at
+	return true, nil
+}
@@ -0,0 +1,24 @@
+package ginHTTP
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/authorization"
+)
+
+type rolesHandler struct {
+	authzSvc authorization.Service
+}
+
+// RegisterRoutes sets up the endpoint listing which permissions every role holds, for those allowed to manage
+// accounts.
+func RegisterRoutes(authzSvc authorization.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc) {
+	h := rolesHandler{authzSvc}
+	r.GET("/api/v1/roles", authMiddleWare, authorize(authorization.ManageAccounts), h.listRoles)
+}
+
+func (h *rolesHandler) listRoles(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"data": h.authzSvc.Roles(), "permissions": authorization.Permissions})
+}
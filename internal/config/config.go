@@ -15,6 +15,52 @@ type Security struct {
 	AlphaNum      bool `yaml:"alpha_num" default:"false"`
 	SpecialChar   bool `yaml:"special_char" default:"false"`
 	CheckPrevious bool `yaml:"check_previous" default:"true"`
+	// PreviousCount is how many of an account's latest passwords, its current one included, CheckPrevious turns down.
+	PreviousCount int `yaml:"previous_count" default:"5"`
+	// MaxAgeDays is how old a password may get before it must be changed; zero means it never expires.
+	MaxAgeDays int     `yaml:"max_age_days" default:"0"`
+	Lockout    Lockout `yaml:"lockout"`
+	// HashAlgorithm is bcrypt or argon2id. Hashes made by the other algorithm, or with other parameters, are
+	// replaced as their accounts log in.
+	HashAlgorithm string `yaml:"hash_algorithm" default:"bcrypt"`
+	BcryptCost    int    `yaml:"bcrypt_cost" default:"10"`
+	Argon2        Argon2 `yaml:"argon2"`
+	PIN           PIN    `yaml:"pin"`
+}
+
+// PIN is the quick login staff use on shared devices: a PIN of MinLength to MaxLength digits, typed on a device
+// holding a device API key, is swapped for an access token lasting TokenMinutes that only grants Scopes, by default
+// menu:read_draft and menu:toggle_active, and only those the account's role holds. MaxFailures wrong PINs lock an account's PIN login the way failed logins do,
+// counted apart from them.
+type PIN struct {
+	MinLength    int      `yaml:"min_length" default:"4"`
+	MaxLength    int      `yaml:"max_length" default:"8"`
+	MaxFailures  int      `yaml:"max_failures" default:"5"`
+	TokenMinutes int      `yaml:"token_minutes" default:"15"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// Argon2 are the cost parameters of Argon2id: MemoryKiB of memory, Iterations passes over it and Parallelism lanes,
+// producing a KeyLength byte hash from a SaltLength byte salt.
+type Argon2 struct {
+	MemoryKiB   uint32 `yaml:"memory_kib" default:"65536"`
+	Iterations  uint32 `yaml:"iterations" default:"3"`
+	Parallelism uint8  `yaml:"parallelism" default:"4"`
+	SaltLength  uint32 `yaml:"salt_length" default:"16"`
+	KeyLength   uint32 `yaml:"key_length" default:"32"`
+}
+
+// Lockout throttles failed logins. After each failure a username waits InitialDelaySeconds, then twice as long each
+// time up to MaxDelaySeconds, before it may try again; MaxFailures failures within WindowMinutes lock it for
+// LockoutMinutes. An IP address is locked the same way after MaxFailuresPerIP failures, without delays, so that staff
+// sharing the restaurant's address are not slowed down by one another.
+type Lockout struct {
+	MaxFailures         int `yaml:"max_failures" default:"5"`
+	MaxFailuresPerIP    int `yaml:"max_failures_per_ip" default:"20"`
+	WindowMinutes       int `yaml:"window_minutes" default:"15"`
+	LockoutMinutes      int `yaml:"lockout_minutes" default:"15"`
+	InitialDelaySeconds int `yaml:"initial_delay_seconds" default:"1"`
+	MaxDelaySeconds     int `yaml:"max_delay_seconds" default:"30"`
 }
 
 type Router struct {
@@ -22,6 +68,9 @@ type Router struct {
 	Port                string `yaml:"port,omitempty" default:"8080"`
 	ReadTimeoutSeconds  int    `yaml:"read_timeout_seconds"`
 	WriteTimeoutSeconds int    `yaml:"write_timeout_seconds"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For header is believed
+	// when telling a client's IP address. Without any, the address of the connection is used.
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
 }
 
 type Database struct {
@@ -33,11 +82,118 @@ type Database struct {
 	Name string `yaml:"name,omitempty"`
 }
 
+// Authentication configures the tokens handed out at login. Access tokens last ExpirationPeriod minutes and refresh
+// tokens RefreshExpirationPeriod minutes. HMAC algorithms sign with SecretKey; RSA, ECDSA and EdDSA algorithms sign
+// with the PEM key in PrivateKeyFile, and also accept tokens signed by the VerificationKeys while keys are rotated.
 type Authentication struct {
-	Algorithm        string `yaml:"algorithm"`
-	ExpirationPeriod int    `yaml:"expiration_period"`
-	MinKeyLength     int    `yaml:"minimum_key_length"`
-	SecretKey        string `yaml:"secret_key"`
+	Algorithm               string            `yaml:"algorithm"`
+	ExpirationPeriod        int               `yaml:"expiration_period"`
+	RefreshExpirationPeriod int               `yaml:"refresh_expiration_period"`
+	MinKeyLength            int               `yaml:"minimum_key_length"`
+	SecretKey               string            `yaml:"secret_key"`
+	PrivateKeyFile          string            `yaml:"private_key_file,omitempty"`
+	KeyID                   string            `yaml:"key_id,omitempty"`
+	VerificationKeys        []VerificationKey `yaml:"verification_keys,omitempty"`
+}
+
+// VerificationKey is a public key tokens are still accepted from, e.g. the key that signed them before the last
+// rotation. The algorithm is inferred from the key unless given, and the key id defaults to the key's thumbprint.
+type VerificationKey struct {
+	KeyID         string `yaml:"key_id,omitempty"`
+	Algorithm     string `yaml:"algorithm,omitempty"`
+	PublicKeyFile string `yaml:"public_key_file"`
+}
+
+// Print configures the printable menus. Templates found in TemplateDir replace the built-in templates of the same
+// name.
+type Print struct {
+	TemplateDir    string `yaml:"template_dir,omitempty"`
+	CurrencySymbol string `yaml:"currency_symbol" default:"$"`
+	PageSize       string `yaml:"page_size" default:"Letter"`
+}
+
+// Webhook configures how webhook deliveries are sent and retried. A failed delivery is retried after
+// InitialBackoffSeconds, then after twice as long each time, up to MaxBackoffSeconds, until MaxAttempts were made.
+type Webhook struct {
+	TimeoutSeconds        int  `yaml:"timeout_seconds" default:"10"`
+	MaxAttempts           uint `yaml:"max_attempts" default:"8"`
+	InitialBackoffSeconds int  `yaml:"initial_backoff_seconds" default:"30"`
+	MaxBackoffSeconds     int  `yaml:"max_backoff_seconds" default:"3600"`
+}
+
+// Mail configures how email is sent. The smtp driver sends through Host; the file driver writes each email to
+// Directory, and the log driver logs it, which suits development. ResetURL and VerificationURL are the links put in
+// password reset and email verification emails, with {token} standing in for the token.
+type Mail struct {
+	Driver          string `yaml:"driver" default:"log"`
+	From            string `yaml:"from"`
+	Host            string `yaml:"host,omitempty"`
+	Port            int    `yaml:"port,omitempty" default:"587"`
+	Username        string `yaml:"username,omitempty"`
+	Password        string `yaml:"password,omitempty"`
+	Directory       string `yaml:"directory,omitempty"`
+	ResetURL        string `yaml:"reset_url"`
+	VerificationURL string `yaml:"verification_url"`
+}
+
+// TwoFactor configures TOTP two-factor authentication. Accounts of the RequiredRoles (admin, employee, guest) cannot
+// log in without a code from their authenticator app, and must enroll at their next login; anyone else may opt in.
+// A login challenge may be answered for ChallengePeriod minutes.
+type TwoFactor struct {
+	Issuer          string   `yaml:"issuer" default:"ServerCarte"`
+	RequiredRoles   []string `yaml:"required_roles"`
+	ChallengePeriod int      `yaml:"challenge_period" default:"5"`
+}
+
+// Authorization maps role names (admin, employee, guest) to the permissions they are granted. Roles left out keep
+// their built-in definition.
+type Authorization struct {
+	Roles map[string]Role `yaml:"roles"`
+}
+
+// Role grants a role its permissions directly, and through the roles it inherits from.
+type Role struct {
+	Inherits    []string `yaml:"inherits"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// Tax configures how tax is charged. Sections and items are given one of Categories, which items inherit from the
+// sections above them; items with none fall under DefaultCategory, or are not taxed when it is empty. Each of
+// Locations has its own rates, and quotes and orders use those of Location unless a quote names another. Rounding is
+// half_even (banker's rounding) or half_up, applied to each rate's total, or to each line with RoundPerLine.
+type Tax struct {
+	Location        string                 `yaml:"location"`
+	Categories      []string               `yaml:"categories"`
+	DefaultCategory string                 `yaml:"default_category,omitempty"`
+	Rounding        string                 `yaml:"rounding" default:"half_even"`
+	RoundPerLine    bool                   `yaml:"round_per_line"`
+	Locations       map[string]TaxLocation `yaml:"locations"`
+}
+
+// TaxLocation is a jurisdiction's rates. Mode is exclusive, where tax is added to prices, or inclusive, where prices
+// include it.
+type TaxLocation struct {
+	Mode     string    `yaml:"mode" default:"exclusive"`
+	TimeZone string    `yaml:"time_zone" default:"UTC"`
+	Rates    []TaxRate `yaml:"rates"`
+}
+
+// TaxRate is a percentage charged on a category. It takes effect on the From date and ends before the Until date,
+// both YYYY-MM-DD in the location's time zone, when they are given.
+type TaxRate struct {
+	Name     string  `yaml:"name"`
+	Category string  `yaml:"category"`
+	Percent  float64 `yaml:"percent"`
+	From     string  `yaml:"from,omitempty"`
+	Until    string  `yaml:"until,omitempty"`
+}
+
+// Kitchen configures the prep stations order tickets are routed to. Sections and items are sent to one of Stations,
+// which items inherit from the sections above them; items with none go to DefaultStation, or get no ticket when it is
+// empty.
+type Kitchen struct {
+	Stations       []string `yaml:"stations"`
+	DefaultStation string   `yaml:"default_station,omitempty"`
 }
 
 type config struct {
@@ -45,14 +201,21 @@ type config struct {
 	Server         Router         `yaml:"server"`
 	Security       Security       `yaml:"security"`
 	Authentication Authentication `yaml:"authentication"`
+	Authorization  Authorization  `yaml:"authorization"`
+	Print          Print          `yaml:"print"`
+	Webhook        Webhook        `yaml:"webhook"`
+	Mail           Mail           `yaml:"mail"`
+	TwoFactor      TwoFactor      `yaml:"two_factor"`
+	Tax            Tax            `yaml:"tax"`
+	Kitchen        Kitchen        `yaml:"kitchen"`
 }
 
 // Load loads the configuration from a local .yml into the struct
-func Load(filePath string) (Router, Database, Security, Authentication, error) {
+func Load(filePath string) (Router, Database, Security, Authentication, Authorization, Print, Webhook, Mail, TwoFactor, Tax, Kitchen, error) {
 	var cfg config
 	f, err := os.Open(filePath)
 	if err != nil {
-		return cfg.Server, cfg.Database, cfg.Security, cfg.Authentication, fmt.Errorf("error loading config.yml: %v",
+		return cfg.Server, cfg.Database, cfg.Security, cfg.Authentication, cfg.Authorization, cfg.Print, cfg.Webhook, cfg.Mail, cfg.TwoFactor, cfg.Tax, cfg.Kitchen, fmt.Errorf("error loading config.yml: %v",
 			err)
 	}
 
@@ -61,8 +224,8 @@ func Load(filePath string) (Router, Database, Security, Authentication, error) {
 	decoder := yaml.NewDecoder(f)
 	err = decoder.Decode(&cfg)
 	if err != nil {
-		return cfg.Server, cfg.Database, cfg.Security, cfg.Authentication, err
+		return cfg.Server, cfg.Database, cfg.Security, cfg.Authentication, cfg.Authorization, cfg.Print, cfg.Webhook, cfg.Mail, cfg.TwoFactor, cfg.Tax, cfg.Kitchen, err
 	}
 
-	return cfg.Server, cfg.Database, cfg.Security, cfg.Authentication, nil
+	return cfg.Server, cfg.Database, cfg.Security, cfg.Authentication, cfg.Authorization, cfg.Print, cfg.Webhook, cfg.Mail, cfg.TwoFactor, cfg.Tax, cfg.Kitchen, nil
 }
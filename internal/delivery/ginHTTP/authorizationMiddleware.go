@@ -1,25 +1,76 @@
 package ginHTTP
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/coquizen/servercarte/domain/account"
 	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/authorization"
 )
 
-func AuthorizationMiddleware(accessLevel account.AccessLevel) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		claims, exists := ctx.Get(authentication.CtxAuthenticationKey)
-		if !exists {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+// Authorizer returns the middleware guarding a route with a permission. It must run after the authentication
+// middleware.
+type Authorizer func(authorization.Permission) gin.HandlerFunc
+
+// NewAuthorizer returns an Authorizer that lets through requests whose role holds the permission, unless the
+// account's password has expired. Requests made with an API key, or with a scoped access token, also need to have
+// been granted the permission; API keys have no role to check.
+func NewAuthorizer(authzSvc authorization.Service) Authorizer {
+	return func(permission authorization.Permission) gin.HandlerFunc {
+		return func(ctx *gin.Context) {
+			claims, exists := ctx.Get(authentication.CtxAuthenticationKey)
+			if !exists {
+				ctx.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			if err := check(authzSvc, claims.(authentication.CustomClaims), permission); err != nil {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Next()
 		}
-		if account.AccessLevel(claims.(authentication.CustomClaims).Role) != accessLevel {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+	}
+}
+
+// Permits reports whether the request holds a permission, for handlers that do more for those who hold it rather
+// than turn away those who do not. It must run after the authentication middleware.
+type Permits func(ctx *gin.Context, permission authorization.Permission) bool
+
+// NewPermits returns Permits deciding the way the Authorizer of NewAuthorizer does.
+func NewPermits(authzSvc authorization.Service) Permits {
+	return func(ctx *gin.Context, permission authorization.Permission) bool {
+		claims, exists := ctx.Get(authentication.CtxAuthenticationKey)
+		return exists && check(authzSvc, claims.(authentication.CustomClaims), permission) == nil
+	}
+}
+
+// check returns why the claims do not grant the permission, or nil when they do.
+func check(authzSvc authorization.Service, claims authentication.CustomClaims, permission authorization.Permission) error {
+	if claims.Restricted() && !hasScope(claims.Scopes, permission) {
+		return fmt.Errorf("credentials lack the %s scope", permission)
+	}
+	if claims.APIKeyID != uuid.Nil {
+		return nil
+	}
+	if claims.PasswordExpired {
+		return account.ErrPasswordExpired
+	}
+	role := account.AccessLevel(claims.Role)
+	if !authzSvc.Allowed(role, permission) {
+		return fmt.Errorf("role %s lacks the %s permission", role, permission)
+	}
+	return nil
+}
+
+func hasScope(scopes []string, permission authorization.Permission) bool {
+	for _, scope := range scopes {
+		if scope == string(permission) {
+			return true
 		}
-		ctx.Next()
 	}
-}
\ No newline at end of file
+	return false
+}
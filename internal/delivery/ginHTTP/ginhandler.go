@@ -21,9 +21,19 @@ func NewServer(cfg config.Router, ginHandler *gin.Engine) *http.Server {
 	}
 }
 
+// StreamLimit is how long a streaming response may stay open before the write timeout would cut it off. Zero means
+// there is no write timeout.
+func StreamLimit(cfg config.Router) time.Duration {
+	return time.Duration(cfg.WriteTimeoutSeconds) * time.Second * 9 / 10
+}
+
 // NewHandler instantiates a new Gin engine
 func NewHandler(rCfg config.Router) *gin.Engine {
 	// With logger and recovery middlewares
 	r := gin.Default()
+	// Login throttling counts failures per client address, which a forged X-Forwarded-For header must not change.
+	if err := r.SetTrustedProxies(rCfg.TrustedProxies); err != nil {
+		logger.Error.Panicf("invalid trusted_proxies: %v", err)
+	}
 	return r
-}
\ No newline at end of file
+}
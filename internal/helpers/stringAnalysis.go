@@ -67,3 +67,34 @@ func IsEmailFormat(str string) bool {
 	}
 	return emailRegex.MatchString(str)
 }
+
+// IsDigits checks to see if a string is made of decimal digits only.
+func IsDigits(str string) bool {
+	if str == "" {
+		return false
+	}
+	for _, character := range str {
+		if character < '0' || character > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDigitRun checks to see if a string of digits repeats one digit, or counts up or down one at a time, e.g. 1111,
+// 1234 or 9876.
+func IsDigitRun(str string) bool {
+	if len(str) < 2 {
+		return true
+	}
+	step := int(str[1]) - int(str[0])
+	if step < -1 || step > 1 {
+		return false
+	}
+	for i := 2; i < len(str); i++ {
+		if int(str[i])-int(str[i-1]) != step {
+			return false
+		}
+	}
+	return true
+}
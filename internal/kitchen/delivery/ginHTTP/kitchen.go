@@ -0,0 +1,161 @@
+package ginHTTP
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/authorization"
+	"github.com/coquizen/servercarte/domain/kitchen"
+)
+
+const (
+	// eventStreamRetry is how long displays wait before reconnecting once the event stream closes.
+	eventStreamRetry = time.Second
+	// eventStreamHeartbeat is how often a comment is sent on a quiet stream to keep proxies from closing it.
+	eventStreamHeartbeat = 15 * time.Second
+)
+
+type kitchenHandler struct {
+	kitchenSvc  kitchen.Service
+	streamLimit time.Duration
+}
+
+// RegisterRoutes sets up the kitchen display API endpoints using Gin. Following tickets requires the kitchen:operate
+// permission, which displays are given through API keys. Event streams are closed after streamLimit, unless it is
+// zero, so that they end before the server's write timeout.
+func RegisterRoutes(svc kitchen.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc, streamLimit time.Duration) {
+	h := kitchenHandler{svc, streamLimit}
+	kitchenGroup := r.Group("/api/v1/kitchen", authMiddleWare, authorize(authorization.OperateKitchen))
+	kitchenGroup.GET("/stations", h.listStations)
+	kitchenGroup.GET("/stations/:station/events", h.streamEvents)
+	kitchenGroup.GET("/tickets", h.listTickets)
+	kitchenGroup.GET("/tickets/:id", h.findTicketByID)
+	kitchenGroup.PUT("/tickets/:id/status", h.setStatus)
+}
+
+func (h *kitchenHandler) listStations(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"data": h.kitchenSvc.Stations()})
+}
+
+// listTickets lists the tickets still on display, oldest first, or with ?status= and ?since= those that were, e.g.
+// for reporting on how long they took.
+func (h *kitchenHandler) listTickets(ctx *gin.Context) {
+	var filter kitchen.Filter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tickets, err := h.kitchenSvc.Tickets(ctx, filter)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": tickets})
+}
+
+func (h *kitchenHandler) findTicketByID(ctx *gin.Context) {
+	ticket, err := h.kitchenSvc.TicketByID(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": ticket})
+}
+
+// setStatus moves a ticket on, e.g. {"status": "ready"}.
+func (h *kitchenHandler) setStatus(ctx *gin.Context) {
+	var req kitchen.StatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	ticket, err := h.kitchenSvc.Advance(ctx, ctx.Param("id"), req.Status)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": ticket})
+}
+
+// streamEvents pushes the ticket changes of a station as server-sent events. Displays resume after a dropped
+// connection by sending the last event id they saw in the Last-Event-ID header or the last_event_id query parameter.
+func (h *kitchenHandler) streamEvents(ctx *gin.Context) {
+	rawID := ctx.GetHeader("Last-Event-ID")
+	if rawID == "" {
+		rawID = ctx.Query("last_event_id")
+	}
+	var lastEventID uint64
+	if rawID != "" {
+		id, err := strconv.ParseUint(rawID, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid last event id %q", rawID)})
+			return
+		}
+		lastEventID = id
+	}
+
+	streamCtx := ctx.Request.Context()
+	if h.streamLimit > 0 {
+		var cancel context.CancelFunc
+		streamCtx, cancel = context.WithTimeout(streamCtx, h.streamLimit)
+		defer cancel()
+	}
+	events, err := h.kitchenSvc.Events(streamCtx, ctx.Param("station"), lastEventID)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	if _, err := fmt.Fprintf(ctx.Writer, "retry: %d\n\n", eventStreamRetry.Milliseconds()); err != nil {
+		return
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type,
+				data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// statusFor maps an error from the kitchen service to the status code it is answered with.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, kitchen.ErrTicketNotFound), errors.Is(err, kitchen.ErrUnknownStation):
+		return http.StatusNotFound
+	case errors.Is(err, kitchen.ErrInvalidTransition), errors.Is(err, kitchen.ErrTicketVoided):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,95 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coquizen/servercarte/domain/kitchen"
+)
+
+// historySize is how many past events are kept for listeners resuming after a dropped connection.
+const historySize = 1024
+
+// subscriberBuffer is how many undelivered events a listener may fall behind by before it is dropped.
+const subscriberBuffer = 64
+
+// bus is an in-memory kitchen.EventBus. Event IDs are shared by every station and start over when the process
+// restarts, which listeners resuming from an ID the bus has not reached yet are told about with a reset.
+type bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []kitchen.Event
+	subscribers map[chan kitchen.Event]string
+}
+
+// New returns an empty event bus.
+func New() *bus {
+	return &bus{subscribers: make(map[chan kitchen.Event]string)}
+}
+
+// Publish records the event and hands it to every listener of its station. Listeners whose buffer is full are
+// dropped rather than holding up the change that published the event.
+func (b *bus) Publish(event kitchen.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	event.At = time.Now().UTC()
+	if len(b.history) == historySize {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:historySize-1]
+	}
+	b.history = append(b.history, event)
+
+	for subscriber, station := range b.subscribers {
+		if station != event.Station {
+			continue
+		}
+		select {
+		case subscriber <- event:
+		default:
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Subscribe replays the station's events after lastEventID and then delivers new ones until ctx is done. A
+// lastEventID of zero starts from the next event.
+func (b *bus) Subscribe(ctx context.Context, station string, lastEventID uint64) (<-chan kitchen.Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []kitchen.Event
+	if lastEventID > 0 && lastEventID != b.lastID {
+		if lastEventID > b.lastID || len(b.history) == 0 || lastEventID+1 < b.history[0].ID {
+			replay = []kitchen.Event{{ID: b.lastID, Type: kitchen.KitchenReset, At: time.Now().UTC(),
+				Station: station}}
+		} else {
+			for _, event := range b.history[len(b.history)-int(b.lastID-lastEventID):] {
+				if event.Station == station {
+					replay = append(replay, event)
+				}
+			}
+		}
+	}
+
+	subscriber := make(chan kitchen.Event, len(replay)+subscriberBuffer)
+	for _, event := range replay {
+		subscriber <- event
+	}
+	b.subscribers[subscriber] = station
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[subscriber]; ok {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}()
+	return subscriber, nil
+}
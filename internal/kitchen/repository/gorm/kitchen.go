@@ -0,0 +1,108 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/coquizen/servercarte/domain/kitchen"
+)
+
+// kitchenRepository represents the client to its persistent repository
+type kitchenRepository struct {
+	db *gorm.DB
+}
+
+// NewKitchenRepository instantiates an instance for data persistence
+func NewKitchenRepository(db *gorm.DB) *kitchenRepository {
+	return &kitchenRepository{db}
+}
+
+func (r *kitchenRepository) CreateTickets(_ context.Context, orderID uuid.UUID, tickets []kitchen.Ticket) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var routed int64
+		if err := tx.Model(&kitchen.RoutedOrder{}).Where("order_id = ?", orderID).Count(&routed).Error; err != nil {
+			return err
+		}
+		if routed > 0 {
+			return kitchen.ErrAlreadyRouted
+		}
+		if err := tx.Create(&kitchen.RoutedOrder{OrderID: orderID, RoutedAt: time.Now().UTC()}).Error; err != nil {
+			return err
+		}
+		for i := range tickets {
+			if err := tx.Create(&tickets[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RoutedOrders also counts orders with tickets as routed, as tickets were stored before routed orders were recorded.
+func (r *kitchenRepository) RoutedOrders(_ context.Context, orderIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	routed := make(map[uuid.UUID]bool, len(orderIDs))
+	var ids []uuid.UUID
+	if err := r.db.Model(&kitchen.RoutedOrder{}).Where("order_id IN ?", orderIDs).Pluck("order_id",
+		&ids).Error; err != nil {
+		return routed, err
+	}
+	var ticketed []uuid.UUID
+	if err := r.db.Model(&kitchen.Ticket{}).Where("order_id IN ?", orderIDs).Distinct().Pluck("order_id",
+		&ticketed).Error; err != nil {
+		return routed, err
+	}
+	for _, id := range append(ids, ticketed...) {
+		routed[id] = true
+	}
+	return routed, nil
+}
+
+// ListTickets lists tickets along with their items, oldest first, so that displays show them in the order they came
+// in.
+func (r *kitchenRepository) ListTickets(_ context.Context, filter kitchen.Filter, limit int) ([]kitchen.Ticket, error) {
+	query := r.db.Preload("Items")
+	if filter.Station != "" {
+		query = query.Where("station = ?", filter.Station)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	} else {
+		query = query.Where("status <> ? AND voided_at IS NULL", kitchen.Bumped)
+	}
+	if filter.Since != nil {
+		query = query.Where("received_at >= ?", *filter.Since)
+	}
+	var tickets []kitchen.Ticket
+	if err := query.Order("received_at").Limit(limit).Find(&tickets).Error; err != nil {
+		return tickets, err
+	}
+	return tickets, nil
+}
+
+func (r *kitchenRepository) OrderTickets(_ context.Context, orderID uuid.UUID) ([]kitchen.Ticket, error) {
+	var tickets []kitchen.Ticket
+	if err := r.db.Preload("Items").Where("order_id = ?", orderID).Order("received_at").Find(
+		&tickets).Error; err != nil {
+		return tickets, err
+	}
+	return tickets, nil
+}
+
+func (r *kitchenRepository) FindTicket(_ context.Context, ticket *kitchen.Ticket) error {
+	if err := r.db.Preload("Items").First(ticket, ticket.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return kitchen.ErrTicketNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *kitchenRepository) UpdateTicket(_ context.Context, ticket *kitchen.Ticket) error {
+	return r.db.Model(ticket).Select("status", "started_at", "ready_at", "bumped_at", "voided_at").Updates(
+		ticket).Error
+}
@@ -0,0 +1,75 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/coquizen/servercarte/domain/kitchen"
+)
+
+func TestCreateTicketsRoutesAnOrderOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:kitchen?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&kitchen.Ticket{}, &kitchen.TicketItem{}, &kitchen.RoutedOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewKitchenRepository(db)
+	ctx := context.Background()
+	ticket := func(orderID uuid.UUID) []kitchen.Ticket {
+		return []kitchen.Ticket{{OrderID: orderID, Station: "grill", Status: kitchen.New, ReceivedAt: time.Now()}}
+	}
+
+	routed, unrouted, legacy, empty := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	if err := repo.CreateTickets(ctx, routed, ticket(routed)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateTickets(ctx, routed, ticket(routed)); err != kitchen.ErrAlreadyRouted {
+		t.Errorf("routing the order again: error = %v, want %v", err, kitchen.ErrAlreadyRouted)
+	}
+	// Orders routed before routed orders were recorded only have their tickets.
+	if err := db.Create(&ticket(legacy)[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Orders without anything for the kitchen are routed all the same.
+	if err := repo.CreateTickets(ctx, empty, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tickets, err := repo.OrderTickets(ctx, routed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 1 {
+		t.Errorf("order has %d tickets, want 1", len(tickets))
+	}
+
+	got, err := repo.RoutedOrders(ctx, []uuid.UUID{routed, unrouted, legacy, empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		orderID uuid.UUID
+		want    bool
+	}{
+		{"routed", routed, true},
+		{"not routed", unrouted, false},
+		{"ticketed before routing was recorded", legacy, true},
+		{"routed without tickets", empty, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got[tt.orderID] != tt.want {
+				t.Errorf("routed = %v, want %v", got[tt.orderID], tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,25 @@
 package logger
 
 import (
+	"fmt"
 	"log"
 	"os"
 )
 
 var (
-	Info = log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
+	Info  = log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	Error = log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 )
 
+// Domain writes what the domain services report to Info and Error, as a domain.Logger.
+var Domain = domainLogger{}
+
+type domainLogger struct{}
+
+func (domainLogger) Infof(format string, v ...interface{}) {
+	_ = Info.Output(2, fmt.Sprintf(format, v...))
+}
+
+func (domainLogger) Errorf(format string, v ...interface{}) {
+	_ = Error.Output(2, fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,53 @@
+package filemailer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/coquizen/servercarte/domain/mail"
+	"github.com/coquizen/servercarte/internal/config"
+	"github.com/coquizen/servercarte/internal/logger"
+	"github.com/coquizen/servercarte/internal/mail/framework/smtpmailer"
+)
+
+// mailer writes each email to a file in a directory instead of sending it, or to the log when there is no directory.
+// It is meant for development and tests.
+type mailer struct {
+	directory string
+	from      string
+	sent      uint64
+}
+
+// New returns a mailer writing to the configured directory, creating it if need be.
+func New(cfg config.Mail) (*mailer, error) {
+	if cfg.Directory != "" {
+		if err := os.MkdirAll(cfg.Directory, 0o700); err != nil {
+			return &mailer{}, err
+		}
+	}
+	from := cfg.From
+	if from == "" {
+		from = "servercarte@localhost"
+	}
+	return &mailer{directory: cfg.Directory, from: from}, nil
+}
+
+// Send writes the message as an .eml file named after the time it was sent.
+func (m *mailer) Send(_ context.Context, message mail.Message) error {
+	if err := message.Validate(); err != nil {
+		return err
+	}
+	email := smtpmailer.Compose(m.from, message)
+	if m.directory == "" {
+		logger.Info.Printf("mail to %s:\n%s", message.To, email)
+		return nil
+	}
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405.000000000"),
+		atomic.AddUint64(&m.sent, 1))
+	return ioutil.WriteFile(filepath.Join(m.directory, name), email, 0o600)
+}
@@ -0,0 +1,66 @@
+package smtpmailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/coquizen/servercarte/domain/mail"
+	"github.com/coquizen/servercarte/internal/config"
+)
+
+// defaultPort is the SMTP submission port, which SendMail upgrades with STARTTLS when the server offers it.
+const defaultPort = 587
+
+// mailer sends email through an SMTP server.
+type mailer struct {
+	address string
+	from    string
+	auth    smtp.Auth
+}
+
+// New returns a mailer for the configured SMTP server, authenticating when a username is set.
+func New(cfg config.Mail) (*mailer, error) {
+	if cfg.Host == "" {
+		return &mailer{}, fmt.Errorf("smtp mailer requires a host")
+	}
+	if cfg.From == "" {
+		return &mailer{}, fmt.Errorf("smtp mailer requires a from address")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	m := mailer{address: net.JoinHostPort(cfg.Host, strconv.Itoa(port)), from: cfg.From}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return &m, nil
+}
+
+// Send delivers the message. The context is not consulted, since net/smtp offers no way to cancel a send.
+func (m *mailer) Send(_ context.Context, message mail.Message) error {
+	if err := message.Validate(); err != nil {
+		return err
+	}
+	return smtp.SendMail(m.address, m.auth, m.from, []string{message.To}, Compose(m.from, message))
+}
+
+// Compose renders the message as an RFC 5322 email.
+func Compose(from string, message mail.Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.Write(bytes.ReplaceAll([]byte(message.Body), []byte("\n"), []byte("\r\n")))
+	return buf.Bytes()
+}
@@ -0,0 +1,86 @@
+package ginHTTP
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+// documentFormat picks JSON or YAML from the "format" query parameter, falling back on the request's content type.
+func documentFormat(ctx *gin.Context) (string, error) {
+	format := strings.ToLower(ctx.Query("format"))
+	if format == "" {
+		format = "json"
+		if strings.Contains(ctx.ContentType(), "yaml") {
+			format = "yaml"
+		}
+	}
+	switch format {
+	case "json", "yaml":
+		return format, nil
+	case "yml":
+		return "yaml", nil
+	default:
+		return "", fmt.Errorf("unsupported format %q; expected json or yaml", format)
+	}
+}
+
+// exportMenus downloads the draft menus as a document that can be imported again.
+func (h *menuHandler) exportMenus(ctx *gin.Context) {
+	format, err := documentFormat(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doc, err := h.menuSvc.Export(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=menus.%s", format))
+	if format == "yaml" {
+		ctx.YAML(http.StatusOK, doc)
+		return
+	}
+	ctx.IndentedJSON(http.StatusOK, doc)
+}
+
+// importMenus loads a document into the draft menus. The "mode" query parameter is either merge (the default) or
+// replace, and "dry_run" reports the changes without applying them.
+func (h *menuHandler) importMenus(ctx *gin.Context) {
+	format, err := documentFormat(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	var doc menu.Document
+	if format == "yaml" {
+		err = ctx.ShouldBindYAML(&doc)
+	} else {
+		err = ctx.ShouldBindJSON(&doc)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mode := menu.ImportMode(strings.ToLower(ctx.DefaultQuery("mode", string(menu.MergeImport))))
+	report, err := h.menuSvc.Import(ctx, &doc, mode, dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": report})
+}
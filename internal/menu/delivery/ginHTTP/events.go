@@ -0,0 +1,85 @@
+package ginHTTP
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventStreamRetry is how long browsers wait before reconnecting once the event stream closes.
+	eventStreamRetry = time.Second
+	// eventStreamHeartbeat is how often a comment is sent on a quiet stream to keep proxies from closing it.
+	eventStreamHeartbeat = 15 * time.Second
+)
+
+// streamEvents pushes menu changes as server-sent events. Clients resume after a dropped connection by sending the
+// last event id they saw in the Last-Event-ID header, which browsers do on their own, or in the last_event_id query
+// parameter. The stream is closed before the server's write timeout would cut it off, and clients reconnect from
+// where they left off.
+func (h *menuHandler) streamEvents(ctx *gin.Context) {
+	rawID := ctx.GetHeader("Last-Event-ID")
+	if rawID == "" {
+		rawID = ctx.Query("last_event_id")
+	}
+	var lastEventID uint64
+	if rawID != "" {
+		id, err := strconv.ParseUint(rawID, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid last event id %q", rawID)})
+			return
+		}
+		lastEventID = id
+	}
+
+	streamCtx := ctx.Request.Context()
+	if h.streamLimit > 0 {
+		var cancel context.CancelFunc
+		streamCtx, cancel = context.WithTimeout(streamCtx, h.streamLimit)
+		defer cancel()
+	}
+	events, err := h.menuSvc.Events(streamCtx, lastEventID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	if _, err := fmt.Fprintf(ctx.Writer, "retry: %d\n\n", eventStreamRetry.Milliseconds()); err != nil {
+		return
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type,
+				data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
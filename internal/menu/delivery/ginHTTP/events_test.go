@@ -0,0 +1,81 @@
+package ginHTTP
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+// eventService streams the events it is given and remembers where the listener asked to resume from. The rest of
+// menu.Service is left unimplemented.
+type eventService struct {
+	menu.Service
+	events      []menu.Event
+	lastEventID uint64
+}
+
+func (s *eventService) Events(_ context.Context, lastEventID uint64) (<-chan menu.Event, error) {
+	s.lastEventID = lastEventID
+	events := make(chan menu.Event, len(s.events))
+	for _, event := range s.events {
+		events <- event
+	}
+	close(events)
+	return events, nil
+}
+
+func TestStreamEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	itemID := uuid.New()
+	tests := []struct {
+		name         string
+		header       string
+		query        string
+		wantStatus   int
+		wantResumeAt uint64
+	}{
+		{"from the next event", "", "", http.StatusOK, 0},
+		{"resume from the header", "41", "", http.StatusOK, 41},
+		{"resume from the query", "", "?last_event_id=12", http.StatusOK, 12},
+		{"header wins over the query", "41", "?last_event_id=12", http.StatusOK, 41},
+		{"malformed event id", "latest", "", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &eventService{events: []menu.Event{{ID: 42, Type: menu.ItemUpdated, EntityID: &itemID}}}
+			h := menuHandler{menuSvc: svc}
+			r := gin.New()
+			r.GET("/menus/events", h.streamEvents)
+
+			req := httptest.NewRequest(http.MethodGet, "/menus/events"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if svc.lastEventID != tt.wantResumeAt {
+				t.Errorf("resumed from %d, want %d", svc.lastEventID, tt.wantResumeAt)
+			}
+			if got := w.Header().Get("Content-Type"); got != "text/event-stream" {
+				t.Errorf("Content-Type = %q, want text/event-stream", got)
+			}
+			if body := w.Body.String(); !strings.Contains(body, "id: 42\nevent: item.updated\ndata: {") {
+				t.Errorf("stream does not carry the event:\n%s", body)
+			}
+		})
+	}
+}
@@ -1,58 +1,231 @@
 package ginHTTP
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/authorization"
 	"github.com/coquizen/servercarte/domain/menu"
 )
 
 type menuHandler struct {
-	menuSvc menu.Service
-	authSvc authentication.Service
+	menuSvc     menu.Service
+	authSvc     authentication.Service
+	streamLimit time.Duration
 }
 
-// RegisterRoutes sets up menu API endpoint using Gin has the delivery.
-func RegisterRoutes(svc menu.Service, authSvc authentication.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorizationMiddleWare gin.HandlerFunc) {
-	h := menuHandler{svc, authSvc}
+// RegisterRoutes sets up menu API endpoint using Gin has the delivery. Private routes are guarded by the permission
+// authorize is given for each. Event streams are closed after streamLimit, unless it is zero, so that they end before
+// the server's write timeout.
+func RegisterRoutes(svc menu.Service, authSvc authentication.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc, streamLimit time.Duration) {
+	h := menuHandler{svc, authSvc, streamLimit}
 	publicRoutes(r, &h)
-	privateRoutes(r, &h, authMiddleWare, authorizationMiddleWare)
+	privateRoutes(r, &h, authMiddleWare, authorize)
 }
 
 func publicRoutes(r *gin.Engine, h *menuHandler) {
 	menuGroup := r.Group("/api/v1")
 	menuViewGroup := menuGroup.Group("")
 	menuViewGroup.GET("/menus", h.listMenus)
-	menuViewGroup.GET("/sections", h.listSections)
-	menuViewGroup.GET("/sections/:id", h.findSectionByID)
-	menuViewGroup.GET("/items", h.listItems)
-	menuViewGroup.GET("/items/:id", h.findItemByID)
+	menuViewGroup.GET("/menus/:id/print", h.printMenu)
 }
 
-func privateRoutes(r *gin.Engine, h *menuHandler, authMiddleWare, authorizationMiddleware gin.HandlerFunc) {
-	menuEditGroup := r.Group("/api/v1", authMiddleWare, authorizationMiddleware)
-	menuEditGroup.POST("/sections", h.createSection)
-	menuEditGroup.PATCH("/sections/:id", h.updateSection)
-	menuEditGroup.DELETE("/sections/:id", h.deleteSection)
-	menuEditGroup.POST("/items", h.createItem)
-	menuEditGroup.PATCH("/items/:id", h.updateItem)
-	menuEditGroup.DELETE("/items/:id", h.deleteItem)
+func privateRoutes(r *gin.Engine, h *menuHandler, authMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc) {
+	readDraft := authorize(authorization.ReadDraftMenu)
+	write := authorize(authorization.WriteMenu)
+	// Routes that take a price need the price permission on top of the write permission.
+	writePrice := authorize(authorization.WritePrice)
+	toggleActive := authorize(authorization.ToggleActive)
+	publish := authorize(authorization.PublishMenu)
+
+	menuEditGroup := r.Group("/api/v1", authMiddleWare)
+	// Sections, items and tags, and the events telling of changes to them, are read from the draft, so reading them is
+	// reserved to those who may see it; the public reads the published menus.
+	menuEditGroup.GET("/menus/draft", readDraft, h.listDraftMenus)
+	menuEditGroup.GET("/menus/events", readDraft, h.streamEvents)
+	menuEditGroup.GET("/sections", readDraft, h.listSections)
+	menuEditGroup.GET("/sections/:id", readDraft, h.findSectionByID)
+	menuEditGroup.GET("/items", readDraft, h.listItems)
+	menuEditGroup.GET("/items/:id", readDraft, h.findItemByID)
+	menuEditGroup.GET("/items/:id/modifiers", readDraft, h.listModifierGroups)
+	menuEditGroup.GET("/items/:id/variants", readDraft, h.listVariants)
+	menuEditGroup.GET("/tags", readDraft, h.listTags)
+	menuEditGroup.POST("/menus/publish", publish, h.publishMenus)
+	menuEditGroup.GET("/menus/versions", readDraft, h.listVersions)
+	menuEditGroup.GET("/menus/versions/:version", readDraft, h.findVersion)
+	menuEditGroup.POST("/menus/versions/:version/rollback", publish, h.rollbackVersion)
+	menuEditGroup.GET("/menus/diff", readDraft, h.diffVersions)
+	menuEditGroup.GET("/menus/export", readDraft, h.exportMenus)
+	menuEditGroup.POST("/menus/import", write, writePrice, h.importMenus)
+	menuEditGroup.GET("/menus/prices", readDraft, h.exportPriceSheet)
+	menuEditGroup.POST("/menus/prices", writePrice, h.importPriceSheet)
+	menuEditGroup.POST("/sections", write, h.createSection)
+	menuEditGroup.PATCH("/sections/:id", write, h.updateSection)
+	menuEditGroup.PUT("/sections/:id/active", toggleActive, h.setSectionActive)
+	menuEditGroup.PUT("/sections/:id/tax-category", write, h.setSectionTaxCategory)
+	menuEditGroup.PUT("/sections/:id/station", write, h.setSectionStation)
+	menuEditGroup.DELETE("/sections/:id", write, h.deleteSection)
+	menuEditGroup.POST("/items", write, writePrice, h.createItem)
+	menuEditGroup.PATCH("/items/:id", write, writePrice, h.updateItem)
+	menuEditGroup.PUT("/items/:id/active", toggleActive, h.setItemActive)
+	menuEditGroup.PUT("/items/:id/tax-category", write, h.setItemTaxCategory)
+	menuEditGroup.PUT("/items/:id/station", write, h.setItemStation)
+	menuEditGroup.DELETE("/items/:id", write, h.deleteItem)
+	menuEditGroup.POST("/items/:id/modifiers", write, writePrice, h.createModifierGroup)
+	menuEditGroup.PATCH("/modifiers/:id", write, writePrice, h.updateModifierGroup)
+	menuEditGroup.DELETE("/modifiers/:id", write, h.deleteModifierGroup)
+	menuEditGroup.POST("/items/:id/variants", write, writePrice, h.createVariant)
+	menuEditGroup.PATCH("/items/:id/variants/:variant_id", write, writePrice, h.updateVariant)
+	menuEditGroup.DELETE("/items/:id/variants/:variant_id", write, h.deleteVariant)
+	menuEditGroup.POST("/sections/:id/availability", write, h.createSectionAvailability)
+	menuEditGroup.POST("/items/:id/availability", write, h.createItemAvailability)
+	menuEditGroup.PATCH("/availability/:id", write, h.updateAvailability)
+	menuEditGroup.DELETE("/availability/:id", write, h.deleteAvailability)
+	menuEditGroup.POST("/tags", write, h.createTag)
+	menuEditGroup.PATCH("/tags/:id", write, h.updateTag)
+	menuEditGroup.DELETE("/tags/:id", write, h.deleteTag)
+	menuEditGroup.PUT("/items/:id/tags", write, h.tagItem)
+	menuEditGroup.PUT("/sections/:id/tags", write, h.tagSection)
 }
 
 // ---   Menus  --- //
 func (h *menuHandler) listMenus(ctx *gin.Context) {
-	menus, err := h.menuSvc.Menus(ctx)
+	filter, err := menuFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	menus, err := h.menuSvc.PublishedMenus(ctx, filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": menus})
+
+}
+
+// listDraftMenus lets editors preview the unpublished menus.
+func (h *menuHandler) listDraftMenus(ctx *gin.Context) {
+	filter, err := menuFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	menus, err := h.menuSvc.Menus(ctx, filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": menus})
+}
+
+type publishRequest struct {
+	Note string `json:"note"`
+}
+
+// publishMenus publishes the draft as the next version of the menus.
+func (h *menuHandler) publishMenus(ctx *gin.Context) {
+	var req publishRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && ctx.Request.ContentLength > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot, err := h.menuSvc.Publish(ctx, currentAccountID(ctx), req.Note)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": snapshot})
+}
+
+func (h *menuHandler) listVersions(ctx *gin.Context) {
+	snapshots, err := h.menuSvc.Snapshots(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	ctx.JSON(http.StatusOK, gin.H{"data": snapshots})
+}
 
+func (h *menuHandler) findVersion(ctx *gin.Context) {
+	version, err := parseVersion(ctx.Param("version"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	menus, err := h.menuSvc.SnapshotMenus(ctx, version)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{"data": menus})
+}
+
+// rollbackVersion republishes an earlier version as the newest one.
+func (h *menuHandler) rollbackVersion(ctx *gin.Context) {
+	version, err := parseVersion(ctx.Param("version"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	snapshot, err := h.menuSvc.Rollback(ctx, version, currentAccountID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": snapshot})
+}
+
+// diffVersions compares the versions given by the "from" and "to" query parameters.
+func (h *menuHandler) diffVersions(ctx *gin.Context) {
+	from, err := parseVersion(ctx.Query("from"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := parseVersion(ctx.Query("to"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	diff, err := h.menuSvc.DiffSnapshots(ctx, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+func parseVersion(raw string) (uint, error) {
+	version, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("invalid menu version %q", raw)
+	}
+	return uint(version), nil
+}
 
+// currentAccountID returns the ID of the authenticated account making the request, if any; requests made with an
+// API key have none.
+func currentAccountID(ctx *gin.Context) *uuid.UUID {
+	claims, exists := ctx.Get(authentication.CtxAuthenticationKey)
+	if !exists {
+		return nil
+	}
+	accountID := claims.(authentication.CustomClaims).AccountID
+	if accountID == uuid.Nil {
+		return nil
+	}
+	return &accountID
 }
 
 // --- Sections --- //
@@ -81,23 +254,23 @@ func (h *menuHandler) findSectionByID(ctx *gin.Context) {
 }
 
 type newSectionRequest struct {
-	Title       string  `json:"title"`
-	Description *string `json:"description,omitempty"`
-	Active      bool    `json:"active"`
-	Visible     bool    `json:"visible"`
-	Type        menu.SectionType     `json:"type"`
-	ListOrder   uint    `json:"list_order"`
-	SectionID   *string `json:"section_id,omitempty"`
+	Title       string           `json:"title"`
+	Description *string          `json:"description,omitempty"`
+	Active      bool             `json:"active"`
+	Visible     bool             `json:"visible"`
+	Type        menu.SectionType `json:"type"`
+	ListOrder   uint             `json:"list_order"`
+	SectionID   *string          `json:"section_id,omitempty"`
 }
 
 type updateSectionRequest struct {
-	ID          *string `json:"id,omitempty"`
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Active      *bool   `json:"active,omitempty"`
-	Visible     *bool   `json:"visible,omitempty"`
-	Type        *menu.SectionType    `json:"type,omitempty"`
-	ListOrder   *uint   `json:"list_order,omitempty"`
+	ID          *string           `json:"id,omitempty"`
+	Title       *string           `json:"title,omitempty"`
+	Description *string           `json:"description,omitempty"`
+	Active      *bool             `json:"active,omitempty"`
+	Visible     *bool             `json:"visible,omitempty"`
+	Type        *menu.SectionType `json:"type,omitempty"`
+	ListOrder   *uint             `json:"list_order,omitempty"`
 }
 
 // createSection creates a new section.
@@ -155,7 +328,7 @@ func (h *menuHandler) updateSection(ctx *gin.Context) {
 		Description: updatedSection.Description,
 		Active:      *updatedSection.Active,
 		Visible:     *updatedSection.Visible,
-		Type:		 *updatedSection.Type,
+		Type:        *updatedSection.Type,
 		ListOrder:   *updatedSection.ListOrder,
 	}
 	section.ID = id
@@ -167,6 +340,65 @@ func (h *menuHandler) updateSection(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"data": section})
 }
 
+type activeRequest struct {
+	Active *bool `json:"active" binding:"required"`
+}
+
+// setSectionActive activates or deactivates a section, leaving the rest of it as it is.
+func (h *menuHandler) setSectionActive(ctx *gin.Context) {
+	var req activeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	section, err := h.menuSvc.SetSectionActive(ctx, ctx.Param("id"), *req.Active)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": section})
+}
+
+// taxCategoryRequest gives a section or an item a tax category, or takes it away with null so that it inherits one.
+type taxCategoryRequest struct {
+	TaxCategory *string `json:"tax_category"`
+}
+
+// setSectionTaxCategory sets the tax category items beneath a section fall under unless they have their own.
+func (h *menuHandler) setSectionTaxCategory(ctx *gin.Context) {
+	var req taxCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	section, err := h.menuSvc.SetSectionTaxCategory(ctx, ctx.Param("id"), req.TaxCategory)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": section})
+}
+
+// stationRequest sends a section or an item to a prep station, or takes it away with null so that it inherits one.
+type stationRequest struct {
+	Station *string `json:"station"`
+}
+
+// setSectionStation sets the prep station items beneath a section are sent to unless they have their own.
+func (h *menuHandler) setSectionStation(ctx *gin.Context) {
+	var req stationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	section, err := h.menuSvc.SetSectionStation(ctx, ctx.Param("id"), req.Station)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": section})
+}
+
 func (h *menuHandler) deleteSection(ctx *gin.Context) {
 	rawID := ctx.Param("id")
 	if err := h.menuSvc.DeleteSection(ctx, rawID); err != nil {
@@ -190,23 +422,23 @@ func (h *menuHandler) listItems(ctx *gin.Context) {
 }
 
 type newItemRequest struct {
-	Title       string  `json:"title"`
-	Description *string `json:"description,omitempty"`
-	Active      bool    `json:"active"`
-	Type        menu.ItemType     `json:"type"`
-	ListOrder   uint    `json:"list_order"`
-	Price       uint64  `json:"visible"`
-	SectionID   string  `json:"section_id"`
+	Title       string        `json:"title"`
+	Description *string       `json:"description,omitempty"`
+	Active      bool          `json:"active"`
+	Type        menu.ItemType `json:"type"`
+	ListOrder   uint          `json:"list_order"`
+	Price       uint64        `json:"visible"`
+	SectionID   string        `json:"section_id"`
 }
 
 type updateItemRequest struct {
-	ID          *string `json:"id,omitempty"`
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Active      *bool   `json:"active,omitempty"`
-	Type        *menu.ItemType   `json:"type,omitempty"`
-	ListOrder   *uint   `json:"list_order,omitempty"`
-	Price       *uint64 `json:"visible,omitempty"`
+	ID          *string        `json:"id,omitempty"`
+	Title       *string        `json:"title,omitempty"`
+	Description *string        `json:"description,omitempty"`
+	Active      *bool          `json:"active,omitempty"`
+	Type        *menu.ItemType `json:"type,omitempty"`
+	ListOrder   *uint          `json:"list_order,omitempty"`
+	Price       *uint64        `json:"visible,omitempty"`
 }
 
 // createSection creates a new section.
@@ -273,6 +505,52 @@ func (h *menuHandler) updateItem(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"data": item})
 }
+
+// setItemActive activates or deactivates an item, e.g. to mark it sold out, leaving the rest of it as it is.
+func (h *menuHandler) setItemActive(ctx *gin.Context) {
+	var req activeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.menuSvc.SetItemActive(ctx, ctx.Param("id"), *req.Active)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// setItemTaxCategory sets the tax category of an item.
+func (h *menuHandler) setItemTaxCategory(ctx *gin.Context) {
+	var req taxCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.menuSvc.SetItemTaxCategory(ctx, ctx.Param("id"), req.TaxCategory)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// setItemStation sets the prep station of an item.
+func (h *menuHandler) setItemStation(ctx *gin.Context) {
+	var req stationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.menuSvc.SetItemStation(ctx, ctx.Param("id"), req.Station)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": item})
+}
+
 func (h *menuHandler) findItemByID(ctx *gin.Context) {
 	rawID := ctx.Param("id")
 	item, err := h.menuSvc.ItemByID(ctx, rawID)
@@ -294,3 +572,436 @@ func (h *menuHandler) deleteItem(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "item deleted"})
 
 }
+
+// --- Modifier Groups --- //
+func (h *menuHandler) listModifierGroups(ctx *gin.Context) {
+	rawID := ctx.Param("id")
+	groups, err := h.menuSvc.ModifierGroups(ctx, rawID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": groups})
+}
+
+type modifierOptionRequest struct {
+	ID         *string `json:"id,omitempty"`
+	Title      string  `json:"title"`
+	PriceDelta int64   `json:"price_delta"`
+	Default    bool    `json:"default"`
+	Active     *bool   `json:"active,omitempty"`
+	ListOrder  uint    `json:"list_order"`
+}
+
+type modifierGroupRequest struct {
+	Title         string                  `json:"title"`
+	MinSelections uint                    `json:"min_selections"`
+	MaxSelections uint                    `json:"max_selections"`
+	ListOrder     uint                    `json:"list_order"`
+	Options       []modifierOptionRequest `json:"options"`
+}
+
+func (r *modifierGroupRequest) unwrap() (menu.ModifierGroup, error) {
+	group := menu.ModifierGroup{
+		Title:         r.Title,
+		MinSelections: r.MinSelections,
+		MaxSelections: r.MaxSelections,
+		ListOrder:     r.ListOrder,
+	}
+	for _, reqOption := range r.Options {
+		option := menu.ModifierOption{
+			Title:      reqOption.Title,
+			PriceDelta: reqOption.PriceDelta,
+			IsDefault:  reqOption.Default,
+			Active:     true,
+			ListOrder:  reqOption.ListOrder,
+		}
+		if reqOption.Active != nil {
+			option.Active = *reqOption.Active
+		}
+		if reqOption.ID != nil {
+			id, err := uuid.Parse(*reqOption.ID)
+			if err != nil {
+				return group, err
+			}
+			option.ID = id
+		}
+		group.Options = append(group.Options, option)
+	}
+	return group, nil
+}
+
+// createModifierGroup attaches a new modifier group to an item.
+func (h *menuHandler) createModifierGroup(ctx *gin.Context) {
+	itemID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req modifierGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group, err := req.unwrap()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group.ItemID = &itemID
+
+	if err := h.menuSvc.NewModifierGroup(ctx, &group); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": group})
+}
+
+// updateModifierGroup replaces a modifier group's rules and options.
+func (h *menuHandler) updateModifierGroup(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req modifierGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group, err := req.unwrap()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group.ID = id
+
+	if err := h.menuSvc.UpdateModifierGroup(ctx, &group); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": group})
+}
+
+func (h *menuHandler) deleteModifierGroup(ctx *gin.Context) {
+	rawID := ctx.Param("id")
+	if err := h.menuSvc.DeleteModifierGroup(ctx, rawID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "modifier group deleted"})
+}
+
+// --- Variants --- //
+func (h *menuHandler) listVariants(ctx *gin.Context) {
+	rawID := ctx.Param("id")
+	variants, err := h.menuSvc.Variants(ctx, rawID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": variants})
+}
+
+type variantRequest struct {
+	Name      string  `json:"name"`
+	Price     uint64  `json:"price"`
+	ListOrder uint    `json:"list_order"`
+	Active    *bool   `json:"active,omitempty"`
+	SKU       *string `json:"sku,omitempty"`
+}
+
+func (r *variantRequest) unwrap(itemID uuid.UUID) menu.Variant {
+	variant := menu.Variant{
+		ItemID:    &itemID,
+		Name:      r.Name,
+		Price:     r.Price,
+		ListOrder: r.ListOrder,
+		Active:    true,
+		SKU:       r.SKU,
+	}
+	if r.Active != nil {
+		variant.Active = *r.Active
+	}
+	return variant
+}
+
+// createVariant adds a size or portion variant to an item.
+func (h *menuHandler) createVariant(ctx *gin.Context) {
+	itemID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req variantRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	variant := req.unwrap(itemID)
+
+	if err := h.menuSvc.NewVariant(ctx, &variant); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": variant})
+}
+
+// updateVariant replaces a variant's name, price, ordering, availability and sku.
+func (h *menuHandler) updateVariant(ctx *gin.Context) {
+	itemID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(ctx.Param("variant_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req variantRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	variant := req.unwrap(itemID)
+	variant.ID = id
+
+	if err := h.menuSvc.UpdateVariant(ctx, &variant); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": variant})
+}
+
+func (h *menuHandler) deleteVariant(ctx *gin.Context) {
+	if err := h.menuSvc.DeleteVariant(ctx, ctx.Param("id"), ctx.Param("variant_id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "variant deleted"})
+}
+
+// menuFilter reads the "at", "exclude_allergens" and "diet" query parameters.
+func menuFilter(ctx *gin.Context) (menu.MenuFilter, error) {
+	var filter menu.MenuFilter
+	if rawAt, ok := ctx.GetQuery("at"); ok {
+		at, err := parseTimestamp(rawAt)
+		if err != nil {
+			return filter, err
+		}
+		filter.At = &at
+	}
+	filter.ExcludeAllergens = splitList(ctx.Query("exclude_allergens"))
+	filter.Diets = splitList(ctx.Query("diet"))
+	return filter, nil
+}
+
+// splitList splits a comma separated query parameter into its lower-cased values.
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// parseTimestamp accepts either an RFC 3339 timestamp or seconds since the Unix epoch.
+func parseTimestamp(raw string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, raw); err == nil {
+		return at, nil
+	}
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q; expected RFC 3339 or unix seconds", raw)
+}
+
+// --- Availability --- //
+type availabilityRequest struct {
+	Days      menu.Weekdays `json:"days"`
+	StartTime string        `json:"start_time"`
+	EndTime   string        `json:"end_time"`
+	TimeZone  string        `json:"time_zone"`
+	StartDate *string       `json:"start_date,omitempty"`
+	EndDate   *string       `json:"end_date,omitempty"`
+}
+
+func (r *availabilityRequest) unwrap() menu.AvailabilityWindow {
+	return menu.AvailabilityWindow{
+		Days:      r.Days,
+		StartTime: r.StartTime,
+		EndTime:   r.EndTime,
+		TimeZone:  r.TimeZone,
+		StartDate: r.StartDate,
+		EndDate:   r.EndDate,
+	}
+}
+
+func (h *menuHandler) createSectionAvailability(ctx *gin.Context) {
+	h.createAvailability(ctx, func(window *menu.AvailabilityWindow, id uuid.UUID) {
+		window.SectionID = &id
+	})
+}
+
+func (h *menuHandler) createItemAvailability(ctx *gin.Context) {
+	h.createAvailability(ctx, func(window *menu.AvailabilityWindow, id uuid.UUID) {
+		window.ItemID = &id
+	})
+}
+
+// createAvailability attaches an availability window to the section or item named in the path.
+func (h *menuHandler) createAvailability(ctx *gin.Context, attach func(*menu.AvailabilityWindow, uuid.UUID)) {
+	parentID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req availabilityRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window := req.unwrap()
+	attach(&window, parentID)
+
+	if err := h.menuSvc.NewAvailabilityWindow(ctx, &window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": window})
+}
+
+func (h *menuHandler) updateAvailability(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req availabilityRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window := req.unwrap()
+	window.ID = id
+
+	if err := h.menuSvc.UpdateAvailabilityWindow(ctx, &window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": window})
+}
+
+func (h *menuHandler) deleteAvailability(ctx *gin.Context) {
+	if err := h.menuSvc.DeleteAvailabilityWindow(ctx, ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "availability window deleted"})
+}
+
+// --- Tags --- //
+func (h *menuHandler) listTags(ctx *gin.Context) {
+	tags, err := h.menuSvc.Tags(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+type tagRequest struct {
+	Name  string       `json:"name"`
+	Label string       `json:"label"`
+	Kind  menu.TagKind `json:"kind"`
+}
+
+func (h *menuHandler) createTag(ctx *gin.Context) {
+	var req tagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag := menu.Tag{Name: req.Name, Label: req.Label, Kind: req.Kind}
+
+	if err := h.menuSvc.NewTag(ctx, &tag); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": tag})
+}
+
+func (h *menuHandler) updateTag(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req tagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag := menu.Tag{Name: req.Name, Label: req.Label, Kind: req.Kind}
+	tag.ID = id
+
+	if err := h.menuSvc.UpdateTag(ctx, &tag); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": tag})
+}
+
+func (h *menuHandler) deleteTag(ctx *gin.Context) {
+	if err := h.menuSvc.DeleteTag(ctx, ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "tag deleted"})
+}
+
+type tagAssignmentRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// tagItem replaces the tags of an item.
+func (h *menuHandler) tagItem(ctx *gin.Context) {
+	var req tagAssignmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.menuSvc.TagItem(ctx, ctx.Param("id"), req.Tags)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// tagSection replaces the tags of a section; they are inherited by everything beneath it.
+func (h *menuHandler) tagSection(ctx *gin.Context) {
+	var req tagAssignmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	section, err := h.menuSvc.TagSection(ctx, ctx.Param("id"), req.Tags)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": section})
+}
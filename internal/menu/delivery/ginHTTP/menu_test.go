@@ -0,0 +1,58 @@
+package ginHTTP
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestModifierGroupRequestDefaultsOptionsToActive(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"active left out", `{"title": "Sides", "options": [{"title": "Fries"}]}`, true},
+		{"active", `{"title": "Sides", "options": [{"title": "Fries", "active": true}]}`, true},
+		{"inactive", `{"title": "Sides", "options": [{"title": "Fries", "active": false}]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req modifierGroupRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+			group, err := req.unwrap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := group.Options[0].Active; got != tt.want {
+				t.Errorf("option active = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariantRequestDefaultsToActive(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"active left out", `{"name": "Large", "price": 1800}`, true},
+		{"active", `{"name": "Large", "price": 1800, "active": true}`, true},
+		{"inactive", `{"name": "Large", "price": 1800, "active": false}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req variantRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.unwrap(uuid.New()).Active; got != tt.want {
+				t.Errorf("variant active = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
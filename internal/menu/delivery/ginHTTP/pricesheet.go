@@ -0,0 +1,46 @@
+package ginHTTP
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportPriceSheet downloads every item of the draft menus as a CSV price sheet.
+func (h *menuHandler) exportPriceSheet(ctx *gin.Context) {
+	var sheet bytes.Buffer
+	if err := h.menuSvc.ExportPriceSheet(ctx, &sheet); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=prices.csv")
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", sheet.Bytes())
+}
+
+// importPriceSheet applies an edited price sheet. The CSV is either the request body or a multipart file field named
+// "file". When any row is rejected nothing is changed and the rejected rows are listed in the response.
+func (h *menuHandler) importPriceSheet(ctx *gin.Context) {
+	body := ctx.Request.Body
+	if file, err := ctx.FormFile("file"); err == nil {
+		upload, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer upload.Close()
+		body = upload
+	}
+
+	report, err := h.menuSvc.ImportPriceSheet(ctx, body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(report.Errors) > 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "price sheet has invalid rows", "data": report})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,36 @@
+package ginHTTP
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+// printMenu renders a published menu for print, as an HTML page by default or as a PDF with "format=pdf".
+func (h *menuHandler) printMenu(ctx *gin.Context) {
+	format, err := menu.PrintFormatFromText(ctx.Query("format"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var page bytes.Buffer
+	if err := h.menuSvc.PrintMenu(ctx, ctx.Param("id"), format, &page); errors.Is(err, menu.ErrMenuNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == menu.PDFPrint {
+		ctx.Header("Content-Disposition", "inline; filename=menu.pdf")
+		ctx.Data(http.StatusOK, "application/pdf", page.Bytes())
+		return
+	}
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
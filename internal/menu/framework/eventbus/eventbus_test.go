@@ -0,0 +1,118 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+// drain collects what is waiting on the channel without blocking.
+func drain(events <-chan menu.Event) []menu.Event {
+	var received []menu.Event
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return received
+			}
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
+
+func TestSubscribeResumes(t *testing.T) {
+	tests := []struct {
+		name        string
+		published   int
+		lastEventID uint64
+		wantIDs     []uint64
+		wantReset   bool
+	}{
+		{"from the next event", 3, 0, nil, false},
+		{"caught up", 3, 3, nil, false},
+		{"missed two events", 3, 1, []uint64{2, 3}, false},
+		{"ahead of the bus after a restart", 3, 7, []uint64{3}, true},
+		{"resuming from a forgotten event", historySize + 10, 5, []uint64{historySize + 10}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New()
+			for i := 0; i < tt.published; i++ {
+				b.Publish(menu.Event{Type: menu.ItemUpdated})
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events, err := b.Subscribe(ctx, tt.lastEventID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			received := drain(events)
+			if len(received) != len(tt.wantIDs) {
+				t.Fatalf("replayed %d events, want %d", len(received), len(tt.wantIDs))
+			}
+			for i, event := range received {
+				if event.ID != tt.wantIDs[i] {
+					t.Errorf("event %d has id %d, want %d", i, event.ID, tt.wantIDs[i])
+				}
+				if isReset := event.Type == menu.MenuReset; isReset != tt.wantReset {
+					t.Errorf("event %d is %s, want a reset: %v", i, event.Type, tt.wantReset)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishDeliversNewEvents(t *testing.T) {
+	b := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := b.Subscribe(ctx, 0)
+
+	b.Publish(menu.Event{Type: menu.SectionCreated})
+	received := drain(events)
+	if len(received) != 1 || received[0].ID != 1 || received[0].Type != menu.SectionCreated {
+		t.Fatalf("received %+v, want section.created with id 1", received)
+	}
+	if received[0].At.IsZero() {
+		t.Error("event was not stamped with the time it was published")
+	}
+}
+
+func TestSlowListenersAreDropped(t *testing.T) {
+	b := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := b.Subscribe(ctx, 0)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		b.Publish(menu.Event{Type: menu.ItemUpdated})
+	}
+	received := drain(events)
+	if len(received) != subscriberBuffer {
+		t.Errorf("received %d events, want the %d that fit the buffer", len(received), subscriberBuffer)
+	}
+	if _, ok := <-events; ok {
+		t.Error("channel of a listener that fell behind is still open")
+	}
+}
+
+func TestSubscriptionEndsWithTheContext(t *testing.T) {
+	b := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	events, _ := b.Subscribe(ctx, 0)
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("received an event after the context was done")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed after the context was done")
+	}
+}
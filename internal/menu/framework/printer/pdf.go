@@ -0,0 +1,129 @@
+package printer
+
+import (
+	"io"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+const (
+	pdfMargin     = 20.0
+	pdfLineHeight = 6.0
+	pdfPriceWidth = 30.0
+)
+
+// pdfPage lays a menu out on PDF pages using the standard PDF fonts, so that no font files have to be shipped.
+type pdfPage struct {
+	*fpdf.Fpdf
+	printer   *printer
+	translate func(string) string
+	width     float64
+}
+
+// PDF renders the menu as a PDF document with the same contents as the HTML page.
+func (p *printer) PDF(w io.Writer, section *menu.Section) error {
+	doc := fpdf.New("P", "mm", p.pageSize, "")
+	doc.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	doc.SetAutoPageBreak(true, pdfMargin)
+	doc.SetTitle(section.Title, true)
+	pageWidth, _ := doc.GetPageSize()
+	page := pdfPage{
+		Fpdf:      doc,
+		printer:   p,
+		translate: doc.UnicodeTranslatorFromDescriptor(""),
+		width:     pageWidth - 2*pdfMargin,
+	}
+
+	page.AddPage()
+	page.SetTextColor(34, 34, 34)
+	page.SetFont("Times", "B", 24)
+	page.MultiCell(0, 12, page.translate(section.Title), "", "C", false)
+	if section.Description != nil {
+		page.SetFont("Times", "I", 11)
+		page.MultiCell(0, pdfLineHeight, page.translate(*section.Description), "", "C", false)
+	}
+	page.Ln(pdfLineHeight)
+	page.contents(*section)
+
+	if err := page.Error(); err != nil {
+		return err
+	}
+	return page.Output(w)
+}
+
+func (page *pdfPage) contents(section menu.Section) {
+	for _, item := range section.Items {
+		page.item(item)
+	}
+	for _, sub := range section.SubSections {
+		page.section(sub)
+	}
+}
+
+func (page *pdfPage) section(section menu.Section) {
+	// Keep a heading together with at least its first line rather than stranding it at the foot of a page.
+	_, pageHeight := page.GetPageSize()
+	if page.GetY()+4*pdfLineHeight > pageHeight-pdfMargin {
+		page.AddPage()
+	}
+	page.Ln(pdfLineHeight / 2)
+	page.SetFont("Helvetica", "B", 13)
+	page.CellFormat(0, 8, page.translate(strings.ToUpper(section.Title)), "B", 1, "L", false, 0, "")
+	page.Ln(pdfLineHeight / 2)
+	if section.Description != nil {
+		page.note(*section.Description)
+	}
+	page.contents(section)
+}
+
+func (page *pdfPage) item(item menu.Item) {
+	page.SetFont("Times", "B", 11)
+	variants := pricedVariants(item)
+	if variants == nil {
+		page.CellFormat(page.width-pdfPriceWidth, pdfLineHeight, page.translate(item.Title), "", 0, "L", false, 0, "")
+		page.SetFont("Times", "", 11)
+		page.CellFormat(pdfPriceWidth, pdfLineHeight, page.translate(page.printer.formatPrice(item.Price)), "", 1,
+			"R", false, 0, "")
+	} else {
+		page.MultiCell(0, pdfLineHeight, page.translate(item.Title), "", "L", false)
+		prices := make([]string, 0, len(variants))
+		for _, variant := range variants {
+			prices = append(prices, variant.Name+" "+page.printer.formatPrice(variant.Price))
+		}
+		page.SetFont("Times", "", 10)
+		page.MultiCell(0, pdfLineHeight-1, page.translate(strings.Join(prices, "    ")), "", "L", false)
+	}
+	if item.Description != nil {
+		page.note(*item.Description)
+	}
+	if len(item.AddOns.Items) > 0 {
+		addOns := make([]string, 0, len(item.AddOns.Items))
+		for _, addOn := range item.AddOns.Items {
+			if addOn.Price > 0 {
+				addOns = append(addOns, addOn.Title+" "+page.printer.formatPrice(addOn.Price))
+			} else {
+				addOns = append(addOns, addOn.Title)
+			}
+		}
+		page.note("Add " + strings.Join(addOns, ", "))
+	}
+	if len(item.Condiments.Items) > 0 {
+		condiments := make([]string, 0, len(item.Condiments.Items))
+		for _, condiment := range item.Condiments.Items {
+			condiments = append(condiments, condiment.Title)
+		}
+		page.note("With " + strings.Join(condiments, ", "))
+	}
+	page.Ln(pdfLineHeight / 2)
+}
+
+// note writes a line of small grey italics, as used for descriptions and extras.
+func (page *pdfPage) note(text string) {
+	page.SetFont("Times", "I", 9)
+	page.SetTextColor(85, 85, 85)
+	page.MultiCell(0, pdfLineHeight-1.5, page.translate(text), "", "L", false)
+	page.SetTextColor(34, 34, 34)
+}
@@ -0,0 +1,87 @@
+package printer
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/internal/config"
+)
+
+// pageTemplate is the template executed to render a menu as HTML.
+const pageTemplate = "menu.html"
+
+//go:embed templates/*.html
+var builtinTemplates embed.FS
+
+// pageSizes are the page sizes known to the PDF renderer.
+var pageSizes = map[string]string{"a3": "A3", "a4": "A4", "a5": "A5", "letter": "Letter", "legal": "Legal"}
+
+// printer renders menus as HTML from templates and as PDF with the built-in layout.
+type printer struct {
+	templates      *template.Template
+	currencySymbol string
+	pageSize       string
+}
+
+// New returns a configured instance. The built-in templates are parsed first so that a template directory only needs
+// to hold the templates it overrides, e.g. a style.html that restyles the page.
+func New(cfg config.Print) (*printer, error) {
+	p := printer{currencySymbol: cfg.CurrencySymbol, pageSize: "Letter"}
+	if p.currencySymbol == "" {
+		p.currencySymbol = "$"
+	}
+	if cfg.PageSize != "" {
+		size, ok := pageSizes[strings.ToLower(cfg.PageSize)]
+		if !ok {
+			return &printer{}, fmt.Errorf("unsupported page size %q", cfg.PageSize)
+		}
+		p.pageSize = size
+	}
+
+	templates, err := template.New(pageTemplate).Funcs(template.FuncMap{
+		"price":    p.formatPrice,
+		"variants": pricedVariants,
+	}).ParseFS(builtinTemplates, "templates/*.html")
+	if err != nil {
+		return &printer{}, err
+	}
+	if cfg.TemplateDir != "" {
+		if templates, err = templates.ParseGlob(filepath.Join(cfg.TemplateDir, "*.html")); err != nil {
+			return &printer{}, fmt.Errorf("error loading print templates: %v", err)
+		}
+	}
+	p.templates = templates
+	return &p, nil
+}
+
+// HTML renders the menu as a standalone HTML page.
+func (p *printer) HTML(w io.Writer, section *menu.Section) error {
+	return p.templates.ExecuteTemplate(w, pageTemplate, section)
+}
+
+// formatPrice formats a price in cents as currency, e.g. 123450 as $1,234.50.
+func (p *printer) formatPrice(cents uint64) string {
+	whole := strconv.FormatUint(cents/100, 10)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%s%s.%02d", p.currencySymbol, grouped.String(), cents%100)
+}
+
+// pricedVariants returns the variants to list under an item, or nothing when the item has a single price.
+func pricedVariants(item menu.Item) []menu.Variant {
+	if len(item.Variants) < 2 {
+		return nil
+	}
+	return item.Variants
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
@@ -14,9 +15,15 @@ import (
 )
 
 var (
-	ErrSectionNotFound = errors.New("section could not be found in the database")
-	ErrItemNotFound = errors.New("item could not be found in the database")
+	ErrSectionNotFound            = errors.New("section could not be found in the database")
+	ErrItemNotFound               = errors.New("item could not be found in the database")
+	ErrModifierGroupNotFound      = errors.New("modifier group could not be found in the database")
+	ErrVariantNotFound            = errors.New("variant could not be found in the database")
+	ErrAvailabilityWindowNotFound = errors.New("availability window could not be found in the database")
+	ErrTagNotFound                = errors.New("tag could not be found in the database")
+	ErrSnapshotNotFound           = errors.New("menu version could not be found in the database")
 )
+
 // menuRepository represents the client to its persistent repository
 type menuRepository struct {
 	db *gorm.DB
@@ -27,20 +34,39 @@ func NewMenuRepository(db *gorm.DB) *menuRepository {
 	return &menuRepository{db}
 }
 
-// ListMenus lists all the menus in the db
-func (r *menuRepository) ListMenus(_ context.Context,) (*[]menu.Section, error) {
+// ListMenus lists all the menus in the db, narrowed down by the filter
+func (r *menuRepository) ListMenus(_ context.Context, filter menu.MenuFilter) (*[]menu.Section, error) {
 	var sections []menu.Section
 
-	if err := r.db.Preload("Items").Preload("SubSections.Items").Preload(clause.Associations).Where(
+	if err := r.db.Scopes(preloadMenuTree).Where(
 		"type = ?",
 		menu.Meal).Find(&sections).Error; err != nil {
 		logger.Error.Printf("db connection error %v", err)
 		return &sections, err
 	}
+	sections = filter.Apply(sections)
 	return &sections, nil
 }
+
+// preloadMenuTree preloads a menu's subsections and items down to the add-ons and condiments of every item, along
+// with the tags, variants and availability windows needed to filter them
+func preloadMenuTree(db *gorm.DB) *gorm.DB {
+	db = db.Preload(clause.Associations).Preload("SubSections.Availability").Preload("SubSections.Tags")
+	for _, items := range []string{"Items", "SubSections.Items"} {
+		db = db.Preload(items).Preload(items+".Variants", byListOrder).Preload(items+".Availability").Preload(
+			items+".Tags").Preload(items+".ModifierGroups", byListOrder).Preload(
+			items+".ModifierGroups.Options", byListOrder)
+		for _, modifiers := range []string{items + ".AddOns", items + ".Condiments"} {
+			db = db.Preload(modifiers).Preload(modifiers+".Tags").Preload(modifiers+".Availability").Preload(
+				modifiers+".Items").Preload(modifiers+".Items.Tags").Preload(modifiers+".Items.Variants",
+				byListOrder).Preload(modifiers + ".Items.Availability")
+		}
+	}
+	return db
+}
+
 // ListSections lists all the sections in the db
-func (r *menuRepository) ListSections(_ context.Context,) (*[]menu.Section, error) {
+func (r *menuRepository) ListSections(_ context.Context) (*[]menu.Section, error) {
 	var sections []menu.Section
 
 	if err := r.db.Preload(clause.Associations).Find(&sections).Error; err != nil {
@@ -49,8 +75,9 @@ func (r *menuRepository) ListSections(_ context.Context,) (*[]menu.Section, erro
 	}
 	return &sections, nil
 }
+
 // FindSection finds an section by its id
-func (r *menuRepository) FindSection(_ context.Context,section *menu.Section) error {
+func (r *menuRepository) FindSection(_ context.Context, section *menu.Section) error {
 	if err := r.db.Preload("Items").Preload(clause.Associations).First(section).Error; errors.Is(err,
 		gorm.ErrRecordNotFound) {
 		return fmt.Errorf("record not found for %v", section.ID)
@@ -62,7 +89,7 @@ func (r *menuRepository) FindSection(_ context.Context,section *menu.Section) er
 }
 
 // CreateSection first checks for preexisting record, and if not found will create the specified section
-func (r *menuRepository) CreateSection(_ context.Context,section *menu.Section) error {
+func (r *menuRepository) CreateSection(_ context.Context, section *menu.Section) error {
 	if err := r.db.Where(
 		"lower(title) = ?",
 		strings.ToLower(section.Title)).First(section).Error; err == nil {
@@ -71,15 +98,52 @@ func (r *menuRepository) CreateSection(_ context.Context,section *menu.Section)
 		return err
 	}
 
-	if err := r.db.Create(&section).Error;  err != nil {
+	if err := r.db.Create(&section).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
 // UpdateSection updates section data
-func (r *menuRepository) UpdateSection(_ context.Context,section *menu.Section) error {
-	return r.db.Save(section).Error
+func (r *menuRepository) UpdateSection(_ context.Context, section *menu.Section) error {
+	// The tax category and station are only changed through SetSectionTaxCategory and SetSectionStation.
+	return r.db.Omit("tax_category", "station").Save(section).Error
+}
+
+// SetSectionActive changes whether the section is active and leaves the rest of it alone
+func (r *menuRepository) SetSectionActive(_ context.Context, section *menu.Section, active bool) error {
+	result := r.db.Model(section).Update("active", active)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSectionNotFound
+	}
+	return nil
+}
+
+// SetSectionTaxCategory changes the tax category of the section and leaves the rest of it alone
+func (r *menuRepository) SetSectionTaxCategory(_ context.Context, section *menu.Section, category *string) error {
+	result := r.db.Model(section).Update("tax_category", category)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSectionNotFound
+	}
+	return nil
+}
+
+// SetSectionStation changes the prep station of the section and leaves the rest of it alone
+func (r *menuRepository) SetSectionStation(_ context.Context, section *menu.Section, station *string) error {
+	result := r.db.Model(section).Update("station", station)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSectionNotFound
+	}
+	return nil
 }
 
 // UpdateSectionParent re-parents a subsection
@@ -88,12 +152,12 @@ func (r *menuRepository) UpdateSectionParent(_ context.Context, child *menu.Sect
 }
 
 // DeleteSection deletes a section
-func (r *menuRepository) DeleteSection(_ context.Context,section *menu.Section) error {
+func (r *menuRepository) DeleteSection(_ context.Context, section *menu.Section) error {
 	return r.db.Delete(section).Error
 }
 
 // ListItems lists all the users in the db
-func (r *menuRepository) ListItems(_ context.Context,) (*[]menu.Item, error) {
+func (r *menuRepository) ListItems(_ context.Context) (*[]menu.Item, error) {
 	var items []menu.Item
 
 	if err := r.db.Preload(clause.Associations).Find(&items).Error; err != nil {
@@ -105,7 +169,8 @@ func (r *menuRepository) ListItems(_ context.Context,) (*[]menu.Item, error) {
 
 // FindItem finds an item by its id
 func (r *menuRepository) FindItem(_ context.Context, item *menu.Item) error {
-	if err := r.db.Preload(clause.Associations).First(item).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+	if err := r.db.Preload(clause.Associations).Preload("ModifierGroups", byListOrder).Preload(
+		"ModifierGroups.Options", byListOrder).Preload("Variants", byListOrder).First(item).Error; errors.Is(err, gorm.ErrRecordNotFound) {
 		return fmt.Errorf("record not found for %v", item.ID)
 	} else if err != nil {
 		logger.Error.Printf("db connection error %v", err)
@@ -125,7 +190,7 @@ func (r *menuRepository) CreateItem(_ context.Context, item *menu.Item) error {
 		return err
 	}
 
-	if err := r.db.Create(&item).Error;  err != nil {
+	if err := r.db.Create(&item).Error; err != nil {
 		return err
 	}
 
@@ -134,9 +199,51 @@ func (r *menuRepository) CreateItem(_ context.Context, item *menu.Item) error {
 
 // UpdateItem updates an item
 func (r *menuRepository) UpdateItem(_ context.Context, item *menu.Item) error {
-	return r.db.Save(&item).Error
+	// The tax category and station are only changed through SetItemTaxCategory and SetItemStation.
+	return r.db.Omit("tax_category", "station").Save(&item).Error
 }
 
+// UpdateItemListing saves the columns of a price sheet, the title, type, price, active flag and list order of the item,
+// and leaves the rest of it, along with whatever it was loaded with, alone
+func (r *menuRepository) UpdateItemListing(_ context.Context, item *menu.Item) error {
+	return r.db.Model(item).Select("title", "type", "price", "active", "list_order").Updates(item).Error
+}
+
+// SetItemActive changes whether the item is active and leaves the rest of it alone
+func (r *menuRepository) SetItemActive(_ context.Context, item *menu.Item, active bool) error {
+	result := r.db.Model(item).Update("active", active)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// SetItemTaxCategory changes the tax category of the item and leaves the rest of it alone
+func (r *menuRepository) SetItemTaxCategory(_ context.Context, item *menu.Item, category *string) error {
+	result := r.db.Model(item).Update("tax_category", category)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// SetItemStation changes the prep station of the item and leaves the rest of it alone
+func (r *menuRepository) SetItemStation(_ context.Context, item *menu.Item, station *string) error {
+	result := r.db.Model(item).Update("station", station)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
 
 // UpdateItemParent re-parents an item
 func (r *menuRepository) UpdateItemParent(_ context.Context, child *menu.Item, newParent *menu.Section) error {
@@ -153,3 +260,375 @@ func (r *menuRepository) DeleteItem(_ context.Context, item *menu.Item) error {
 	}
 	return r.db.Delete(&item).Error
 }
+
+// byListOrder orders preloaded associations the way they are meant to be displayed
+func byListOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("list_order")
+}
+
+// ListModifierGroups lists the modifier groups, and their options, that belong to an item
+func (r *menuRepository) ListModifierGroups(_ context.Context, item *menu.Item) (*[]menu.ModifierGroup, error) {
+	var groups []menu.ModifierGroup
+
+	if err := r.db.Preload("Options", byListOrder).Where("item_id = ?", item.ID).Order(
+		"list_order").Find(&groups).Error; err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return &groups, err
+	}
+	return &groups, nil
+}
+
+// FindModifierGroup finds a modifier group by its id
+func (r *menuRepository) FindModifierGroup(_ context.Context, group *menu.ModifierGroup) error {
+	if err := r.db.Preload("Options", byListOrder).First(group).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrModifierGroupNotFound
+	} else if err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return err
+	}
+	return nil
+}
+
+// CreateModifierGroup creates a modifier group along with its options
+func (r *menuRepository) CreateModifierGroup(_ context.Context, group *menu.ModifierGroup) error {
+	return r.db.Create(group).Error
+}
+
+// UpdateModifierGroup updates a modifier group's rules and reconciles its options in a single transaction
+func (r *menuRepository) UpdateModifierGroup(_ context.Context, group *menu.ModifierGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Select("title", "min_selections", "max_selections", "list_order").Updates(
+			group).Error; err != nil {
+			return err
+		}
+
+		keep := make([]uuid.UUID, 0, len(group.Options))
+		for i := range group.Options {
+			option := &group.Options[i]
+			option.ModifierGroupID = &group.ID
+			if option.ID == uuid.Nil {
+				if err := tx.Create(option).Error; err != nil {
+					return err
+				}
+			} else {
+				result := tx.Model(option).Where("modifier_group_id = ?", group.ID).Select("title", "price_delta",
+					"is_default", "active", "list_order").Updates(option)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return fmt.Errorf("%w: %v", menu.ErrUnknownOption, option.ID)
+				}
+			}
+			keep = append(keep, option.ID)
+		}
+
+		stale := tx.Where("modifier_group_id = ?", group.ID)
+		if len(keep) > 0 {
+			stale = stale.Where("id NOT IN ?", keep)
+		}
+		return stale.Delete(&menu.ModifierOption{}).Error
+	})
+}
+
+// DeleteModifierGroup deletes a modifier group and its options
+func (r *menuRepository) DeleteModifierGroup(_ context.Context, group *menu.ModifierGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("modifier_group_id = ?", group.ID).Delete(&menu.ModifierOption{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(group)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrModifierGroupNotFound
+		}
+		return result.Error
+	})
+}
+
+// ListVariants lists the stored variants of an item
+func (r *menuRepository) ListVariants(_ context.Context, item *menu.Item) (*[]menu.Variant, error) {
+	var variants []menu.Variant
+
+	if err := r.db.Where("item_id = ?", item.ID).Order("list_order").Find(&variants).Error; err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return &variants, err
+	}
+	return &variants, nil
+}
+
+// FindVariant finds a variant by its id, scoped to its item
+func (r *menuRepository) FindVariant(_ context.Context, variant *menu.Variant) error {
+	if err := r.db.Where("item_id = ?", variant.ItemID).First(variant).Error; errors.Is(err,
+		gorm.ErrRecordNotFound) {
+		return ErrVariantNotFound
+	} else if err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return err
+	}
+	return nil
+}
+
+// CreateVariant creates a variant for an item
+func (r *menuRepository) CreateVariant(_ context.Context, variant *menu.Variant) error {
+	return r.db.Create(variant).Error
+}
+
+// UpdateVariant updates a variant's name, price, ordering, availability and sku
+func (r *menuRepository) UpdateVariant(_ context.Context, variant *menu.Variant) error {
+	result := r.db.Model(variant).Where("item_id = ?", variant.ItemID).Select("name", "price", "list_order", "active",
+		"sku").Updates(variant)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrVariantNotFound
+	}
+	return result.Error
+}
+
+// DeleteVariant deletes a variant of an item
+func (r *menuRepository) DeleteVariant(_ context.Context, variant *menu.Variant) error {
+	result := r.db.Where("item_id = ?", variant.ItemID).Delete(variant)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrVariantNotFound
+	}
+	return result.Error
+}
+
+// FindAvailabilityWindow finds an availability window by its id
+func (r *menuRepository) FindAvailabilityWindow(_ context.Context, window *menu.AvailabilityWindow) error {
+	if err := r.db.First(window).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAvailabilityWindowNotFound
+	} else if err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return err
+	}
+	return nil
+}
+
+// CreateAvailabilityWindow creates an availability window for a section or an item
+func (r *menuRepository) CreateAvailabilityWindow(_ context.Context, window *menu.AvailabilityWindow) error {
+	return r.db.Create(window).Error
+}
+
+// UpdateAvailabilityWindow updates the schedule of an availability window
+func (r *menuRepository) UpdateAvailabilityWindow(_ context.Context, window *menu.AvailabilityWindow) error {
+	return r.db.Model(window).Select("days", "start_time", "end_time", "time_zone", "start_date",
+		"end_date").Updates(window).Error
+}
+
+// DeleteAvailabilityWindow deletes an availability window
+func (r *menuRepository) DeleteAvailabilityWindow(_ context.Context, window *menu.AvailabilityWindow) error {
+	result := r.db.Delete(window)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrAvailabilityWindowNotFound
+	}
+	return result.Error
+}
+
+// ListTags lists the tag vocabulary
+func (r *menuRepository) ListTags(_ context.Context) (*[]menu.Tag, error) {
+	var tags []menu.Tag
+
+	if err := r.db.Order("kind").Order("name").Find(&tags).Error; err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return &tags, err
+	}
+	return &tags, nil
+}
+
+// FindTags finds the tags with the given names
+func (r *menuRepository) FindTags(_ context.Context, names []string) (*[]menu.Tag, error) {
+	var tags []menu.Tag
+
+	if err := r.db.Where("name IN ?", names).Find(&tags).Error; err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return &tags, err
+	}
+	return &tags, nil
+}
+
+// CreateTag first checks for a preexisting tag with the same name, and if not found will create it
+func (r *menuRepository) CreateTag(_ context.Context, tag *menu.Tag) error {
+	if err := r.db.Where("name = ?", tag.Name).First(&menu.Tag{}).Error; err == nil {
+		return errors.New("tag already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return r.db.Create(tag).Error
+}
+
+// UpdateTag updates a tag's name, label and kind
+func (r *menuRepository) UpdateTag(_ context.Context, tag *menu.Tag) error {
+	result := r.db.Model(tag).Select("name", "label", "kind").Updates(tag)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrTagNotFound
+	}
+	return result.Error
+}
+
+// DeleteTag deletes a tag and removes it from every section and item
+func (r *menuRepository) DeleteTag(_ context.Context, tag *menu.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM item_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM section_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(tag)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrTagNotFound
+		}
+		return result.Error
+	})
+}
+
+// ReplaceItemTags replaces the tags attached to an item
+func (r *menuRepository) ReplaceItemTags(_ context.Context, item *menu.Item, tags []menu.Tag) error {
+	return r.db.Model(item).Association("Tags").Replace(tags)
+}
+
+// ReplaceSectionTags replaces the tags attached to a section
+func (r *menuRepository) ReplaceSectionTags(_ context.Context, section *menu.Section, tags []menu.Tag) error {
+	return r.db.Model(section).Association("Tags").Replace(tags)
+}
+
+// publishAttempts is how many times a snapshot is given the next version before PublishSnapshot gives up, when
+// versions keep being taken by snapshots published at the same time
+const publishAttempts = 5
+
+// PublishSnapshot stores a snapshot as the next version in a single transaction. Publishing at the same time as
+// someone else can pick a version they are already storing, which the unique index on versions refuses; it is then
+// tried again with the version after theirs.
+func (r *menuRepository) PublishSnapshot(_ context.Context, snapshot *menu.Snapshot) error {
+	for attempt := 1; ; attempt++ {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			var latest uint
+			if err := tx.Model(&menu.Snapshot{}).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+				return err
+			}
+			snapshot.Version = latest + 1
+			return tx.Create(snapshot).Error
+		})
+		if err == nil || attempt == publishAttempts || !r.versionTaken(snapshot.Version) {
+			return err
+		}
+	}
+}
+
+// versionTaken reports whether a snapshot has been stored under the version
+func (r *menuRepository) versionTaken(version uint) bool {
+	var count int64
+	if err := r.db.Model(&menu.Snapshot{}).Where("version = ?", version).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// ListSnapshots lists every published version, newest first, without their content
+func (r *menuRepository) ListSnapshots(_ context.Context) (*[]menu.Snapshot, error) {
+	var snapshots []menu.Snapshot
+
+	if err := r.db.Omit("content").Order("version desc").Find(&snapshots).Error; err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return &snapshots, err
+	}
+	return &snapshots, nil
+}
+
+// FindSnapshot finds a snapshot by its version
+func (r *menuRepository) FindSnapshot(_ context.Context, snapshot *menu.Snapshot) error {
+	if err := r.db.Where("version = ?", snapshot.Version).First(snapshot).Error; errors.Is(err,
+		gorm.ErrRecordNotFound) {
+		return ErrSnapshotNotFound
+	} else if err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return err
+	}
+	return nil
+}
+
+// LatestSnapshot finds the most recently published snapshot
+func (r *menuRepository) LatestSnapshot(_ context.Context, snapshot *menu.Snapshot) error {
+	if err := r.db.Order("version desc").First(snapshot).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return menu.ErrNoPublishedMenu
+	} else if err != nil {
+		logger.Error.Printf("db connection error %v", err)
+		return err
+	}
+	return nil
+}
+
+// SaveMenus writes whole menu trees, creating the sections and items that do not exist yet and overwriting those that
+// do. The variants, modifier groups, availability windows and tags of existing sections and items are replaced by
+// those in the trees.
+func (r *menuRepository) SaveMenus(_ context.Context, sections []menu.Section) error {
+	var sectionIDs, itemIDs []uuid.UUID
+	collectMenuIDs(sections, &sectionIDs, &itemIDs)
+
+	if len(sectionIDs) > 0 {
+		if err := r.db.Unscoped().Where("section_id IN ?", sectionIDs).Delete(&menu.AvailabilityWindow{}).Error; err != nil {
+			return err
+		}
+		if err := r.db.Exec("DELETE FROM section_tags WHERE section_id IN ?", sectionIDs).Error; err != nil {
+			return err
+		}
+	}
+	if len(itemIDs) > 0 {
+		if err := r.db.Exec("DELETE FROM modifier_options WHERE modifier_group_id IN "+
+			"(SELECT id FROM modifier_groups WHERE item_id IN ?)", itemIDs).Error; err != nil {
+			return err
+		}
+		for _, owned := range []interface{}{&menu.ModifierGroup{}, &menu.Variant{}, &menu.AvailabilityWindow{}} {
+			if err := r.db.Unscoped().Where("item_id IN ?", itemIDs).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		if err := r.db.Exec("DELETE FROM item_tags WHERE item_id IN ?", itemIDs).Error; err != nil {
+			return err
+		}
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+	return r.db.Save(&sections).Error
+}
+
+func collectMenuIDs(sections []menu.Section, sectionIDs, itemIDs *[]uuid.UUID) {
+	for _, section := range sections {
+		if section.ID == uuid.Nil {
+			continue
+		}
+		*sectionIDs = append(*sectionIDs, section.ID)
+		collectMenuIDs(section.SubSections, sectionIDs, itemIDs)
+		for _, item := range section.Items {
+			if item.ID == uuid.Nil {
+				continue
+			}
+			*itemIDs = append(*itemIDs, item.ID)
+			collectMenuIDs([]menu.Section{item.AddOns, item.Condiments}, sectionIDs, itemIDs)
+		}
+	}
+}
+
+// DeleteMenus permanently deletes every section and item along with everything that belongs to them. Tags and
+// published snapshots are kept.
+func (r *menuRepository) DeleteMenus(_ context.Context) error {
+	for _, table := range []string{"item_tags", "section_tags"} {
+		if err := r.db.Exec("DELETE FROM " + table).Error; err != nil {
+			return err
+		}
+	}
+	for _, model := range []interface{}{&menu.ModifierOption{}, &menu.ModifierGroup{}, &menu.Variant{},
+		&menu.AvailabilityWindow{}, &menu.Item{}, &menu.Section{}} {
+		if err := r.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transaction runs fn against a repository whose changes are committed only if fn returns nil
+func (r *menuRepository) Transaction(_ context.Context, fn func(menu.Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&menuRepository{tx})
+	})
+}
@@ -0,0 +1,71 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/coquizen/servercarte/domain/menu"
+)
+
+func openMenuDB(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"),
+		&gorm.Config{Logger: logger.Discard, FullSaveAssociations: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&menu.Section{}, &menu.Item{}, &menu.ModifierGroup{}, &menu.ModifierOption{},
+		&menu.Variant{}, &menu.AvailabilityWindow{}, &menu.Tag{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCreateModifierGroupKeepsInactiveOptions(t *testing.T) {
+	repo := NewMenuRepository(openMenuDB(t, "modifiers"))
+	ctx := context.Background()
+	item := menu.Item{Title: "Bagel", Type: menu.Plate, Price: 395, Active: true}
+	if err := repo.db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	group := menu.ModifierGroup{Title: "Add a side", ItemID: &item.ID, Options: []menu.ModifierOption{
+		{Title: "Hash browns", ListOrder: 1, Active: false},
+	}}
+	if err := repo.CreateModifierGroup(ctx, &group); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := menu.ModifierGroup{}
+	stored.ID = group.ID
+	if err := repo.FindModifierGroup(ctx, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Options) != 1 || stored.Options[0].Active {
+		t.Errorf("stored options %+v, want the one option inactive", stored.Options)
+	}
+}
+
+func TestCreateVariantKeepsInactiveVariants(t *testing.T) {
+	repo := NewMenuRepository(openMenuDB(t, "variants"))
+	ctx := context.Background()
+	item := menu.Item{Title: "Salad", Type: menu.Plate, Price: 1095, Active: true}
+	if err := repo.db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	variant := menu.Variant{ItemID: &item.ID, Name: "Half", Price: 645, Active: false}
+	if err := repo.CreateVariant(ctx, &variant); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := menu.Variant{ItemID: &item.ID}
+	stored.ID = variant.ID
+	if err := repo.FindVariant(ctx, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Active {
+		t.Error("variant created inactive was stored as active")
+	}
+}
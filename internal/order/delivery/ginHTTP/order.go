@@ -0,0 +1,176 @@
+package ginHTTP
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/authorization"
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/order"
+	"github.com/coquizen/servercarte/domain/quote"
+)
+
+type orderHandler struct {
+	orderSvc order.Service
+	permits  func(*gin.Context, authorization.Permission) bool
+}
+
+// RegisterRoutes sets up the cart and order API endpoints using Gin. Ordering requires the order:place permission,
+// and only reaches the orders of whoever placed them; the order:manage permission reaches every order.
+func RegisterRoutes(svc order.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc, permits func(*gin.Context, authorization.Permission) bool) {
+	h := orderHandler{svc, permits}
+	orderGroup := r.Group("/api/v1", authMiddleWare, authorize(authorization.PlaceOrder))
+	orderGroup.POST("/carts", h.createCart)
+	orderGroup.POST("/carts/:id/lines", h.addLine)
+	orderGroup.DELETE("/carts/:id/lines/:line_id", h.removeLine)
+	orderGroup.POST("/carts/:id/checkout", h.checkout)
+	orderGroup.POST("/orders", h.placeOrder)
+	orderGroup.GET("/orders", h.listOrders)
+	orderGroup.GET("/orders/:id", h.findOrderByID)
+	orderGroup.POST("/orders/:id/cancel", h.cancelOrder)
+}
+
+// customer returns who the request orders for: the account logged in, or the device calling with an API key.
+func customer(ctx *gin.Context) order.Customer {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	if claims.APIKeyID != uuid.Nil {
+		return order.Customer{APIKeyID: &claims.APIKeyID}
+	}
+	return order.Customer{AccountID: &claims.AccountID}
+}
+
+// owner returns the customer whose orders the request may reach, or nil when it may reach every order.
+func (h *orderHandler) owner(ctx *gin.Context) *order.Customer {
+	if h.permits(ctx, authorization.ManageOrders) {
+		return nil
+	}
+	c := customer(ctx)
+	return &c
+}
+
+// createCart starts a cart, with {"lines": [...]} already in it if given.
+func (h *orderHandler) createCart(ctx *gin.Context) {
+	var req order.OrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	cart, err := h.orderSvc.NewCart(ctx, customer(ctx), req)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": cart})
+}
+
+func (h *orderHandler) addLine(ctx *gin.Context) {
+	var req quote.LineRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	cart, err := h.orderSvc.AddLine(ctx, ctx.Param("id"), h.owner(ctx), req)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": cart})
+}
+
+func (h *orderHandler) removeLine(ctx *gin.Context) {
+	cart, err := h.orderSvc.RemoveLine(ctx, ctx.Param("id"), ctx.Param("line_id"), h.owner(ctx))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": cart})
+}
+
+// checkout places a cart at the prices of the moment.
+func (h *orderHandler) checkout(ctx *gin.Context) {
+	placed, err := h.orderSvc.Checkout(ctx, ctx.Param("id"), h.owner(ctx))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": placed})
+}
+
+// placeOrder places an order right away, e.g. from a kiosk that keeps the cart on its own.
+func (h *orderHandler) placeOrder(ctx *gin.Context) {
+	var req order.OrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	placed, err := h.orderSvc.Place(ctx, customer(ctx), req)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": placed})
+}
+
+// listOrders lists the latest orders, newest first, narrowed down by ?status=.
+func (h *orderHandler) listOrders(ctx *gin.Context) {
+	var filter order.Filter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Customer = h.owner(ctx)
+	orders, err := h.orderSvc.Orders(ctx, filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": orders})
+}
+
+func (h *orderHandler) findOrderByID(ctx *gin.Context) {
+	found, err := h.orderSvc.OrderByID(ctx, ctx.Param("id"), h.owner(ctx))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": found})
+}
+
+// cancelOrder calls off a cart or a placed order, with an optional {"reason"}.
+func (h *orderHandler) cancelOrder(ctx *gin.Context) {
+	var req order.CancelRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	cancelled, err := h.orderSvc.Cancel(ctx, ctx.Param("id"), h.owner(ctx), req)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": cancelled})
+}
+
+// statusFor maps an error from the order service to the status code it is answered with.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, order.ErrOrderNotFound), errors.Is(err, order.ErrLineNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, order.ErrNotACart), errors.Is(err, order.ErrNotCancellable),
+		errors.Is(err, order.ErrOrderChanged):
+		return http.StatusConflict
+	case errors.Is(err, quote.ErrItemUnavailable), errors.Is(err, quote.ErrInvalidSelection),
+		errors.Is(err, order.ErrEmptyCart),
+		errors.Is(err, menu.ErrTooFewSelections), errors.Is(err, menu.ErrTooManySelections),
+		errors.Is(err, menu.ErrUnknownOption), errors.Is(err, menu.ErrInactiveOption):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,107 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/coquizen/servercarte/domain/order"
+)
+
+// orderRepository represents the client to its persistent repository
+type orderRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderRepository instantiates an instance for data persistence
+func NewOrderRepository(db *gorm.DB) *orderRepository {
+	return &orderRepository{db}
+}
+
+// preloadLines loads the line items of orders in the order they were added, along with what was picked for them.
+func preloadLines(db *gorm.DB) *gorm.DB {
+	return db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Preload("Lines.Selections", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	})
+}
+
+func (r *orderRepository) CreateOrder(_ context.Context, o *order.Order) error {
+	return r.db.Create(o).Error
+}
+
+func (r *orderRepository) FindOrder(_ context.Context, id uuid.UUID) (*order.Order, error) {
+	var o order.Order
+	if err := r.db.Scopes(preloadLines).First(&o, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &o, order.ErrOrderNotFound
+		}
+		return &o, err
+	}
+	return &o, nil
+}
+
+func (r *orderRepository) ListOrders(_ context.Context, filter order.Filter, limit int) ([]order.Order, error) {
+	var orders []order.Order
+	query := r.db.Scopes(preloadLines).Order("created_at desc").Limit(limit)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.PlacedSince != nil {
+		query = query.Where("placed_at >= ?", *filter.PlacedSince)
+	}
+	if filter.Customer != nil {
+		switch {
+		case filter.Customer.AccountID != nil:
+			query = query.Where("account_id = ?", *filter.Customer.AccountID)
+		case filter.Customer.APIKeyID != nil:
+			query = query.Where("api_key_id = ?", *filter.Customer.APIKeyID)
+		default:
+			return []order.Order{}, nil
+		}
+	}
+	if err := query.Find(&orders).Error; err != nil {
+		return orders, err
+	}
+	return orders, nil
+}
+
+// SaveOrder updates the order row only while it still has the status it was read with, then replaces its line items
+// and their selections with the ones given.
+func (r *orderRepository) SaveOrder(_ context.Context, o *order.Order, from order.Status) (bool, error) {
+	saved := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&order.Order{}).Where("id = ? AND status = ?", o.ID, from).Select("status", "notes",
+			"subtotal", "discount", "tax", "total", "placed_at", "cancelled_at", "cancel_reason", "updated_at").Updates(o)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		var lineIDs []uuid.UUID
+		if err := tx.Model(&order.LineItem{}).Where("order_id = ?", o.ID).Pluck("id", &lineIDs).Error; err != nil {
+			return err
+		}
+		if len(lineIDs) > 0 {
+			if err := tx.Where("line_item_id IN ?", lineIDs).Delete(&order.Selection{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("order_id = ?", o.ID).Delete(&order.LineItem{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range o.Lines {
+			o.Lines[i].OrderID = o.ID
+			for j := range o.Lines[i].Selections {
+				o.Lines[i].Selections[j].ID = uuid.Nil
+			}
+			if err := tx.Create(&o.Lines[i]).Error; err != nil {
+				return err
+			}
+		}
+		saved = true
+		return nil
+	})
+	return saved, err
+}
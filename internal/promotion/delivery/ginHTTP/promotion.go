@@ -0,0 +1,122 @@
+package ginHTTP
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/authorization"
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/promotion"
+)
+
+type promotionHandler struct {
+	promotionSvc promotion.Service
+}
+
+type promotionRequest struct {
+	Name        string             `json:"name" binding:"required"`
+	Description *string            `json:"description,omitempty"`
+	Kind        promotion.Kind     `json:"kind" binding:"required"`
+	Active      *bool              `json:"active,omitempty"`
+	Priority    int                `json:"priority"`
+	Stackable   bool               `json:"stackable"`
+	Percent     uint               `json:"percent"`
+	Amount      uint64             `json:"amount"`
+	Buy         uint               `json:"buy"`
+	Get         uint               `json:"get"`
+	Targets     []promotion.Target `json:"targets"`
+	Days        menu.Weekdays      `json:"days"`
+	StartTime   string             `json:"start_time"`
+	EndTime     string             `json:"end_time"`
+	TimeZone    string             `json:"time_zone"`
+	StartDate   *string            `json:"start_date,omitempty"`
+	EndDate     *string            `json:"end_date,omitempty"`
+}
+
+// RegisterRoutes sets up the promotion API endpoints using Gin. Managing promotions requires the promotion:manage
+// permission.
+func RegisterRoutes(svc promotion.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc) {
+	h := promotionHandler{svc}
+	promotionGroup := r.Group("/api/v1/promotions", authMiddleWare, authorize(authorization.ManagePromotions))
+	promotionGroup.GET("", h.listPromotions)
+	promotionGroup.POST("", h.createPromotion)
+	promotionGroup.GET("/:id", h.findPromotionByID)
+	promotionGroup.PATCH("/:id", h.updatePromotion)
+	promotionGroup.DELETE("/:id", h.deletePromotion)
+}
+
+func (h *promotionHandler) listPromotions(ctx *gin.Context) {
+	promotions, err := h.promotionSvc.Promotions(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": promotions})
+}
+
+func (h *promotionHandler) createPromotion(ctx *gin.Context) {
+	var req promotionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	p := promotion.Promotion{Name: req.Name, Description: req.Description, Kind: req.Kind, Active: true,
+		Priority: req.Priority, Stackable: req.Stackable, Percent: req.Percent, Amount: req.Amount, Buy: req.Buy,
+		Get: req.Get, Targets: req.Targets, Days: req.Days, StartTime: req.StartTime, EndTime: req.EndTime,
+		TimeZone: req.TimeZone, StartDate: req.StartDate, EndDate: req.EndDate}
+	if req.Active != nil {
+		p.Active = *req.Active
+	}
+	if p.TimeZone == "" {
+		p.TimeZone = "UTC"
+	}
+	if err := h.promotionSvc.CreatePromotion(ctx, &p); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": p})
+}
+
+func (h *promotionHandler) findPromotionByID(ctx *gin.Context) {
+	p, err := h.promotionSvc.PromotionByID(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": p})
+}
+
+// updatePromotion changes the fields given, e.g. {"active": false} to pause a promotion.
+func (h *promotionHandler) updatePromotion(ctx *gin.Context) {
+	var req promotion.UpdatePromotionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.promotionSvc.UpdatePromotion(ctx, ctx.Param("id"), req)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": p})
+}
+
+func (h *promotionHandler) deletePromotion(ctx *gin.Context) {
+	if err := h.promotionSvc.DeletePromotion(ctx, ctx.Param("id")); err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "promotion successfully deleted"})
+}
+
+// statusFor maps an error from the promotion service to the status code it is answered with.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, promotion.ErrPromotionNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,72 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/coquizen/servercarte/domain/promotion"
+)
+
+// promotionRepository represents the client to its persistent repository
+type promotionRepository struct {
+	db *gorm.DB
+}
+
+// NewPromotionRepository instantiates an instance for data persistence
+func NewPromotionRepository(db *gorm.DB) *promotionRepository {
+	return &promotionRepository{db}
+}
+
+// ListPromotions lists every promotion along with its targets, oldest first.
+func (r *promotionRepository) ListPromotions(_ context.Context) (*[]promotion.Promotion, error) {
+	var promotions []promotion.Promotion
+	if err := r.db.Preload("Targets").Order("created_at").Find(&promotions).Error; err != nil {
+		return &promotions, err
+	}
+	return &promotions, nil
+}
+
+func (r *promotionRepository) FindPromotion(_ context.Context, p *promotion.Promotion) error {
+	if err := r.db.Preload("Targets").First(p, p.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return promotion.ErrPromotionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *promotionRepository) CreatePromotion(_ context.Context, p *promotion.Promotion) error {
+	return r.db.Create(p).Error
+}
+
+// UpdatePromotion saves the promotion and replaces its targets with those it has now.
+func (r *promotionRepository) UpdatePromotion(_ context.Context, p *promotion.Promotion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Targets").Save(p).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("promotion_id = ?", p.ID).Delete(&promotion.Target{}).Error; err != nil {
+			return err
+		}
+		if len(p.Targets) == 0 {
+			return nil
+		}
+		for i := range p.Targets {
+			p.Targets[i].PromotionID = p.ID
+		}
+		return tx.Create(&p.Targets).Error
+	})
+}
+
+// DeletePromotion deletes the promotion and its targets.
+func (r *promotionRepository) DeletePromotion(_ context.Context, p *promotion.Promotion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("promotion_id = ?", p.ID).Delete(&promotion.Target{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(p).Error
+	})
+}
@@ -0,0 +1,52 @@
+package ginHTTP
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/quote"
+	"github.com/coquizen/servercarte/domain/tax"
+)
+
+type quoteHandler struct {
+	quoteSvc quote.Service
+}
+
+// RegisterRoutes sets up the price quote API endpoint using Gin. Quotes only read the published menu, so they need no
+// login.
+func RegisterRoutes(svc quote.Service, r *gin.Engine) {
+	h := quoteHandler{svc}
+	quoteGroup := r.Group("/api/v1")
+	quoteGroup.POST("/quote", h.quote)
+}
+
+// quote prices {"lines": [...]} against the published menu and returns the itemised breakdown.
+func (h *quoteHandler) quote(ctx *gin.Context) {
+	var req quote.Request
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	q, err := h.quoteSvc.Quote(ctx, req)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": q})
+}
+
+// statusFor maps an error from the quote service to the status code it is answered with.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, quote.ErrItemUnavailable), errors.Is(err, quote.ErrInvalidSelection),
+		errors.Is(err, quote.ErrNoLines), errors.Is(err, tax.ErrUnknownLocation),
+		errors.Is(err, menu.ErrTooFewSelections), errors.Is(err, menu.ErrTooManySelections),
+		errors.Is(err, menu.ErrUnknownOption), errors.Is(err, menu.ErrInactiveOption):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,172 @@
+package argon2id
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/coquizen/servercarte/domain/security"
+	"github.com/coquizen/servercarte/internal/config"
+)
+
+// prefix starts every hash this package encodes, in the PHC string format:
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+const prefix = "$argon2id$"
+
+var (
+	ErrMalformedHash     = errors.New("malformed argon2id hash")
+	ErrIncompatibleHash  = errors.New("argon2id hash of an unsupported version")
+	ErrPasswordsMismatch = errors.New("hashedPassword is not the hash of the given password")
+)
+
+// encoding is the unpadded standard base64 the PHC string format uses for the salt and key.
+var encoding = base64.RawStdEncoding
+
+// Params are the cost parameters of Argon2id. Zero fields take the value of DefaultParams.
+type Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams are the second recommended option of RFC 9106: 64 MiB of memory and three passes.
+var DefaultParams = Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+
+// Argon2id hashes passwords with Argon2id. The password policy, and the hashes it does not make itself, e.g. the
+// bcrypt hashes from before switching over, are left to the framework it wraps.
+type Argon2id struct {
+	security.Service
+	params Params
+}
+
+// NewSecurityFramework returns an Argon2id framework with the parameters configured, falling back on the framework
+// given for everything but hashing.
+func NewSecurityFramework(cfg config.Argon2, fallback security.Service) (*Argon2id, error) {
+	params := Params{
+		Memory:      cfg.MemoryKiB,
+		Iterations:  cfg.Iterations,
+		Parallelism: cfg.Parallelism,
+		SaltLength:  cfg.SaltLength,
+		KeyLength:   cfg.KeyLength,
+	}.withDefaults()
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return &Argon2id{}, fmt.Errorf("argon2id needs at least %d KiB of memory for a parallelism of %d",
+			8*uint32(params.Parallelism), params.Parallelism)
+	}
+	if params.SaltLength < 8 {
+		return &Argon2id{}, fmt.Errorf("argon2id salt must be at least 8 bytes; given %d", params.SaltLength)
+	}
+	if params.KeyLength < 16 {
+		return &Argon2id{}, fmt.Errorf("argon2id key must be at least 16 bytes; given %d", params.KeyLength)
+	}
+	return &Argon2id{fallback, params}, nil
+}
+
+func (p Params) withDefaults() Params {
+	if p.Memory == 0 {
+		p.Memory = DefaultParams.Memory
+	}
+	if p.Iterations == 0 {
+		p.Iterations = DefaultParams.Iterations
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = DefaultParams.Parallelism
+	}
+	if p.SaltLength == 0 {
+		p.SaltLength = DefaultParams.SaltLength
+	}
+	if p.KeyLength == 0 {
+		p.KeyLength = DefaultParams.KeyLength
+	}
+	return p
+}
+
+// Hash hashes the password with a random salt, encoding the parameters along with it.
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism,
+		a.params.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", prefix, argon2.Version, a.params.Memory, a.params.Iterations,
+		a.params.Parallelism, encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// VerifyPasswordMatches checks the password against an Argon2id hash, with the parameters recorded in it, or against
+// any other hash with the fallback framework.
+func (a *Argon2id) VerifyPasswordMatches(hashedPW, givenPW string) error {
+	if !IsHash(hashedPW) {
+		return a.Service.VerifyPasswordMatches(hashedPW, givenPW)
+	}
+	return Verify(hashedPW, givenPW)
+}
+
+// NeedsRehash reports whether the hash was made by another algorithm or with other parameters.
+func (a *Argon2id) NeedsRehash(hashedPW string) bool {
+	params, _, key, err := decode(hashedPW)
+	if err != nil {
+		return true
+	}
+	params.KeyLength = uint32(len(key))
+	return params != a.params
+}
+
+// IsHash reports whether the hash is an Argon2id hash.
+func IsHash(hashedPW string) bool {
+	return strings.HasPrefix(hashedPW, prefix)
+}
+
+// Verify checks the password against an Argon2id hash in the PHC string format.
+func Verify(hashedPW, givenPW string) error {
+	params, salt, key, err := decode(hashedPW)
+	if err != nil {
+		return err
+	}
+	given := argon2.IDKey([]byte(givenPW), salt, params.Iterations, params.Memory, params.Parallelism,
+		uint32(len(key)))
+	if subtle.ConstantTimeCompare(given, key) != 1 {
+		return ErrPasswordsMismatch
+	}
+	return nil
+}
+
+// decode splits a hash into its parameters, salt and key.
+func decode(hashedPW string) (Params, []byte, []byte, error) {
+	if !IsHash(hashedPW) {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	parts := strings.Split(strings.TrimPrefix(hashedPW, prefix), "$")
+	if len(parts) != 4 {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	if version != argon2.Version {
+		return Params{}, nil, nil, ErrIncompatibleHash
+	}
+	var params Params
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations,
+		&params.Parallelism); err != nil {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	salt, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	key, err := encoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	params.SaltLength = uint32(len(salt))
+	return params, salt, key, nil
+}
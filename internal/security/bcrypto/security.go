@@ -8,20 +8,50 @@ import (
 
 	"github.com/coquizen/servercarte/internal/config"
 	"github.com/coquizen/servercarte/internal/helpers"
+	"github.com/coquizen/servercarte/internal/security/argon2id"
 )
 
 var ErrPasswordsDoNotMatch = errors.New("passwords do not match")
 
+const (
+	// DefaultPINMinLength and DefaultPINMaxLength bound the length of a PIN when the configuration does not.
+	DefaultPINMinLength = 4
+	DefaultPINMaxLength = 8
+)
+
+// BCrypt hashes passwords and checks them against the password policy. Reusing previous passwords is checked by the
+// account service, which keeps the password history.
 type BCrypt struct {
-	Length        int
-	MixedCase     bool
-	AlphaNum      bool
-	SpecialChar   bool
-	CheckPrevious bool
+	Length      int
+	MixedCase   bool
+	AlphaNum    bool
+	SpecialChar bool
+	Cost        int
+	PINMin      int
+	PINMax      int
 }
 
-func NewSecurityFramework(cfg config.Security) *BCrypt {
-	return &BCrypt{cfg.Length, cfg.MixedCase, cfg.AlphaNum, cfg.SpecialChar, cfg.CheckPrevious}
+func NewSecurityFramework(cfg config.Security) (*BCrypt, error) {
+	cost := cfg.BcryptCost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return &BCrypt{}, fmt.Errorf("bcrypt cost must be between %d and %d; given %d", bcrypt.MinCost,
+			bcrypt.MaxCost, cost)
+	}
+	pinMin, pinMax := cfg.PIN.MinLength, cfg.PIN.MaxLength
+	if pinMin == 0 {
+		pinMin = DefaultPINMinLength
+	}
+	if pinMax == 0 {
+		pinMax = DefaultPINMaxLength
+	}
+	if pinMin < 4 || pinMax < pinMin {
+		return &BCrypt{}, fmt.Errorf("pin length must be at least 4 digits and at most its maximum; given %d to %d",
+			pinMin, pinMax)
+	}
+	return &BCrypt{cfg.Length, cfg.MixedCase, cfg.AlphaNum, cfg.SpecialChar, cost, pinMin, pinMax}, nil
 }
 
 // ConfirmationChecker compares two given literal password inputs and returns whether they are equivalent.
@@ -32,20 +62,32 @@ func (s *BCrypt) ConfirmationChecker(password, confirmPassword string) error {
 	return nil
 }
 
-// VerifyPasswordMatches verifies that a given password is equivalent to its hashed form
+// VerifyPasswordMatches verifies that a given password is equivalent to its hashed form. Argon2id hashes are
+// verified too, so that switching back to bcrypt does not lock anyone out.
 func (s *BCrypt) VerifyPasswordMatches(hashedPW, givenPW string) error {
+	if argon2id.IsHash(hashedPW) {
+		return argon2id.Verify(hashedPW, givenPW)
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hashedPW), []byte(givenPW))
 }
 
 // Hash takes as input a given password and hashes it so it is suitable for persistent repository
-func (s *BCrypt) Hash(password string) string {
-	encryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(encryptedPassword)
+func (s *BCrypt) Hash(password string) (string, error) {
+	encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(password), s.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(encryptedPassword), nil
+}
+
+// NeedsRehash reports whether the hash is not a bcrypt hash of the configured cost.
+func (s *BCrypt) NeedsRehash(hashedPW string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPW))
+	return err != nil || cost != s.Cost
 }
 
 // IsValid validates whether a given string complies with the policy as declared by PasswordPolicy
 func (s *BCrypt) IsValid(password string) error {
-	// TODO: add conditional to check if password has been used previously.
 	if s.Length > len(password) {
 		return fmt.Errorf("password too short; should have %d characters", s.Length)
 	}
@@ -61,3 +103,18 @@ func (s *BCrypt) IsValid(password string) error {
 	}
 	return nil
 }
+
+// IsValidPIN validates whether a given PIN is made of PINMin to PINMax digits that are not all the same or in a row,
+// e.g. 1111 or 1234.
+func (s *BCrypt) IsValidPIN(pin string) error {
+	if !helpers.IsDigits(pin) {
+		return errors.New("pin must only have digits")
+	}
+	if len(pin) < s.PINMin || len(pin) > s.PINMax {
+		return fmt.Errorf("pin should have %d to %d digits", s.PINMin, s.PINMax)
+	}
+	if helpers.IsDigitRun(pin) {
+		return errors.New("pin must not repeat a digit or count up or down, e.g. 1111 or 1234")
+	}
+	return nil
+}
@@ -4,8 +4,15 @@ import (
 	"fmt"
 
 	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/apikey"
+	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/kitchen"
 	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/order"
+	"github.com/coquizen/servercarte/domain/promotion"
+	"github.com/coquizen/servercarte/domain/twofactor"
 	"github.com/coquizen/servercarte/domain/user"
+	"github.com/coquizen/servercarte/domain/webhook"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -21,14 +28,14 @@ func StrPtr(s string) *string {
 func seedDB(db *gorm.DB) error {
 	// Drop all Tables
 	var migrator = db.Migrator()
-	if err := migrator.DropTable(&menu.Section{}, &menu.Item{}, &user.User{}, &account.Account{}); err != nil {
+	if err := migrator.DropTable(&menu.Section{}, &menu.Item{}, &menu.ModifierGroup{}, &menu.ModifierOption{}, &menu.Variant{}, &menu.AvailabilityWindow{}, &menu.Tag{}, &menu.Snapshot{}, "item_tags", "section_tags", &user.User{}, &account.Account{}, &account.OneTimeToken{}, &account.LoginThrottle{}, &account.AuditEntry{}, &account.PasswordHistory{}, &authentication.Session{}, &authentication.RefreshToken{}, &twofactor.Enrollment{}, &twofactor.RecoveryCode{}, &twofactor.Challenge{}, &webhook.Subscription{}, &webhook.Delivery{}, &apikey.APIKey{}, &order.Order{}, &order.LineItem{}, &order.Selection{}, &promotion.Promotion{}, &promotion.Target{}, &kitchen.Ticket{}, &kitchen.TicketItem{}, &kitchen.RoutedOrder{}); err != nil {
 		return err
 	}
 
 	logger.Info.Println("All tables have been dropped...")
 	logger.Info.Println("Now migrating...")
 	// Migrate model over to db
-	err := db.AutoMigrate(&menu.Section{}, &menu.Item{}, &user.User{}, &account.Account{})
+	err := db.AutoMigrate(&menu.Section{}, &menu.Item{}, &menu.ModifierGroup{}, &menu.ModifierOption{}, &menu.Variant{}, &menu.AvailabilityWindow{}, &menu.Tag{}, &menu.Snapshot{}, &user.User{}, &account.Account{}, &account.OneTimeToken{}, &account.LoginThrottle{}, &account.AuditEntry{}, &account.PasswordHistory{}, &authentication.Session{}, &authentication.RefreshToken{}, &twofactor.Enrollment{}, &twofactor.RecoveryCode{}, &twofactor.Challenge{}, &webhook.Subscription{}, &webhook.Delivery{}, &apikey.APIKey{}, &order.Order{}, &order.LineItem{}, &order.Selection{}, &promotion.Promotion{}, &promotion.Target{}, &kitchen.Ticket{}, &kitchen.TicketItem{}, &kitchen.RoutedOrder{})
 	if err != nil {
 		return fmt.Errorf("error migrating scheme to db: %v", err)
 	}
@@ -36,6 +43,14 @@ func seedDB(db *gorm.DB) error {
 
 	logger.Info.Println("Seeding...")
 
+	tags := make(map[string]menu.Tag, len(menu.DefaultTags))
+	for _, tag := range menu.DefaultTags {
+		if err := db.Create(&tag).Error; err != nil {
+			return err
+		}
+		tags[tag.Name] = tag
+	}
+
 	everything := &menu.Item{Title: "Everything", Description: StrPtr("With onions, sesame seeds, & poppy seeds"), ListOrder: 1, Type: menu.AddOn, Price: 0, Active: true}
 	sesame := &menu.Item{Title: "Sesame Seed", ListOrder: 2, Type: menu.AddOn, Price: 0, Active: true}
 	poppy := &menu.Item{Title: "Poppy Seed", ListOrder: 3, Type: menu.AddOn, Price: 0, Active: true}
@@ -50,17 +65,36 @@ func seedDB(db *gorm.DB) error {
 	lightplaincreamcheese := &menu.Item{Title: "Light Cream Cheese", ListOrder: 3, Price: 25, Type: menu.AddOn, Active: true}
 	garliccreamcheese := &menu.Item{Title: "Garlic Cream Cheese", ListOrder: 4, Price: 75, Type: menu.AddOn, Active: true}
 	onionandchivecreamcheese := &menu.Item{Title: "Onion & Chive Cream Cheese", ListOrder: 5, Price: 150, Type: menu.AddOn, Active: true}
-	smokedsalmoncreamcheese := &menu.Item{Title: "Smoked Salmon Cream Cheese", ListOrder: 6, Price: 50, Type: menu.AddOn, Active: true}
+	smokedsalmoncreamcheese := &menu.Item{Title: "Smoked Salmon Cream Cheese", ListOrder: 6, Price: 50, Type: menu.AddOn, Active: true, Tags: []menu.Tag{tags["fish"]}}
 
 	bagelcondimentcontainer := &menu.Section{Title: "Bagel Condiments",
-		ListOrder: 0, Type: menu.Container, Active: true, Visible: false,
+		ListOrder: 0, Type: menu.Container, Active: true, Visible: false, Tags: []menu.Tag{tags["dairy"]},
 		Items: []menu.Item{*plaincreamcheese, *scallioncreamcheese, *lightplaincreamcheese, *onionandchivecreamcheese, *garliccreamcheese, *smokedsalmoncreamcheese}}
 
-	bagel := &menu.Item{Title: "Bagel", Description: StrPtr("Your choice of bagel."), Type: menu.Plate, ListOrder: 1, Price: 395, Active: true, AddOns: *bagelcontainer}
+	chooseBagel := menu.ModifierGroup{Title: "Choose your bagel", MinSelections: 1, MaxSelections: 1, ListOrder: 1,
+		Options: []menu.ModifierOption{
+			{Title: "Everything", ListOrder: 1, IsDefault: true, Active: true},
+			{Title: "Sesame Seed", ListOrder: 2, Active: true},
+			{Title: "Poppy Seed", ListOrder: 3, Active: true},
+			{Title: "Plain", ListOrder: 4, Active: true},
+			{Title: "Chocolate Chip", ListOrder: 5, Active: true},
+			{Title: "Onion", ListOrder: 6, Active: true},
+		}}
+	addCreamCheese := menu.ModifierGroup{Title: "Add cream cheese", MinSelections: 0, MaxSelections: 2, ListOrder: 2,
+		Options: []menu.ModifierOption{
+			{Title: "Plain Cream Cheese", ListOrder: 1, PriceDelta: 50, Active: true},
+			{Title: "Scallion Cream Cheese", ListOrder: 2, PriceDelta: 100, Active: true},
+			{Title: "Light Cream Cheese", ListOrder: 3, PriceDelta: 25, Active: true},
+			{Title: "Garlic Cream Cheese", ListOrder: 4, PriceDelta: 75, Active: true},
+			{Title: "Onion & Chive Cream Cheese", ListOrder: 5, PriceDelta: 150, Active: true},
+			{Title: "Smoked Salmon Cream Cheese", ListOrder: 6, PriceDelta: 50, Active: true},
+		}}
+
+	bagel := &menu.Item{Title: "Bagel", Description: StrPtr("Your choice of bagel."), Type: menu.Plate, ListOrder: 1, Price: 395, Active: true, AddOns: *bagelcontainer, ModifierGroups: []menu.ModifierGroup{chooseBagel, addCreamCheese}}
 	bagelwcreamcheese := &menu.Item{Title: "Bagel w/ Cream Cheese", Description: StrPtr("Toasted H&H Bagel with your choice of cream cheese."), Type: menu.Plate, ListOrder: 2, Price: 595, Active: true, AddOns: *bagelcontainer, Condiments: *bagelcondimentcontainer}
 	bagelwlox := &menu.Item{Title: "Bagel with Lox", Description: StrPtr("Your choice of H&H bagels and Atlantic smoked lox."), Type: menu.Plate, ListOrder: 3, Price: 995, Active: true, AddOns: *bagelcontainer, Condiments: *bagelcondimentcontainer}
 
-	bagels := menu.Section{Title: "Bagels", ListOrder: 1, Type: menu.Category, Items: []menu.Item{*bagelwcreamcheese, *bagelwlox, *bagel}}
+	bagels := menu.Section{Title: "Bagels", ListOrder: 1, Type: menu.Category, Tags: []menu.Tag{tags["wheat"]}, Items: []menu.Item{*bagelwcreamcheese, *bagelwlox, *bagel}}
 
 	maplesyrup := &menu.Item{Title: "Canadian Maple Syrup", ListOrder: 1, Type: menu.Condiment, Price: 0, Active: true}
 	butter := &menu.Item{Title: "Butter", ListOrder: 2, Type: menu.Condiment, Price: 0, Active: true}
@@ -86,11 +120,13 @@ func seedDB(db *gorm.DB) error {
 	chickensausage := &menu.Item{Title: "Chicken Sausage", Type: menu.Side, ListOrder: 2, Active: true, Price: 200}
 	potatolatke := &menu.Item{Title: "Potatoe Latke", Type: menu.Side, ListOrder: 1, Active: true, Price: 200}
 	breakfastsidecontainer := &menu.Section{Title: "Sides", Items: []menu.Item{*turkeybacon, *chickensausage, *potatolatke}, Type: menu.Container, Active: true, Visible: true, ListOrder: 0}
-	breakfast := &menu.Section{Title: "Breakfast", ListOrder: 1, Type: menu.Meal, SubSections: []menu.Section{bagels, eggs, waffles, *breakfastsidecontainer}, Active: true, Visible: true}
+	breakfast := &menu.Section{Title: "Breakfast", ListOrder: 1, Type: menu.Meal, SubSections: []menu.Section{bagels, eggs, waffles, *breakfastsidecontainer}, Active: true, Visible: true,
+		Availability: []menu.AvailabilityWindow{{StartTime: "07:00", EndTime: "11:00", TimeZone: "America/New_York"}}}
 	db.Create(&breakfast)
 
-	sunomonosalad := menu.Item{Title: "Sunomono Salad", Description: StrPtr("Thin rice noodles, shrimp, crab, soy sauce and rice vinegar."), Type: menu.Plate, Price: 395, ListOrder: 1, Active: true}
-	cobbsalad := menu.Item{Title: "Cobb Salad", Description: StrPtr("Blue cheese, grilled chicken breasts, red wine vinegar, eggs, and bacon."), Type: menu.Plate, Price: 645, ListOrder: 2, Active: true}
+	sunomonosalad := menu.Item{Title: "Sunomono Salad", Description: StrPtr("Thin rice noodles, shrimp, crab, soy sauce and rice vinegar."), Type: menu.Plate, Price: 395, ListOrder: 1, Active: true, Tags: []menu.Tag{tags["shellfish"], tags["soy"]}}
+	cobbsalad := menu.Item{Title: "Cobb Salad", Description: StrPtr("Blue cheese, grilled chicken breasts, red wine vinegar, eggs, and bacon."), Type: menu.Plate, Price: 645, ListOrder: 2, Active: true,
+		Variants: []menu.Variant{{Name: "Half", Price: 645, ListOrder: 1, Active: true}, {Name: "Full", Price: 1095, ListOrder: 2, Active: true}}}
 
 	salads := menu.Section{Title: "Salads", Type: menu.Category, Items: []menu.Item{sunomonosalad, cobbsalad}, Active: true, Visible: true, ListOrder: 1}
 
@@ -117,7 +153,7 @@ func seedDB(db *gorm.DB) error {
 
 	ossobuco := menu.Item{Title: "Osso Buco", Description: StrPtr("Braised veal shank served over risotto milanese"), ListOrder: 3, Type: menu.Plate, Price: 2895, Active: true}
 	shortribtortelloni := menu.Item{Title: "Short Ribs Tortelloni", Description: StrPtr("Tortelloni stuffed with braised beef short ribs"), ListOrder: 1, Type: menu.Plate, Price: 2495, Active: true}
-	gnocchi := menu.Item{Title: "Gnocchi Castelmagno", Description: StrPtr("Handmade Kale Potato Gnocchi in Castelmagno cream sauce"), ListOrder: 2, Type: menu.Plate, Price: 1895, Active: true}
+	gnocchi := menu.Item{Title: "Gnocchi Castelmagno", Description: StrPtr("Handmade Kale Potato Gnocchi in Castelmagno cream sauce"), ListOrder: 2, Type: menu.Plate, Price: 1895, Active: true, Tags: []menu.Tag{tags["vegetarian"], tags["dairy"]}}
 	entrees := menu.Section{Title: "Entrées", Type: menu.Category, ListOrder: 2, Items: []menu.Item{ossobuco, shortribtortelloni, gnocchi}}
 
 	dinner := &menu.Section{Title: "Dinner", Type: menu.Meal, ListOrder: 3, SubSections: []menu.Section{starters, entrees}, Active: true, Visible: true}
@@ -0,0 +1,113 @@
+package ginHTTP
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/twofactor"
+)
+
+type twoFactorHandler struct {
+	twoFactorSvc twofactor.Service
+}
+
+type codeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RegisterRoutes sets up the endpoints accounts manage their own two-factor authentication with.
+func RegisterRoutes(svc twofactor.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc) {
+	h := twoFactorHandler{svc}
+	twoFactorGroup := r.Group("/2fa", authMiddleWare)
+	twoFactorGroup.GET("", h.status)
+	twoFactorGroup.POST("/enroll", h.enroll)
+	twoFactorGroup.POST("/confirm", h.confirm)
+	twoFactorGroup.POST("/recovery-codes", h.regenerateRecoveryCodes)
+	twoFactorGroup.DELETE("", h.disable)
+}
+
+func (h *twoFactorHandler) status(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	status, err := h.twoFactorSvc.Status(ctx, claims.AccountID, account.AccessLevel(claims.Role).String())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// enroll generates a secret to add to an authenticator app. It takes effect once confirmed with a code.
+func (h *twoFactorHandler) enroll(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	secret, err := h.twoFactorSvc.Enroll(ctx, claims.AccountID, claims.Username)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": secret})
+}
+
+// confirm turns two-factor authentication on and returns the recovery codes, which are never shown again.
+func (h *twoFactorHandler) confirm(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	var req codeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.twoFactorSvc.Confirm(ctx, claims.AccountID, req.Code)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": codes}})
+}
+
+// regenerateRecoveryCodes replaces the recovery codes, e.g. once most have been used.
+func (h *twoFactorHandler) regenerateRecoveryCodes(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	var req codeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.twoFactorSvc.RegenerateRecoveryCodes(ctx, claims.AccountID, req.Code)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": codes}})
+}
+
+// disable turns two-factor authentication off, given a code or recovery code, unless the account's role requires it.
+func (h *twoFactorHandler) disable(ctx *gin.Context) {
+	claims := ctx.MustGet(authentication.CtxAuthenticationKey).(authentication.CustomClaims)
+	var req codeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.twoFactorSvc.Disable(ctx, claims.AccountID, account.AccessLevel(claims.Role).String(),
+		req.Code); err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// statusFor maps an error from the two-factor service to the status code it is answered with.
+func statusFor(err error) int {
+	switch err {
+	case twofactor.ErrInvalidCode:
+		return http.StatusUnauthorized
+	case twofactor.ErrRequired:
+		return http.StatusForbidden
+	case twofactor.ErrNotEnrolled, twofactor.ErrAlreadyEnrolled:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
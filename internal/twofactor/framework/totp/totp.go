@@ -0,0 +1,95 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// digits, period and the SHA-1 hash are the defaults of RFC 6238, the only settings every authenticator app reads.
+	digits = 6
+	period = 30
+	// secretSize is the size of the secrets generated, the 160 bits RFC 4226 recommends.
+	secretSize = 20
+	// skew is how many time steps a code may be off either way, to make up for clocks drifting and slow typists.
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// framework generates and checks RFC 6238 time-based one-time passwords.
+type framework struct{}
+
+// New returns a TOTP framework.
+func New() *framework {
+	return &framework{}
+}
+
+// NewSecret returns a random secret, base32 encoded as authenticator apps expect it.
+func (f *framework) NewSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth:// URI of the secret, as shown in a QR code.
+func (f *framework) URI(secret, issuer, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(period))
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}).String()
+}
+
+// Verify checks the code against the time step of the time given and the steps next to it.
+func (f *framework) Verify(secret, given string, at time.Time) (int64, bool) {
+	given = strings.ReplaceAll(given, " ", "")
+	if len(given) != digits {
+		return 0, false
+	}
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return 0, false
+	}
+	current := at.Unix() / period
+	for step := current - skew; step <= current+skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(code(key, step)), []byte(given)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// code returns the code of the time step, following the dynamic truncation of RFC 4226.
+func code(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}
@@ -0,0 +1,122 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret of the test vectors in appendix B of RFC 6238, "12345678901234567890", base32
+// encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfcVectors are the SHA-1 test vectors of RFC 6238, cut down to six digits, which leaves the last six of the eight
+// the RFC gives.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range rfcVectors {
+		if got := code(key, tt.unix/period); got != tt.code {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestVerifyAcceptsRFC6238Codes(t *testing.T) {
+	f := New()
+	for _, tt := range rfcVectors {
+		step, ok := f.Verify(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("code %s was refused at %d", tt.code, tt.unix)
+			continue
+		}
+		if step != tt.unix/period {
+			t.Errorf("code %s at %d was taken for step %d, want %d", tt.code, tt.unix, step, tt.unix/period)
+		}
+	}
+}
+
+func TestVerifyAllowsOneStepOfSkew(t *testing.T) {
+	f := New()
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(1111111111, 0)
+	current := at.Unix() / period
+
+	tests := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, tt := range tests {
+		step, ok := f.Verify(rfcSecret, code(key, current+tt.offset), at)
+		if ok != tt.ok {
+			t.Errorf("code %d steps off: accepted %t, want %t", tt.offset, ok, tt.ok)
+			continue
+		}
+		if ok && step != current+tt.offset {
+			t.Errorf("code %d steps off was taken for step %d, want %d", tt.offset, step, current+tt.offset)
+		}
+	}
+}
+
+func TestVerifyInput(t *testing.T) {
+	f := New()
+	at := time.Unix(59, 0)
+	tests := []struct {
+		name, secret, code string
+		ok                 bool
+	}{
+		{"spaces are ignored", rfcSecret, "287 082", true},
+		{"lower case secrets", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", true},
+		{"padded secrets", rfcSecret + "====", "287082", true},
+		{"wrong code", rfcSecret, "287083", false},
+		{"too short", rfcSecret, "28708", false},
+		{"too long", rfcSecret, "2870820", false},
+		{"invalid secret", "not base32!", "287082", false},
+	}
+	for _, tt := range tests {
+		if _, ok := f.Verify(tt.secret, tt.code, at); ok != tt.ok {
+			t.Errorf("%s: accepted %t, want %t", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestNewSecretIsUsable(t *testing.T) {
+	f := New()
+	secret, err := f.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret is %d bytes, want %d", len(key), secretSize)
+	}
+	at := time.Now()
+	if _, ok := f.Verify(secret, code(key, at.Unix()/period), at); !ok {
+		t.Error("code of a new secret was refused")
+	}
+}
@@ -0,0 +1,126 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/coquizen/servercarte/domain/twofactor"
+)
+
+// TwoFactorRepository represents the client to its persistent repository
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorRepository instantiates an instance for data persistence
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db}
+}
+
+func (r *TwoFactorRepository) FindEnrollment(_ context.Context, accountID uuid.UUID) (twofactor.Enrollment, error) {
+	var enrollment twofactor.Enrollment
+	err := r.db.Where("account_id = ?", accountID).First(&enrollment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return enrollment, twofactor.ErrNotEnrolled
+	}
+	return enrollment, err
+}
+
+func (r *TwoFactorRepository) ReplaceEnrollment(_ context.Context, enrollment *twofactor.Enrollment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteEnrollment(tx, enrollment.AccountID); err != nil {
+			return err
+		}
+		return tx.Create(enrollment).Error
+	})
+}
+
+func (r *TwoFactorRepository) ConfirmEnrollment(_ context.Context, accountID uuid.UUID, at time.Time) error {
+	return r.db.Model(&twofactor.Enrollment{}).Where("account_id = ?", accountID).Update("confirmed_at", at).Error
+}
+
+func (r *TwoFactorRepository) DeleteEnrollment(_ context.Context, accountID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteEnrollment(tx, accountID)
+	})
+}
+
+func deleteEnrollment(tx *gorm.DB, accountID uuid.UUID) error {
+	if err := tx.Where("account_id = ?", accountID).Delete(&twofactor.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("account_id = ?", accountID).Delete(&twofactor.Enrollment{}).Error
+}
+
+// UseStep moves the last step used forward in a single conditional update, so that of two requests racing with the
+// same code only one succeeds.
+func (r *TwoFactorRepository) UseStep(_ context.Context, accountID uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&twofactor.Enrollment{}).Where("account_id = ? AND last_used_step < ?", accountID,
+		step).Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(_ context.Context, accountID uuid.UUID,
+	codes []twofactor.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", accountID).Delete(&twofactor.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(_ context.Context, accountID uuid.UUID, codeHash string,
+	at time.Time) (bool, error) {
+	result := r.db.Model(&twofactor.RecoveryCode{}).Where(
+		"account_id = ? AND code_hash = ? AND used_at IS NULL", accountID, codeHash).Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *TwoFactorRepository) CountRecoveryCodes(_ context.Context, accountID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&twofactor.RecoveryCode{}).Where("account_id = ? AND used_at IS NULL", accountID).Count(
+		&count).Error
+	return count, err
+}
+
+func (r *TwoFactorRepository) CreateChallenge(_ context.Context, challenge *twofactor.Challenge) error {
+	return r.db.Create(challenge).Error
+}
+
+func (r *TwoFactorRepository) FindChallenge(_ context.Context, tokenHash string) (twofactor.Challenge, error) {
+	var challenge twofactor.Challenge
+	err := r.db.Where("token_hash = ?", tokenHash).First(&challenge).Error
+	return challenge, err
+}
+
+// UseChallenge marks the challenge used in a single conditional update, so that of two requests racing to answer
+// the same challenge only one succeeds.
+func (r *TwoFactorRepository) UseChallenge(_ context.Context, challengeID uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&twofactor.Challenge{}).Where("id = ? AND used_at IS NULL", challengeID).Update("used_at",
+		at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *TwoFactorRepository) FailChallenge(_ context.Context, challengeID uuid.UUID, maxAttempts int,
+	at time.Time) error {
+	if err := r.db.Model(&twofactor.Challenge{}).Where("id = ?", challengeID).Update("failed_attempts",
+		gorm.Expr("failed_attempts + 1")).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&twofactor.Challenge{}).Where("id = ? AND used_at IS NULL AND failed_attempts >= ?",
+		challengeID, maxAttempts).Update("used_at", at).Error
+}
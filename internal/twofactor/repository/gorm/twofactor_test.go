@@ -0,0 +1,70 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/coquizen/servercarte/domain/twofactor"
+)
+
+func TestUseStepRefusesReplays(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&twofactor.Enrollment{}, &twofactor.RecoveryCode{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewTwoFactorRepository(db)
+	ctx := context.Background()
+	accountID := uuid.New()
+	if err := repo.ReplaceEnrollment(ctx, &twofactor.Enrollment{AccountID: accountID, Secret: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const step = 37037037
+	tests := []struct {
+		name  string
+		step  int64
+		fresh bool
+	}{
+		{"first code", step, true},
+		{"same code again", step, false},
+		{"code of an earlier step", step - 1, false},
+		{"code of the next step", step + 1, true},
+		{"code of the next step again", step + 1, false},
+		{"first code once the next was used", step, false},
+	}
+	for _, tt := range tests {
+		fresh, err := repo.UseStep(ctx, accountID, tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fresh != tt.fresh {
+			t.Errorf("%s: fresh is %t, want %t", tt.name, fresh, tt.fresh)
+		}
+	}
+
+	if fresh, err := repo.UseStep(ctx, uuid.New(), step+2); err != nil || fresh {
+		t.Errorf("step of an account without an enrollment: fresh is %t, error %v", fresh, err)
+	}
+}
+
+func TestFindEnrollmentReportsMissingEnrollment(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&twofactor.Enrollment{}, &twofactor.RecoveryCode{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewTwoFactorRepository(db)
+	if _, err := repo.FindEnrollment(context.Background(), uuid.New()); err != twofactor.ErrNotEnrolled {
+		t.Errorf("FindEnrollment() error = %v, want %v", err, twofactor.ErrNotEnrolled)
+	}
+}
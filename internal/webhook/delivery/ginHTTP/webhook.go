@@ -0,0 +1,126 @@
+package ginHTTP
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coquizen/servercarte/domain/authorization"
+	"github.com/coquizen/servercarte/domain/webhook"
+)
+
+type webhookHandler struct {
+	webhookSvc webhook.Service
+}
+
+type subscriptionRequest struct {
+	URL         string              `json:"url" binding:"required"`
+	Secret      string              `json:"secret"`
+	Events      webhook.EventFilter `json:"events" binding:"required"`
+	Description *string             `json:"description,omitempty"`
+}
+
+// RegisterRoutes sets up the webhook API endpoints using Gin. Managing webhooks requires the webhook:manage
+// permission.
+func RegisterRoutes(svc webhook.Service, r *gin.Engine, authMiddleWare gin.HandlerFunc, authorize func(authorization.Permission) gin.HandlerFunc) {
+	h := webhookHandler{svc}
+	webhookGroup := r.Group("/api/v1/webhooks", authMiddleWare, authorize(authorization.ManageWebhooks))
+	webhookGroup.GET("", h.listSubscriptions)
+	webhookGroup.POST("", h.createSubscription)
+	webhookGroup.GET("/:id", h.findSubscriptionByID)
+	webhookGroup.PATCH("/:id", h.updateSubscription)
+	webhookGroup.DELETE("/:id", h.deleteSubscription)
+	webhookGroup.GET("/:id/deliveries", h.listDeliveries)
+	webhookGroup.POST("/:id/deliveries/:delivery_id/redeliver", h.redeliver)
+}
+
+func (h *webhookHandler) listSubscriptions(ctx *gin.Context) {
+	subscriptions, err := h.webhookSvc.Subscriptions(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": subscriptions})
+}
+
+// createSubscription registers a webhook. The secret payloads are signed with is only ever shown in this response.
+func (h *webhookHandler) createSubscription(ctx *gin.Context) {
+	var req subscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	subscription := webhook.Subscription{URL: req.URL, Secret: req.Secret, Events: req.Events,
+		Description: req.Description}
+	if err := h.webhookSvc.Subscribe(ctx, &subscription); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": subscription, "secret": subscription.Secret})
+}
+
+func (h *webhookHandler) findSubscriptionByID(ctx *gin.Context) {
+	subscription, err := h.webhookSvc.SubscriptionByID(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": subscription})
+}
+
+// updateSubscription changes the fields given, e.g. {"active": false} to pause a webhook or {"secret": "..."} to
+// rotate its secret.
+func (h *webhookHandler) updateSubscription(ctx *gin.Context) {
+	var req webhook.UpdateSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	subscription, err := h.webhookSvc.UpdateSubscription(ctx, ctx.Param("id"), req)
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": subscription})
+}
+
+func (h *webhookHandler) deleteSubscription(ctx *gin.Context) {
+	if err := h.webhookSvc.Unsubscribe(ctx, ctx.Param("id")); err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": "webhook successfully deleted"})
+}
+
+// listDeliveries returns the delivery log of a webhook, newest first.
+func (h *webhookHandler) listDeliveries(ctx *gin.Context) {
+	deliveries, err := h.webhookSvc.Deliveries(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+// redeliver sends a delivery again right away and returns how it went.
+func (h *webhookHandler) redeliver(ctx *gin.Context) {
+	delivery, err := h.webhookSvc.Redeliver(ctx, ctx.Param("id"), ctx.Param("delivery_id"))
+	if err != nil {
+		ctx.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": delivery})
+}
+
+// statusFor maps an error from the webhook service to the status code it is answered with.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, webhook.ErrSubscriptionNotFound), errors.Is(err, webhook.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrDeliveryInProgress):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,55 @@
+package httpsender
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/coquizen/servercarte/internal/config"
+)
+
+// defaultTimeout is how long a subscriber has to answer when no timeout is configured.
+const defaultTimeout = 10 * time.Second
+
+// maxResponseBody is how much of a subscriber's answer is read before the connection is let go.
+const maxResponseBody = 64 << 10
+
+// sender posts webhook payloads over HTTP.
+type sender struct {
+	client *http.Client
+}
+
+// New returns a sender whose requests time out after the configured number of seconds.
+func New(cfg config.Webhook) *sender {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &sender{client: &http.Client{Timeout: timeout}}
+}
+
+// NewWithClient returns a sender using the given client, e.g. that of an httptest.Server.
+func NewWithClient(client *http.Client) *sender {
+	return &sender{client: client}
+}
+
+// Send posts the body and returns the status code the subscriber answered with.
+func (s *sender) Send(ctx context.Context, url string, header http.Header, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = header.Clone()
+	req.Header.Set("User-Agent", "ServerCarte-Webhook/1.0")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain the answer so that the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,96 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/coquizen/servercarte/domain/webhook"
+)
+
+// webhookRepository represents the client to its persistent repository
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository instantiates an instance for data persistence
+func NewWebhookRepository(db *gorm.DB) *webhookRepository {
+	return &webhookRepository{db}
+}
+
+func (r *webhookRepository) ListSubscriptions(_ context.Context) (*[]webhook.Subscription, error) {
+	var subscriptions []webhook.Subscription
+	if err := r.db.Order("created_at").Find(&subscriptions).Error; err != nil {
+		return &subscriptions, err
+	}
+	return &subscriptions, nil
+}
+
+func (r *webhookRepository) FindSubscription(_ context.Context, subscription *webhook.Subscription) error {
+	if err := r.db.First(subscription, subscription.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return webhook.ErrSubscriptionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) CreateSubscription(_ context.Context, subscription *webhook.Subscription) error {
+	return r.db.Create(subscription).Error
+}
+
+func (r *webhookRepository) UpdateSubscription(_ context.Context, subscription *webhook.Subscription) error {
+	return r.db.Save(subscription).Error
+}
+
+// DeleteSubscription deletes the subscription and its delivery log.
+func (r *webhookRepository) DeleteSubscription(_ context.Context, subscription *webhook.Subscription) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", subscription.ID).Delete(&webhook.Delivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(subscription).Error
+	})
+}
+
+// ListDeliveries lists the deliveries of a subscription, newest first.
+func (r *webhookRepository) ListDeliveries(_ context.Context, subscriptionID uuid.UUID) (*[]webhook.Delivery, error) {
+	var deliveries []webhook.Delivery
+	if err := r.db.Where("subscription_id = ?", subscriptionID).Order("created_at desc").Find(
+		&deliveries).Error; err != nil {
+		return &deliveries, err
+	}
+	return &deliveries, nil
+}
+
+func (r *webhookRepository) FindDelivery(_ context.Context, delivery *webhook.Delivery) error {
+	if err := r.db.First(delivery, delivery.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return webhook.ErrDeliveryNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) CreateDelivery(_ context.Context, delivery *webhook.Delivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *webhookRepository) UpdateDelivery(_ context.Context, delivery *webhook.Delivery) error {
+	return r.db.Save(delivery).Error
+}
+
+// DueDeliveries lists up to limit pending deliveries whose next attempt is due by now, the longest waiting first.
+func (r *webhookRepository) DueDeliveries(_ context.Context, now time.Time, limit int) (*[]webhook.Delivery, error) {
+	var deliveries []webhook.Delivery
+	if err := r.db.Where("status = ? AND next_attempt_at <= ?", webhook.Pending, now).Order(
+		"next_attempt_at").Limit(limit).Find(&deliveries).Error; err != nil {
+		return &deliveries, err
+	}
+	return &deliveries, nil
+}
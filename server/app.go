@@ -1,8 +1,12 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/coquizen/servercarte/domain/security"
 
@@ -10,20 +14,54 @@ import (
 
 	"github.com/coquizen/servercarte/internal/authentication/framework/jwt"
 	"github.com/coquizen/servercarte/internal/config"
+	kitchenEvents "github.com/coquizen/servercarte/internal/kitchen/framework/eventbus"
+	"github.com/coquizen/servercarte/internal/logger"
+	"github.com/coquizen/servercarte/internal/mail/framework/filemailer"
+	"github.com/coquizen/servercarte/internal/mail/framework/smtpmailer"
+	"github.com/coquizen/servercarte/internal/menu/framework/eventbus"
+	"github.com/coquizen/servercarte/internal/menu/framework/printer"
+	"github.com/coquizen/servercarte/internal/security/argon2id"
 	"github.com/coquizen/servercarte/internal/security/bcrypto"
 	"github.com/coquizen/servercarte/internal/store/gormDB"
+	"github.com/coquizen/servercarte/internal/twofactor/framework/totp"
 
 	"github.com/coquizen/servercarte/domain/account"
+	"github.com/coquizen/servercarte/domain/apikey"
 	"github.com/coquizen/servercarte/domain/authentication"
+	"github.com/coquizen/servercarte/domain/authorization"
+	"github.com/coquizen/servercarte/domain/kitchen"
+	"github.com/coquizen/servercarte/domain/mail"
 	"github.com/coquizen/servercarte/domain/menu"
+	"github.com/coquizen/servercarte/domain/order"
+	"github.com/coquizen/servercarte/domain/promotion"
+	"github.com/coquizen/servercarte/domain/quote"
+	"github.com/coquizen/servercarte/domain/tax"
+	"github.com/coquizen/servercarte/domain/twofactor"
 	"github.com/coquizen/servercarte/domain/user"
+	"github.com/coquizen/servercarte/domain/webhook"
 	accountTransport "github.com/coquizen/servercarte/internal/account/delivery/ginHTTP"
 	accountRepo "github.com/coquizen/servercarte/internal/account/repository/gorm"
+	apiKeyTransport "github.com/coquizen/servercarte/internal/apikey/delivery/ginHTTP"
+	apiKeyRepo "github.com/coquizen/servercarte/internal/apikey/repository/gorm"
 	authHTTP "github.com/coquizen/servercarte/internal/authentication/delivery/ginHTTP"
+	sessionRepo "github.com/coquizen/servercarte/internal/authentication/repository/gorm"
+	authorizationTransport "github.com/coquizen/servercarte/internal/authorization/delivery/ginHTTP"
+	kitchenTransport "github.com/coquizen/servercarte/internal/kitchen/delivery/ginHTTP"
+	kitchenRepo "github.com/coquizen/servercarte/internal/kitchen/repository/gorm"
 	menuTransport "github.com/coquizen/servercarte/internal/menu/delivery/ginHTTP"
 	menuRepo "github.com/coquizen/servercarte/internal/menu/repository/gorm"
+	orderTransport "github.com/coquizen/servercarte/internal/order/delivery/ginHTTP"
+	orderRepo "github.com/coquizen/servercarte/internal/order/repository/gorm"
+	promotionTransport "github.com/coquizen/servercarte/internal/promotion/delivery/ginHTTP"
+	promotionRepo "github.com/coquizen/servercarte/internal/promotion/repository/gorm"
+	quoteTransport "github.com/coquizen/servercarte/internal/quote/delivery/ginHTTP"
+	twoFactorTransport "github.com/coquizen/servercarte/internal/twofactor/delivery/ginHTTP"
+	twoFactorRepo "github.com/coquizen/servercarte/internal/twofactor/repository/gorm"
 	userTransport "github.com/coquizen/servercarte/internal/user/delivery/ginHTTP"
 	userRepo "github.com/coquizen/servercarte/internal/user/repository/gorm"
+	webhookTransport "github.com/coquizen/servercarte/internal/webhook/delivery/ginHTTP"
+	"github.com/coquizen/servercarte/internal/webhook/framework/httpsender"
+	webhookRepo "github.com/coquizen/servercarte/internal/webhook/repository/gorm"
 )
 
 // App struct represents this application
@@ -32,7 +70,9 @@ type App struct {
 }
 
 // NewApp serves as the main entry point for this application
-func NewApp(rCfg config.Router, dCfg config.Database, aCfg config.Authentication, sCfg config.Security,
+func NewApp(rCfg config.Router, dCfg config.Database, aCfg config.Authentication, azCfg config.Authorization,
+	sCfg config.Security,
+	pCfg config.Print, wCfg config.Webhook, mCfg config.Mail, tCfg config.TwoFactor, xCfg config.Tax, kCfg config.Kitchen,
 	seedDatabase bool) *App {
 	//Set up repositories
 	db, err := gormDB.Start(dCfg, seedDatabase)
@@ -43,27 +83,141 @@ func NewApp(rCfg config.Router, dCfg config.Database, aCfg config.Authentication
 	menuRepository := menuRepo.NewMenuRepository(db)
 	userRepository := userRepo.NewUserRepository(db)
 	accountRepository := accountRepo.NewAccountRepository(db)
+	webhookRepository := webhookRepo.NewWebhookRepository(db)
+	sessionRepository := sessionRepo.NewSessionRepository(db)
+	twoFactorRepository := twoFactorRepo.NewTwoFactorRepository(db)
+	apiKeyRepository := apiKeyRepo.NewAPIKeyRepository(db)
+	orderRepository := orderRepo.NewOrderRepository(db)
+	promotionRepository := promotionRepo.NewPromotionRepository(db)
+	kitchenRepository := kitchenRepo.NewKitchenRepository(db)
 
 	authenticationFramework, err := jwt.New(aCfg)
 	if err != nil {
 		log.Panicf("authentication framework loading error %v", err)
 	}
-	authenticationService := authentication.NewService(authenticationFramework)
+	authenticationService := authentication.NewService(authenticationFramework, sessionRepository,
+		time.Duration(aCfg.RefreshExpirationPeriod)*time.Minute)
 
-	securityFramework := bcrypto.NewSecurityFramework(sCfg)
-	securityService := security.NewService(securityFramework)
+	roles := make(map[string]authorization.Role, len(azCfg.Roles))
+	for name, role := range azCfg.Roles {
+		permissions := make([]authorization.Permission, len(role.Permissions))
+		for i, permission := range role.Permissions {
+			permissions[i] = authorization.Permission(permission)
+		}
+		roles[name] = authorization.Role{Inherits: role.Inherits, Permissions: permissions}
+	}
+	authorizationService, err := authorization.NewService(roles)
+	if err != nil {
+		log.Panicf("authorization configuration error %v", err)
+	}
+
+	for _, role := range tCfg.RequiredRoles {
+		if _, err := account.AccessLevelFromName(role); err != nil {
+			log.Panicf("two-factor configuration error %v", err)
+		}
+	}
+	twoFactorService := twofactor.NewService(totp.New(), twoFactorRepository, twofactor.Options{
+		Issuer:          tCfg.Issuer,
+		RequiredRoles:   tCfg.RequiredRoles,
+		ChallengePeriod: time.Duration(tCfg.ChallengePeriod) * time.Minute,
+	})
+
+	passwordPolicy := account.PasswordPolicy{MaxAge: time.Duration(sCfg.MaxAgeDays) * 24 * time.Hour}
+	if sCfg.CheckPrevious {
+		passwordPolicy.History = sCfg.PreviousCount
+		if passwordPolicy.History <= 0 {
+			passwordPolicy.History = account.DefaultPasswordHistory
+		}
+	}
+
+	pinPolicy := account.PINPolicy{
+		MaxFailures: sCfg.PIN.MaxFailures,
+		TokenPeriod: time.Duration(sCfg.PIN.TokenMinutes) * time.Minute,
+		Scopes:      sCfg.PIN.Scopes,
+	}
+	for _, scope := range pinPolicy.Scopes {
+		if _, err := authorization.PermissionFromText(scope); err != nil {
+			log.Panicf("pin configuration error %v", err)
+		}
+	}
+
+	securityService, err := newSecurityFramework(sCfg)
+	if err != nil {
+		log.Panicf("security framework loading error %v", err)
+	}
+
+	printFramework, err := printer.New(pCfg)
+	if err != nil {
+		log.Panicf("print framework loading error %v", err)
+	}
+
+	mailer, err := newMailer(mCfg)
+	if err != nil {
+		log.Panicf("mailer loading error %v", err)
+	}
 
 	// Setup services
-	menuService := menu.NewService(menuRepository)
+	webhookService := webhook.NewService(webhookRepository, httpsender.New(wCfg), webhook.RetryPolicy{
+		MaxAttempts:    wCfg.MaxAttempts,
+		InitialBackoff: time.Duration(wCfg.InitialBackoffSeconds) * time.Second,
+		MaxBackoff:     time.Duration(wCfg.MaxBackoffSeconds) * time.Second,
+	}, logger.Domain)
+	taxPolicy, err := newTaxPolicy(xCfg)
+	if err != nil {
+		log.Panicf("tax configuration error %v", err)
+	}
+	taxCalculator, err := tax.NewCalculator(taxPolicy)
+	if err != nil {
+		log.Panicf("tax configuration error %v", err)
+	}
+	kitchenRouting := kitchen.Routing{Stations: kCfg.Stations, DefaultStation: kCfg.DefaultStation}
+	menuService := menu.NewService(menuRepository, printFramework, eventbus.New(), taxCalculator, &kitchenRouting)
+	kitchenService, err := kitchen.NewService(kitchenRepository, menuService, kitchenEvents.New(), kitchenRouting,
+		logger.Domain)
+	if err != nil {
+		log.Panicf("kitchen configuration error %v", err)
+	}
 	userService := user.NewService(userRepository)
-	accountService := account.NewService(accountRepository, userService, securityService, authenticationService)
+	promotionService := promotion.NewService(promotionRepository)
+	// Promotions come before tax, so that tax is charged on what is left to pay.
+	quoteService := quote.NewService(menuService, promotionService, taxCalculator)
+	orderService := order.NewService(orderRepository, quoteService, logger.Domain, webhookService, kitchenService)
+	accountService := account.NewService(accountRepository, userService, securityService, authenticationService,
+		webhookService, mailer, account.Links{PasswordReset: mCfg.ResetURL, EmailVerification: mCfg.VerificationURL},
+		account.LockoutPolicy{
+			MaxFailures:      sCfg.Lockout.MaxFailures,
+			MaxFailuresPerIP: sCfg.Lockout.MaxFailuresPerIP,
+			Window:           time.Duration(sCfg.Lockout.WindowMinutes) * time.Minute,
+			LockoutPeriod:    time.Duration(sCfg.Lockout.LockoutMinutes) * time.Minute,
+			InitialDelay:     time.Duration(sCfg.Lockout.InitialDelaySeconds) * time.Second,
+			MaxDelay:         time.Duration(sCfg.Lockout.MaxDelaySeconds) * time.Second,
+		}, passwordPolicy, pinPolicy, logger.Domain)
+	apiKeyService := apikey.NewService(apiKeyRepository, authorizationService, accountService)
 
-	authenticationMiddleware := authHTTP.NewMiddleWare(authenticationService)
+	go webhookService.Run(context.Background())
+	go webhookService.FollowMenu(context.Background(), menuService)
+	go kitchenService.Run(context.Background(), orderService)
+
+	// Routes acting on the account logged in only take access tokens; the others take API keys as well.
+	sessionMiddleware := authHTTP.NewMiddleWare(authenticationService)
+	authenticationMiddleware := authHTTP.NewMiddleWareWithAPIKeys(authenticationService, apiKeyService)
 
 	ginHandler := ginHTTP.NewHandler(rCfg)
-	menuTransport.RegisterRoutes(menuService, authenticationService, ginHandler, authenticationMiddleware, ginHTTP.AuthorizationMiddleware(0))
+	authorize := ginHTTP.NewAuthorizer(authorizationService)
+	permits := ginHTTP.NewPermits(authorizationService)
+
+	menuTransport.RegisterRoutes(menuService, authenticationService, ginHandler, authenticationMiddleware, authorize, ginHTTP.StreamLimit(rCfg))
 	userTransport.RegisterRoutes(userService, ginHandler)
-	accountTransport.RegisterRoutes(accountService, authenticationService, ginHandler, authenticationMiddleware, ginHTTP.AuthorizationMiddleware(0))
+	authHTTP.RegisterRoutes(authenticationService, ginHandler)
+	accountTransport.RegisterRoutes(accountService, authenticationService, twoFactorService, apiKeyService, ginHandler, authenticationMiddleware, sessionMiddleware, authorize)
+	twoFactorTransport.RegisterRoutes(twoFactorService, ginHandler, sessionMiddleware)
+	webhookTransport.RegisterRoutes(webhookService, ginHandler, authenticationMiddleware, authorize)
+	authorizationTransport.RegisterRoutes(authorizationService, ginHandler, authenticationMiddleware, authorize)
+	apiKeyTransport.RegisterRoutes(apiKeyService, ginHandler, sessionMiddleware, authorize)
+	quoteTransport.RegisterRoutes(quoteService, ginHandler)
+	orderTransport.RegisterRoutes(orderService, ginHandler, authenticationMiddleware, authorize, permits)
+	promotionTransport.RegisterRoutes(promotionService, ginHandler, authenticationMiddleware, authorize)
+	kitchenTransport.RegisterRoutes(kitchenService, ginHandler, authenticationMiddleware, authorize, ginHTTP.StreamLimit(rCfg))
 
 	server := ginHTTP.NewServer(rCfg, ginHandler)
 
@@ -72,6 +226,92 @@ func NewApp(rCfg config.Router, dCfg config.Database, aCfg config.Authentication
 	}
 }
 
+// newSecurityFramework returns the password hashing of the configured algorithm. Argon2id falls back on bcrypt for
+// the password policy and for the hashes made before switching over.
+func newSecurityFramework(cfg config.Security) (security.Service, error) {
+	bcryptFramework, err := bcrypto.NewSecurityFramework(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.HashAlgorithm {
+	case "bcrypt", "":
+		return security.NewService(bcryptFramework), nil
+	case "argon2id":
+		argon2Framework, err := argon2id.NewSecurityFramework(cfg.Argon2, bcryptFramework)
+		if err != nil {
+			return nil, err
+		}
+		return security.NewService(argon2Framework), nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q", cfg.HashAlgorithm)
+	}
+}
+
+// newMailer returns the mailer of the configured driver, logging email when none is configured.
+func newMailer(cfg config.Mail) (mail.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return smtpmailer.New(cfg)
+	case "file", "log", "":
+		if cfg.Driver != "file" {
+			cfg.Directory = ""
+		}
+		return filemailer.New(cfg)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// newTaxPolicy reads the tax configuration, turning percentages into parts per million and effective dates into
+// instants in the time zone of their location.
+func newTaxPolicy(cfg config.Tax) (tax.Policy, error) {
+	policy := tax.Policy{
+		Categories:      cfg.Categories,
+		DefaultCategory: cfg.DefaultCategory,
+		DefaultLocation: cfg.Location,
+		Rounding:        tax.Rounding(cfg.Rounding),
+		RoundPerLine:    cfg.RoundPerLine,
+		Locations:       make(map[string]tax.Location, len(cfg.Locations)),
+	}
+	if policy.Rounding == "" {
+		policy.Rounding = tax.HalfEven
+	}
+	for name, locationCfg := range cfg.Locations {
+		timeZone, err := time.LoadLocation(locationCfg.TimeZone)
+		if err != nil {
+			return policy, fmt.Errorf("location %q: %v", name, err)
+		}
+		location := tax.Location{Mode: tax.Mode(locationCfg.Mode)}
+		if location.Mode == "" {
+			location.Mode = tax.Exclusive
+		}
+		for _, rateCfg := range locationCfg.Rates {
+			if rateCfg.Percent < 0 || rateCfg.Percent > 100 {
+				return policy, fmt.Errorf("location %q: rate %q must be between 0 and 100 percent", name,
+					rateCfg.Name)
+			}
+			rate := tax.Rate{Name: rateCfg.Name, Category: rateCfg.Category,
+				PPM: uint64(math.Round(rateCfg.Percent * 10000))}
+			for _, date := range []struct {
+				raw string
+				at  **time.Time
+			}{{rateCfg.From, &rate.From}, {rateCfg.Until, &rate.Until}} {
+				if date.raw == "" {
+					continue
+				}
+				at, err := time.ParseInLocation("2006-01-02", date.raw, timeZone)
+				if err != nil {
+					return policy, fmt.Errorf("location %q: rate %q: %v", name, rateCfg.Name, err)
+				}
+				*date.at = &at
+			}
+			location.Rates = append(location.Rates, rate)
+		}
+		policy.Locations[name] = location
+	}
+	return policy, nil
+}
+
 func (a *App) Run() error {
 	return a.httpServer.ListenAndServe()
 }